@@ -0,0 +1,364 @@
+// Package accrual lança os juros diários e as tarifas mensais das contas.
+// Cada execução processa um dia já encerrado em que a conta estava aberta:
+// credita juros sobre o saldo
+// positivo ao fim do dia, cobra juros do cheque especial sobre o saldo
+// negativo e cobra a tarifa de manutenção do mês, se ainda não foi cobrada.
+// Os lançamentos têm uma chave por conta (models.Transaction.PostingKey), de
+// modo que repetir o mesmo dia não lança nada em dobro.
+package accrual
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// DateLayout é o formato das datas aceitas e retornadas pelo motor
+const DateLayout = "2006-01-02"
+
+// ErrOpenDay indica uma data que ainda não terminou
+var ErrOpenDay = errors.New("a data informada ainda não foi encerrada")
+
+// Registro de auditoria dos lançamentos feitos sem um chamador, pela execução
+// diária ou pelo subcomando accrue: o chamador é AuditActor e a ação é a mesma
+// da rota de apuração
+const (
+	AuditActor  = "accrual"
+	auditAction = "accruals:run"
+)
+
+// Descrições dos lançamentos de juros do cheque especial e de tarifa
+const (
+	OverdraftInterestDescription = "Juros do cheque especial"
+	MaintenanceFeeDescription    = "Tarifa de manutenção mensal"
+)
+
+// maxAttempts limita as tentativas por conta quando outra execução grava a
+// mesma conta ao mesmo tempo
+const maxAttempts = 3
+
+// Config define as taxas e tarifas. As taxas são anuais, em pontos-base
+// (250 = 2,5% ao ano), e aplicadas por dia como taxa/365.
+type Config struct {
+	InterestRate        int64
+	OverdraftRate       int64
+	PersonalMonthlyFee  models.Money
+	CorporateMonthlyFee models.Money
+
+	// Location define quando cada dia começa e termina; o padrão é time.Local
+	Location *time.Location
+}
+
+// Result resume a execução de um dia. Incomplete indica um dia interrompido
+// pela indisponibilidade do banco, em que parte das contas não foi processada.
+type Result struct {
+	Date              string    `json:"date"`
+	Clients           int       `json:"clients"`
+	Interest          int       `json:"interest"`
+	OverdraftInterest int       `json:"overdraft_interest"`
+	Fees              int       `json:"fees"`
+	Failed            []Failure `json:"failed,omitempty"`
+	Incomplete        bool      `json:"incomplete,omitempty"`
+}
+
+// Failure é uma conta que não pôde ser processada
+type Failure struct {
+	ClientID string `json:"client_id"`
+	Error    string `json:"error"`
+}
+
+// Engine executa os lançamentos sobre um banco de dados
+type Engine struct {
+	db  database.Database
+	cfg Config
+	now func() time.Time
+}
+
+// New cria um motor com a configuração informada
+func New(db database.Database, cfg Config) *Engine {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	return &Engine{db: db, cfg: cfg, now: time.Now}
+}
+
+// ParseDate lê uma data AAAA-MM-DD no fuso do motor
+func (e *Engine) ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, s, e.cfg.Location)
+}
+
+// Yesterday retorna o último dia encerrado
+func (e *Engine) Yesterday() time.Time {
+	return startOfDay(e.now().In(e.cfg.Location)).AddDate(0, 0, -1)
+}
+
+// RunRange executa Run para cada dia de from até to, inclusive, parando no
+// primeiro erro. Os lançamentos já feitos continuam gravados: junto com o erro
+// são retornados os resultados dos dias processados, inclusive o do dia
+// interrompido, se houver.
+func (e *Engine) RunRange(from, to time.Time, audit *database.AuditEntry) ([]Result, error) {
+	if from.After(to) {
+		return nil, errors.New("from não pode ser posterior a to")
+	}
+
+	results := make([]Result, 0)
+	last := startOfDay(to.In(e.cfg.Location))
+	for day := startOfDay(from.In(e.cfg.Location)); !day.After(last); day = day.AddDate(0, 0, 1) {
+		result, err := e.Run(day, audit)
+		if result != nil {
+			results = append(results, *result)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// Run processa o dia de date nas contas que estavam abertas ao fim dele. Cada
+// conta é gravada com o registro de audit, completado pelo banco com os saldos;
+// com audit nil o chamador registrado é AuditActor. Erros de uma conta são
+// registrados em Result.Failed; apenas a indisponibilidade do banco
+// interrompe a execução, e o resultado parcial é retornado com o erro.
+func (e *Engine) Run(date time.Time, audit *database.AuditEntry) (*Result, error) {
+	day := startOfDay(date.In(e.cfg.Location))
+	if day.AddDate(0, 0, 1).After(e.now()) {
+		return nil, ErrOpenDay
+	}
+	if audit == nil {
+		audit = &database.AuditEntry{Actor: AuditActor, ActorType: AuditActor, Action: auditAction}
+	}
+
+	ids, err := e.openClients(day)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Date: day.Format(DateLayout)}
+	for _, id := range ids {
+		posted, err := e.accrue(id, day, audit)
+		if errors.Is(err, database.ErrUnavailable) {
+			result.Incomplete = true
+			return result, err
+		}
+		if err != nil {
+			result.Failed = append(result.Failed, Failure{ClientID: id, Error: err.Error()})
+			continue
+		}
+
+		result.Clients++
+		for _, p := range posted {
+			switch p {
+			case kindInterest:
+				result.Interest++
+			case kindOverdraftInterest:
+				result.OverdraftInterest++
+			case kindMaintenanceFee:
+				result.Fees++
+			}
+		}
+	}
+	return result, nil
+}
+
+// Schedule executa Run para o último dia encerrado ao iniciar e, depois, a
+// cada virada de dia, até ctx ser cancelado
+func (e *Engine) Schedule(ctx context.Context) {
+	for {
+		day := e.Yesterday()
+		result, err := e.Run(day, nil)
+		if err != nil {
+			log.Printf("accrual %s: %v", day.Format(DateLayout), err)
+		}
+		if result != nil {
+			log.Printf("accrual %s: %d conta(s), %d juros, %d juros do cheque especial, %d tarifa(s), %d falha(s)",
+				result.Date, result.Clients, result.Interest, result.OverdraftInterest, result.Fees, len(result.Failed))
+		}
+
+		next := startOfDay(e.now().In(e.cfg.Location)).AddDate(0, 0, 1)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Tipos de lançamento, usados também como prefixo das chaves
+const (
+	kindInterest          = "interest"
+	kindOverdraftInterest = "overdraft_interest"
+	kindMaintenanceFee    = "maintenance_fee"
+)
+
+// errNothingToPost evita gravar a conta quando não há lançamentos
+var errNothingToPost = errors.New("nothing to post")
+
+// history é o que post precisa saber das transações já gravadas da conta
+type history struct {
+	balance models.Money    // saldo ao fim do dia
+	posted  map[string]bool // chaves do dia e do mês já lançadas
+}
+
+// accrue faz os lançamentos do dia em uma conta e retorna os tipos lançados.
+// O histórico é lido antes de bloquear a conta: se outra execução lançar a
+// mesma chave nesse intervalo, a gravação falha com ErrConflict e a leitura
+// é refeita. Uma conta encerrada depois do dia não aceita mais lançamentos; o
+// erro é retornado para que o dia não processado apareça em Result.Failed.
+func (e *Engine) accrue(id string, day time.Time, audit *database.AuditEntry) ([]string, error) {
+	var posted []string
+	for attempt := 1; ; attempt++ {
+		h, err := e.history(id, day)
+		if err != nil {
+			return nil, err
+		}
+		_, err = e.db.UpdateClientTx(id, audit, func(client models.Client) error {
+			var err error
+			if posted, err = e.post(client, day, h); err != nil {
+				return err
+			}
+			if len(posted) == 0 {
+				return errNothingToPost
+			}
+			return nil
+		})
+		switch {
+		case errors.Is(err, errNothingToPost):
+			return nil, nil
+		case errors.Is(err, database.ErrConflict) && attempt < maxAttempts:
+			continue
+		case err != nil:
+			return nil, err
+		}
+		return posted, nil
+	}
+}
+
+// history lê o saldo ao fim do dia e quais lançamentos do dia já foram feitos
+func (e *Engine) history(id string, day time.Time) (*history, error) {
+	balance, err := e.db.BalanceAt(id, endOfDay(day))
+	if err != nil {
+		return nil, err
+	}
+
+	h := &history{balance: balance, posted: make(map[string]bool)}
+	interestKey, overdraftKey, feeKey := postingKeys(day)
+	for _, key := range []string{interestKey, overdraftKey, feeKey} {
+		if h.posted[key], err = e.db.HasPosting(id, key); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// post aplica ao cliente os lançamentos do dia ainda não feitos, datados do
+// último microssegundo do dia (a precisão do TIMESTAMPTZ), e retorna os
+// tipos lançados
+func (e *Engine) post(client models.Client, day time.Time, h *history) ([]string, error) {
+	at := endOfDay(day)
+	// Dias encerrados antes da abertura da conta, e com eles os meses, não
+	// têm lançamentos: o saldo inicial não tem transação e BalanceAt o
+	// estenderia para trás
+	if at.Before(client.GetOpenedAt()) {
+		return nil, nil
+	}
+	balance := h.balance
+	interestKey, overdraftKey, feeKey := postingKeys(day)
+
+	var posted []string
+	if key := interestKey; balance.IsPositive() && !h.posted[key] {
+		if amount := dailyInterest(balance, e.cfg.InterestRate); amount.IsPositive() {
+			if err := client.CreditInterest(amount, key, at); err != nil {
+				return nil, err
+			}
+			posted = append(posted, kindInterest)
+		}
+	}
+	if key := overdraftKey; balance.IsNegative() && !h.posted[key] {
+		if amount := dailyInterest(balance.Neg(), e.cfg.OverdraftRate); amount.IsPositive() {
+			if err := client.ChargeFee(amount, OverdraftInterestDescription, key, at); err != nil {
+				return nil, err
+			}
+			posted = append(posted, kindOverdraftInterest)
+		}
+	}
+	if key := feeKey; !h.posted[key] {
+		if fee := e.monthlyFee(client); fee.IsPositive() {
+			if err := client.ChargeFee(fee, MaintenanceFeeDescription, key, at); err != nil {
+				return nil, err
+			}
+			posted = append(posted, kindMaintenanceFee)
+		}
+	}
+	return posted, nil
+}
+
+func (e *Engine) monthlyFee(client models.Client) models.Money {
+	switch client.(type) {
+	case *models.PersonalClient:
+		return e.cfg.PersonalMonthlyFee
+	case *models.CorporateClient:
+		return e.cfg.CorporateMonthlyFee
+	default:
+		return models.Money{}
+	}
+}
+
+// openClients retorna os IDs das contas que estavam abertas ao fim do dia,
+// inclusive as encerradas depois dele
+func (e *Engine) openClients(day time.Time) ([]string, error) {
+	filter := database.ClientFilter{SortBy: database.SortByID, Limit: database.MaxPageSize, IncludeClosed: true}
+	end := endOfDay(day)
+	ids := make([]string, 0)
+	for {
+		page, err := e.db.ListClients(filter)
+		if err != nil {
+			return nil, fmt.Errorf("error listing clients: %w", err)
+		}
+		for _, client := range page.Clients {
+			if closed := client.GetClosedAt(); closed != nil && !closed.After(end) {
+				continue
+			}
+			ids = append(ids, client.GetID())
+		}
+		if page.NextCursor == "" {
+			return ids, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// dailyInterest aplica um dia da taxa anual em pontos-base, truncando os
+// centavos
+func dailyInterest(balance models.Money, rate int64) models.Money {
+	return models.NewMoney(balance.Cents * rate / (10000 * 365))
+}
+
+func postingKey(kind, period string) string {
+	return kind + ":" + period
+}
+
+// postingKeys retorna as chaves dos juros e dos juros do cheque especial do
+// dia e da tarifa do mês
+func postingKeys(day time.Time) (interest, overdraft, fee string) {
+	date := day.Format(DateLayout)
+	return postingKey(kindInterest, date), postingKey(kindOverdraftInterest, date), postingKey(kindMaintenanceFee, day.Format("2006-01"))
+}
+
+// endOfDay retorna o último microssegundo do dia, quando os lançamentos são
+// datados
+func endOfDay(day time.Time) time.Time {
+	return day.AddDate(0, 0, 1).Add(-time.Microsecond)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,231 @@
+package accrual
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+func setup(t *testing.T) (*Engine, database.Database, *models.PersonalClient, *models.CorporateClient) {
+	db := database.NewMemoryDB()
+
+	saver, err := models.NewPersonalClient("John Doe", "529.982.247-25", models.MustParseMoney("36500.00"))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	debtor, err := models.NewCorporateClient("ACME Corp", "11.222.333/0001-81", models.NewMoney(0))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	debtor.Balance, debtor.CreditLimit = models.MustParseMoney("-3650.00"), models.MustParseMoney("5000.00")
+	saver.OpenedAt = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	debtor.OpenedAt = saver.OpenedAt
+	if err := db.CreatePersonalClient(saver, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := db.CreateCorporateClient(debtor, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	engine := New(db, Config{
+		InterestRate:        1000,  // 10% ao ano: 10.00 por dia sobre 36500.00
+		OverdraftRate:       10000, // 100% ao ano: 10.00 por dia sobre 3650.00
+		PersonalMonthlyFee:  models.MustParseMoney("15.00"),
+		CorporateMonthlyFee: models.MustParseMoney("50.00"),
+		Location:            time.UTC,
+	})
+	engine.now = func() time.Time { return time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC) }
+	return engine, db, saver, debtor
+}
+
+// transactionsOf retorna as transações gravadas do cliente, que GetClient não traz
+func transactionsOf(t *testing.T, db database.Database, id string) []models.Transaction {
+	statement, err := db.GetStatement(id, database.StatementFilter{Limit: database.MaxPageSize})
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	return statement.Transactions
+}
+
+func TestRun(t *testing.T) {
+	engine, db, saver, debtor := setup(t)
+
+	result, err := engine.Run(engine.Yesterday(), nil)
+	if err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+	if result.Date != "2026-10-16" || result.Clients != 2 || result.Interest != 1 || result.OverdraftInterest != 1 || result.Fees != 2 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	got, _ := db.GetClient(saver.ID)
+	if got.GetBalance() != models.MustParseMoney("36495.00") {
+		t.Errorf("Expected 36500.00 + 10.00 - 15.00, got %v", got.GetBalance())
+	}
+	got, _ = db.GetClient(debtor.ID)
+	if got.GetBalance() != models.MustParseMoney("-3710.00") {
+		t.Errorf("Expected -3650.00 - 10.00 - 50.00, got %v", got.GetBalance())
+	}
+	for _, tx := range transactionsOf(t, db, debtor.ID) {
+		if tx.Type != models.TransactionTypeFee || tx.CreatedAt != time.Date(2026, 10, 16, 23, 59, 59, 999999000, time.UTC) {
+			t.Errorf("Expected fees dated at the end of the day, got %+v", tx)
+		}
+	}
+
+	// Cada conta é auditada com os saldos antes e depois dos lançamentos
+	page, _ := db.Audit().List(database.AuditFilter{Actor: AuditActor})
+	if len(page.Entries) != 2 {
+		t.Fatalf("Expected one audit entry per account, got %+v", page.Entries)
+	}
+	for _, entry := range page.Entries {
+		if entry.Action != auditAction || entry.BalanceBefore == nil || entry.BalanceAfter == nil {
+			t.Errorf("Unexpected audit entry %+v", entry)
+		}
+	}
+
+	// Repetir o mesmo dia não lança nada
+	result, err = engine.Run(engine.Yesterday(), nil)
+	if err != nil {
+		t.Fatalf("Failed to run again: %v", err)
+	}
+	if result.Interest != 0 || result.OverdraftInterest != 0 || result.Fees != 0 {
+		t.Errorf("Expected nothing to be posted twice, got %+v", result)
+	}
+
+	today := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	if _, err := engine.Run(today, nil); !errors.Is(err, ErrOpenDay) {
+		t.Errorf("Expected ErrOpenDay, got %v", err)
+	}
+}
+
+func TestRunRange(t *testing.T) {
+	engine, db, saver, _ := setup(t)
+
+	from, _ := engine.ParseDate("2026-09-29")
+	to, _ := engine.ParseDate("2026-10-02")
+	results, err := engine.RunRange(from, to, nil)
+	if err != nil {
+		t.Fatalf("Failed to run range: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 days, got %d", len(results))
+	}
+
+	// Juros a cada dia e uma tarifa em setembro e outra em outubro
+	var interest, fees int
+	for _, tx := range transactionsOf(t, db, saver.ID) {
+		switch tx.Type {
+		case models.TransactionTypeInterest:
+			interest++
+		case models.TransactionTypeFee:
+			fees++
+		}
+	}
+	if interest != 4 || fees != 2 {
+		t.Errorf("Expected 4 interest postings and 2 fees, got %d and %d", interest, fees)
+	}
+
+	// Os juros de cada dia incidem sobre o saldo com os lançamentos dos dias
+	// anteriores: a tarifa de setembro reduz os juros de 30/09 e a de
+	// outubro os de 02/10
+	statement, _ := db.GetStatement(saver.ID, database.StatementFilter{Types: []string{models.TransactionTypeInterest}})
+	for i, want := range []string{"10.00", "9.99", "10.00", "9.99"} {
+		if statement.Transactions[i].Amount != models.MustParseMoney(want) {
+			t.Errorf("Expected interest of %s on day %d, got %v", want, i+1, statement.Transactions[i].Amount)
+		}
+	}
+}
+
+func TestRunRangeStopsAtOpenDay(t *testing.T) {
+	engine, db, saver, _ := setup(t)
+
+	// O período termina hoje: os dias encerrados são lançados e retornados
+	// junto com o erro
+	from, _ := engine.ParseDate("2026-10-15")
+	to, _ := engine.ParseDate("2026-10-17")
+	results, err := engine.RunRange(from, to, nil)
+	if !errors.Is(err, ErrOpenDay) {
+		t.Fatalf("Expected ErrOpenDay, got %v", err)
+	}
+	if len(results) != 2 || results[0].Date != "2026-10-15" || results[1].Date != "2026-10-16" || results[1].Interest != 1 {
+		t.Errorf("Expected the two closed days, got %+v", results)
+	}
+
+	statement, _ := db.GetStatement(saver.ID, database.StatementFilter{Types: []string{models.TransactionTypeInterest}})
+	if len(statement.Transactions) != 2 {
+		t.Errorf("Expected the interest of the two closed days to be kept, got %+v", statement.Transactions)
+	}
+}
+
+func TestRunClosedAccount(t *testing.T) {
+	engine, _, _, _ := setup(t)
+
+	// Conta encerrada ao meio-dia de 16/10: ainda estava aberta ao fim de
+	// 15/10, mas não aceita mais a tarifa de outubro
+	client, err := models.NewPersonalClient("Mary Doe", "111.444.777-35", models.NewMoney(0))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	closedAt := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	client.OpenedAt, client.ClosedAt = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), &closedAt
+	if err := engine.db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	day, _ := engine.ParseDate("2026-10-15")
+	result, err := engine.Run(day, nil)
+	if err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+	if result.Clients != 2 || len(result.Failed) != 1 || result.Failed[0].ClientID != client.ID {
+		t.Errorf("Expected the closed account to be reported as failed, got %+v", result)
+	}
+
+	result, err = engine.Run(engine.Yesterday(), nil)
+	if err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+	if result.Clients != 2 || len(result.Failed) != 0 {
+		t.Errorf("Expected the closed account to be skipped after it was closed, got %+v", result)
+	}
+}
+
+func TestRunRangeBeforeOpening(t *testing.T) {
+	engine, db, _, _ := setup(t)
+
+	// Conta aberta ao meio-dia de 01/10: o processamento retroativo não lança
+	// juros de setembro nem a tarifa de setembro
+	client, err := models.NewPersonalClient("Mary Doe", "111.444.777-35", models.MustParseMoney("36500.00"))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	client.OpenedAt = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	from, _ := engine.ParseDate("2026-09-29")
+	to, _ := engine.ParseDate("2026-10-02")
+	if _, err := engine.RunRange(from, to, nil); err != nil {
+		t.Fatalf("Failed to run range: %v", err)
+	}
+
+	var interest, fees int
+	for _, tx := range transactionsOf(t, db, client.ID) {
+		if tx.CreatedAt.Before(client.OpenedAt) {
+			t.Errorf("Expected nothing posted before the opening, got %+v", tx)
+		}
+		switch tx.Type {
+		case models.TransactionTypeInterest:
+			interest++
+		case models.TransactionTypeFee:
+			fees++
+		}
+	}
+	if interest != 2 || fees != 1 {
+		t.Errorf("Expected 2 interest postings and 1 fee, got %d and %d", interest, fees)
+	}
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKey é uma chave de API de serviço. Apenas o hash SHA-256 da chave é
+// guardado; a chave em si só é conhecida por quem a usa.
+type APIKey struct {
+	Name  string   `json:"name"`
+	Hash  string   `json:"hash"`
+	Roles []string `json:"roles"`
+}
+
+// HashAPIKey retorna o hash, em hexadecimal, com que a chave é configurada
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadAPIKeys lê as chaves de um arquivo JSON no formato
+// [{"name": "...", "hash": "...", "roles": ["..."]}]
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading API keys: %w", err)
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("error parsing API keys: %w", err)
+	}
+	for i, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key %d has no name", i)
+		}
+		if hash, err := hex.DecodeString(key.Hash); err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("API key %q: hash must be a hex-encoded SHA-256", key.Name)
+		}
+		keys[i].Hash = strings.ToLower(key.Hash)
+	}
+	return keys, nil
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader é o cabeçalho com a chave de API dos serviços
+const APIKeyHeader = "X-API-Key"
+
+// Config define as credenciais aceitas pelo Authenticator. Chaves de API e
+// JWT podem ser usados juntos; ao menos um deles precisa estar configurado.
+type Config struct {
+	APIKeys []APIKey
+
+	// HMACSecret habilita tokens HS256 e RSAPublicKey habilita tokens RS256
+	HMACSecret   []byte
+	RSAPublicKey *rsa.PublicKey
+
+	// Issuer e Audience, se definidos, precisam coincidir com iss e aud
+	Issuer   string
+	Audience string
+}
+
+// Authenticator identifica o chamador de uma requisição HTTP
+type Authenticator struct {
+	keys map[string]APIKey // hash -> chave
+	jwt  jwtVerifier
+}
+
+// New cria um Authenticator com as credenciais de cfg
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{
+		keys: make(map[string]APIKey, len(cfg.APIKeys)),
+		jwt: jwtVerifier{
+			hmacSecret: cfg.HMACSecret,
+			rsaKey:     cfg.RSAPublicKey,
+			issuer:     cfg.Issuer,
+			audience:   cfg.Audience,
+			now:        time.Now,
+		},
+	}
+	for _, key := range cfg.APIKeys {
+		a.keys[strings.ToLower(key.Hash)] = key
+	}
+
+	if len(a.keys) == 0 && !a.jwt.enabled() {
+		return nil, errors.New("no API keys or JWT keys configured")
+	}
+	return a, nil
+}
+
+// Authenticate identifica o chamador pelo cabeçalho X-API-Key ou por um
+// token em Authorization: Bearer
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.authenticateAPIKey(key)
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrMissingCredentials
+	}
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrInvalidCredentials
+	}
+	return a.authenticateToken(strings.TrimSpace(token))
+}
+
+func (a *Authenticator) authenticateAPIKey(key string) (*Principal, error) {
+	stored, ok := a.keys[HashAPIKey(key)]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Subject: stored.Name, Method: MethodAPIKey, Roles: stored.Roles}, nil
+}
+
+func (a *Authenticator) authenticateToken(token string) (*Principal, error) {
+	if !a.jwt.enabled() {
+		return nil, ErrInvalidCredentials
+	}
+	claims, err := a.jwt.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: claims.Subject, Method: MethodJWT, Roles: claims.Roles}, nil
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestAuthenticator(t *testing.T, cfg Config) *Authenticator {
+	t.Helper()
+	a, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	a.jwt.now = func() time.Time { return testNow }
+	return a
+}
+
+func signToken(t *testing.T, alg string, claims interface{}, sign func([]byte) []byte) string {
+	t.Helper()
+	header, _ := json.Marshal(jwtHeader{Algorithm: alg, Type: "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Failed to encode claims: %v", err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func authenticate(a *Authenticator, header, value string) (*Principal, error) {
+	r := httptest.NewRequest("GET", "/api/clients", nil)
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	return a.Authenticate(r)
+}
+
+func validClaims() Claims {
+	return Claims{
+		Subject:   "user-1",
+		Issuer:    "bank",
+		Audience:  audience{"api"},
+		ExpiresAt: testNow.Add(time.Hour).Unix(),
+		Roles:     []string{"teller"},
+	}
+}
+
+func TestNewRequiresCredentials(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("Expected an error without API keys or JWT keys")
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	a := newTestAuthenticator(t, Config{APIKeys: []APIKey{
+		{Name: "billing", Hash: HashAPIKey("s3cret"), Roles: []string{"teller"}},
+	}})
+
+	principal, err := authenticate(a, APIKeyHeader, "s3cret")
+	if err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	if principal.Subject != "billing" || principal.Method != MethodAPIKey || !principal.HasRole("teller") {
+		t.Errorf("Unexpected principal %+v", principal)
+	}
+
+	if _, err := authenticate(a, APIKeyHeader, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := authenticate(a, "", ""); !errors.Is(err, ErrMissingCredentials) {
+		t.Errorf("Expected ErrMissingCredentials, got %v", err)
+	}
+	// Sem chave HMAC ou RSA configurada, tokens são recusados
+	if _, err := authenticate(a, "Authorization", "Bearer a.b.c"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestHS256(t *testing.T) {
+	secret := []byte("hmac-secret")
+	a := newTestAuthenticator(t, Config{HMACSecret: secret, Issuer: "bank", Audience: "api"})
+	hs256 := func(data []byte) []byte { return signHMAC(data, secret) }
+
+	principal, err := authenticate(a, "Authorization", "Bearer "+signToken(t, "HS256", validClaims(), hs256))
+	if err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	if principal.Subject != "user-1" || principal.Method != MethodJWT || !principal.HasRole("teller") {
+		t.Errorf("Unexpected principal %+v", principal)
+	}
+
+	expired := validClaims()
+	expired.ExpiresAt = testNow.Add(-time.Minute).Unix()
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "other"
+	wrongAudience := validClaims()
+	wrongAudience.Audience = audience{"other"}
+	notYet := validClaims()
+	notYet.NotBefore = testNow.Add(time.Hour).Unix()
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = 0
+
+	tests := []struct {
+		name     string
+		header   string
+		expected error
+	}{
+		{"Expired", "Bearer " + signToken(t, "HS256", expired, hs256), ErrTokenExpired},
+		{"WrongIssuer", "Bearer " + signToken(t, "HS256", wrongIssuer, hs256), ErrInvalidCredentials},
+		{"WrongAudience", "Bearer " + signToken(t, "HS256", wrongAudience, hs256), ErrInvalidCredentials},
+		{"NotYetValid", "Bearer " + signToken(t, "HS256", notYet, hs256), ErrInvalidCredentials},
+		{"NoExpiry", "Bearer " + signToken(t, "HS256", noExpiry, hs256), ErrInvalidCredentials},
+		{"WrongSecret", "Bearer " + signToken(t, "HS256", validClaims(), func(data []byte) []byte {
+			return signHMAC(data, []byte("other"))
+		}), ErrInvalidCredentials},
+		{"AlgNone", "Bearer " + signToken(t, "none", validClaims(), func([]byte) []byte { return nil }), ErrInvalidCredentials},
+		{"Malformed", "Bearer not-a-token", ErrInvalidCredentials},
+		{"WrongScheme", "Basic dXNlcjpwYXNz", ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := authenticate(a, "Authorization", tt.header); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	publicKey, err := ParseRSAPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %v", err)
+	}
+
+	a := newTestAuthenticator(t, Config{RSAPublicKey: publicKey})
+	rs256 := func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return signature
+	}
+
+	if _, err := authenticate(a, "Authorization", "Bearer "+signToken(t, "RS256", validClaims(), rs256)); err != nil {
+		t.Errorf("Failed to authenticate: %v", err)
+	}
+
+	// Um token HS256 assinado com a chave pública não pode ser aceito
+	forged := signToken(t, "HS256", validClaims(), func(data []byte) []byte {
+		return signHMAC(data, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	})
+	if _, err := authenticate(a, "Authorization", "Bearer "+forged); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "keys.json")
+	os.WriteFile(valid, []byte(`[{"name": "billing", "hash": "`+HashAPIKey("s3cret")+`", "roles": ["teller"]}]`), 0o600)
+	keys, err := LoadAPIKeys(valid)
+	if err != nil {
+		t.Fatalf("Failed to load keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Name != "billing" || keys[0].Roles[0] != "teller" {
+		t.Errorf("Unexpected keys %+v", keys)
+	}
+
+	// Chaves em texto puro no lugar do hash são rejeitadas
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[{"name": "billing", "hash": "s3cret"}]`), 0o600)
+	if _, err := LoadAPIKeys(invalid); err == nil {
+		t.Error("Expected an error for a key without a SHA-256 hash")
+	}
+}
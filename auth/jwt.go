@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// clockSkew é a tolerância aplicada a exp e nbf
+const clockSkew = 30 * time.Second
+
+// Claims são os campos do token JWT usados pela API
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+// audience aceita aud como texto ou como lista, como permite a RFC 7519
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// jwtVerifier valida tokens HS256 e RS256. Cada algoritmo só é aceito se a
+// chave correspondente estiver configurada, o que impede que um token HS256
+// seja assinado com a chave pública RSA.
+type jwtVerifier struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	issuer     string
+	audience   string
+	now        func() time.Time
+}
+
+func (v *jwtVerifier) enabled() bool {
+	return len(v.hmacSecret) > 0 || v.rsaKey != nil
+}
+
+func (v *jwtVerifier) verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: token malformado", ErrInvalidCredentials)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: assinatura malformada", ErrInvalidCredentials)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	switch {
+	case header.Algorithm == "HS256" && len(v.hmacSecret) > 0:
+		if !hmac.Equal(signature, signHMAC(signed, v.hmacSecret)) {
+			return nil, fmt.Errorf("%w: assinatura inválida", ErrInvalidCredentials)
+		}
+	case header.Algorithm == "RS256" && v.rsaKey != nil:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(v.rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, fmt.Errorf("%w: assinatura inválida", ErrInvalidCredentials)
+		}
+	default:
+		return nil, fmt.Errorf("%w: algoritmo %q não aceito", ErrInvalidCredentials, header.Algorithm)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.validate(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *jwtVerifier) validate(claims *Claims) error {
+	now := v.now()
+	switch {
+	case claims.Subject == "":
+		return fmt.Errorf("%w: token sem sub", ErrInvalidCredentials)
+	case claims.ExpiresAt == 0:
+		return fmt.Errorf("%w: token sem exp", ErrInvalidCredentials)
+	case now.Add(-clockSkew).After(time.Unix(claims.ExpiresAt, 0)):
+		return ErrTokenExpired
+	case claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)):
+		return fmt.Errorf("%w: token ainda não é válido", ErrInvalidCredentials)
+	case v.issuer != "" && claims.Issuer != v.issuer:
+		return fmt.Errorf("%w: emissor inválido", ErrInvalidCredentials)
+	case v.audience != "" && !claims.Audience.contains(v.audience):
+		return fmt.Errorf("%w: audiência inválida", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: token malformado", ErrInvalidCredentials)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: token malformado", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+func signHMAC(data, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// ParseRSAPublicKey lê uma chave pública RSA em PEM, nos formatos PKIX
+// ("PUBLIC KEY") ou PKCS #1 ("RSA PUBLIC KEY")
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in public key")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key: %w", err)
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an RSA key")
+		}
+		return rsaKey, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
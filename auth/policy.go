@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// AnyAction concede todas as ações a um papel
+const AnyAction = "*"
+
+// ErrForbidden é satisfeito por todo *ForbiddenError
+var ErrForbidden = errors.New("acesso negado")
+
+// Policy define quais ações cada papel pode executar. Roles vale para os
+// papéis do chamador em qualquer conta; HolderRoles vale apenas nas contas
+// das quais o usuário é titular, conforme o seu papel na conta; Self vale
+// quando o usuário acessa os próprios dados em /api/users/{id}.
+type Policy struct {
+	Roles       map[string][]string `json:"roles"`
+	HolderRoles map[string][]string `json:"holder_roles"`
+	Self        []string            `json:"self"`
+}
+
+// Resource identifica o alvo de uma ação: a conta ou o usuário afetado
+type Resource struct {
+	ClientID string
+	UserID   string
+}
+
+// HolderLookup retorna o papel do usuário na conta, ou "" se ele não tem
+// acesso a ela
+type HolderLookup func(clientID, userID string) (string, error)
+
+// Denial descreve por que uma ação foi negada
+type Denial struct {
+	Action     string   `json:"action"`
+	Subject    string   `json:"subject"`
+	Roles      []string `json:"roles"`
+	ClientID   string   `json:"client_id,omitempty"`
+	HolderRole string   `json:"holder_role,omitempty"`
+	Message    string   `json:"message"`
+}
+
+// ForbiddenError é retornado por Authorize quando a política nega a ação
+type ForbiddenError struct {
+	Denial Denial
+}
+
+func (e *ForbiddenError) Error() string {
+	return ErrForbidden.Error() + ": " + e.Denial.Message
+}
+
+func (e *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+// LoadPolicy lê a política de um arquivo JSON e confere que ela só menciona
+// ações conhecidas
+func LoadPolicy(path string, actions []string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policy: %w", err)
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("error parsing policy: %w", err)
+	}
+	if err := policy.validate(actions); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (p *Policy) validate(actions []string) error {
+	known := make(map[string]bool, len(actions)+1)
+	known[AnyAction] = true
+	for _, action := range actions {
+		known[action] = true
+	}
+
+	check := func(section, name string, granted []string) error {
+		for _, action := range granted {
+			if !known[action] {
+				return fmt.Errorf("policy %s %q: unknown action %q", section, name, action)
+			}
+		}
+		return nil
+	}
+
+	for _, section := range []struct {
+		name  string
+		roles map[string][]string
+	}{{"role", p.Roles}, {"holder role", p.HolderRoles}} {
+		for role, granted := range section.roles {
+			if err := check(section.name, role, granted); err != nil {
+				return err
+			}
+		}
+	}
+	return check("section", "self", p.Self)
+}
+
+// Authorize decide se o chamador pode executar a ação sobre o recurso. Os
+// papéis do chamador são conferidos primeiro; em seguida, para usuários, o
+// acesso aos próprios dados e o papel de titular na conta, obtido de lookup.
+func (p *Policy) Authorize(principal *Principal, action string, resource Resource, lookup HolderLookup) error {
+	if principal == nil {
+		return ErrMissingCredentials
+	}
+
+	for _, role := range principal.Roles {
+		if grants(p.Roles[role], action) {
+			return nil
+		}
+	}
+
+	denial := Denial{
+		Action:   action,
+		Subject:  principal.Subject,
+		Roles:    sortedRoles(principal.Roles),
+		ClientID: resource.ClientID,
+		Message:  fmt.Sprintf("nenhum papel de %s permite %s", principal.Subject, action),
+	}
+
+	if principal.Method == MethodJWT {
+		if resource.UserID != "" && resource.UserID == principal.Subject && grants(p.Self, action) {
+			return nil
+		}
+
+		if resource.ClientID != "" && lookup != nil {
+			role, err := lookup(resource.ClientID, principal.Subject)
+			if err != nil {
+				return err
+			}
+			if role != "" && grants(p.HolderRoles[role], action) {
+				return nil
+			}
+			if role != "" {
+				denial.HolderRole = role
+				denial.Message = fmt.Sprintf("o papel %s na conta %s não permite %s", role, resource.ClientID, action)
+			}
+		}
+	}
+
+	return &ForbiddenError{Denial: denial}
+}
+
+func grants(granted []string, action string) bool {
+	for _, a := range granted {
+		if a == action || a == AnyAction {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedRoles(roles []string) []string {
+	sorted := append(make([]string, 0, len(roles)), roles...)
+	sort.Strings(sorted)
+	return sorted
+}
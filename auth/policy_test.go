@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyAuthorize(t *testing.T) {
+	policy := &Policy{
+		Roles: map[string][]string{
+			"admin":   {AnyAction},
+			"teller":  {"accounts:withdraw", "accounts:statement"},
+			"auditor": {"accounts:statement"},
+		},
+		HolderRoles: map[string][]string{
+			"owner":  {"accounts:withdraw", "accounts:statement"},
+			"viewer": {"accounts:statement"},
+		},
+		Self: []string{"users:read"},
+	}
+
+	holders := map[string]string{"client-1/user-1": "owner", "client-1/user-2": "viewer"}
+	lookup := func(clientID, userID string) (string, error) {
+		return holders[clientID+"/"+userID], nil
+	}
+
+	admin := &Principal{Subject: "ops", Method: MethodAPIKey, Roles: []string{"admin"}}
+	teller := &Principal{Subject: "branch", Method: MethodAPIKey, Roles: []string{"teller"}}
+	auditor := &Principal{Subject: "audit", Method: MethodAPIKey, Roles: []string{"auditor"}}
+	owner := &Principal{Subject: "user-1", Method: MethodJWT}
+	viewer := &Principal{Subject: "user-2", Method: MethodJWT}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		action    string
+		resource  Resource
+		allowed   bool
+	}{
+		{"admin can do anything", admin, "clients:create_corporate", Resource{}, true},
+		{"teller can withdraw", teller, "accounts:withdraw", Resource{ClientID: "client-1"}, true},
+		{"auditor cannot withdraw", auditor, "accounts:withdraw", Resource{ClientID: "client-1"}, false},
+		{"auditor can read statements", auditor, "accounts:statement", Resource{ClientID: "client-1"}, true},
+		{"owner can withdraw from own account", owner, "accounts:withdraw", Resource{ClientID: "client-1"}, true},
+		{"owner cannot withdraw from other account", owner, "accounts:withdraw", Resource{ClientID: "client-2"}, false},
+		{"viewer cannot withdraw", viewer, "accounts:withdraw", Resource{ClientID: "client-1"}, false},
+		{"viewer can read statement", viewer, "accounts:statement", Resource{ClientID: "client-1"}, true},
+		{"user can read self", owner, "users:read", Resource{UserID: "user-1"}, true},
+		{"user cannot read others", owner, "users:read", Resource{UserID: "user-2"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(tt.principal, tt.action, tt.resource, lookup)
+			if tt.allowed && err != nil {
+				t.Errorf("Expected action to be allowed, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbidden) {
+				t.Errorf("Expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+
+	var forbidden *ForbiddenError
+	err := policy.Authorize(viewer, "accounts:withdraw", Resource{ClientID: "client-1"}, lookup)
+	if !errors.As(err, &forbidden) {
+		t.Fatalf("Expected *ForbiddenError, got %v", err)
+	}
+	if forbidden.Denial.Action != "accounts:withdraw" || forbidden.Denial.HolderRole != "viewer" || forbidden.Denial.ClientID != "client-1" {
+		t.Errorf("Unexpected denial %+v", forbidden.Denial)
+	}
+
+	if err := policy.Authorize(nil, "accounts:withdraw", Resource{}, lookup); !errors.Is(err, ErrMissingCredentials) {
+		t.Errorf("Expected ErrMissingCredentials without a principal, got %v", err)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	actions := []string{"accounts:withdraw", "accounts:statement"}
+
+	valid := filepath.Join(dir, "policy.json")
+	os.WriteFile(valid, []byte(`{"roles": {"teller": ["accounts:withdraw"]}, "holder_roles": {"viewer": ["accounts:statement"]}}`), 0o600)
+	policy, err := LoadPolicy(valid, actions)
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+	if len(policy.Roles["teller"]) != 1 {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+
+	// Ações com erro de digitação são rejeitadas em vez de nunca concederem acesso
+	typo := filepath.Join(dir, "typo.json")
+	os.WriteFile(typo, []byte(`{"roles": {"teller": ["accounts:withdrawl"]}}`), 0o600)
+	if _, err := LoadPolicy(typo, actions); err == nil {
+		t.Error("Expected an error for an unknown action")
+	}
+}
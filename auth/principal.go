@@ -0,0 +1,54 @@
+// Package auth identifica quem faz cada requisição, por chave de API ou por
+// token JWT, e guarda o resultado no contexto da requisição.
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Métodos de autenticação
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+var (
+	ErrMissingCredentials = errors.New("credenciais ausentes")
+	ErrInvalidCredentials = errors.New("credenciais inválidas")
+	ErrTokenExpired       = errors.New("token expirado")
+)
+
+// Principal é o chamador autenticado: um serviço, identificado pelo nome da
+// chave de API, ou um usuário, identificado pelo sub do token
+type Principal struct {
+	Subject string   `json:"subject"`
+	Method  string   `json:"method"`
+	Roles   []string `json:"roles,omitempty"`
+}
+
+// HasRole indica se o chamador tem o papel informado
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+// NewContext retorna uma cópia de ctx com o chamador autenticado
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// FromContext retorna o chamador autenticado, ou nil se a requisição não
+// passou pelo middleware de autenticação
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey).(*Principal)
+	return principal
+}
@@ -0,0 +1,279 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// AuditEntry é um registro do log de auditoria. Cada registro guarda o hash
+// do anterior, formando uma cadeia: alterar ou remover um registro invalida
+// todos os seguintes.
+type AuditEntry struct {
+	ID            int64         `json:"id"`
+	Actor         string        `json:"actor"`
+	ActorType     string        `json:"actor_type"`
+	Action        string        `json:"action"`
+	ClientID      string        `json:"client_id,omitempty"`
+	UserID        string        `json:"user_id,omitempty"`
+	ScheduleID    string        `json:"schedule_id,omitempty"`
+	BalanceBefore *models.Money `json:"balance_before,omitempty"`
+	BalanceAfter  *models.Money `json:"balance_after,omitempty"`
+	SourceIP      string        `json:"source_ip"`
+	RequestID     string        `json:"request_id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	PrevHash      string        `json:"prev_hash"`
+	Hash          string        `json:"hash"`
+}
+
+// ComputeHash calcula o hash SHA-256 do registro, incluindo PrevHash e
+// excluindo o próprio Hash
+func (e *AuditEntry) ComputeHash() string {
+	payload := *e
+	payload.Hash = ""
+	payload.CreatedAt = e.CreatedAt.UTC()
+	data, _ := json.Marshal(payload)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditLog é o log de auditoria, que só aceita novos registros
+type AuditLog interface {
+	// Append numera o registro, define CreatedAt, encadeia-o ao último e o grava
+	Append(entry AuditEntry) (*AuditEntry, error)
+	// List retorna uma página de registros em ordem de ID
+	List(filter AuditFilter) (*AuditPage, error)
+	// Verify percorre a cadeia de hashes desde o primeiro registro
+	Verify() (*AuditVerification, error)
+}
+
+// AuditFilter descreve os filtros e a paginação de AuditLog.List
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	ClientID   string
+	From       *time.Time // inclusivo
+	To         *time.Time // inclusivo
+	Descending bool
+	Limit      int
+	Cursor     string // valor de AuditPage.NextCursor da página anterior
+}
+
+// AuditPage é uma página de resultados de AuditLog.List
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// AuditVerification é o resultado de AuditLog.Verify. Se a cadeia estiver
+// quebrada, BrokenAt é o primeiro registro inválido.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`
+	LastHash string `json:"last_hash,omitempty"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Validate verifica o filtro e preenche o limite padrão
+func (f *AuditFilter) Validate() error {
+	if f.From != nil && f.To != nil && f.From.After(*f.To) {
+		return errors.New("from não pode ser posterior a to")
+	}
+
+	if f.Limit == 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		return errors.New("limit deve estar entre 1 e 200")
+	}
+
+	if f.Cursor != "" {
+		if _, err := decodeAuditCursor(f.Cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *AuditFilter) matches(e *AuditEntry) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.ClientID != "" && e.ClientID != f.ClientID:
+		return false
+	case f.From != nil && e.CreatedAt.Before(*f.From):
+		return false
+	case f.To != nil && e.CreatedAt.After(*f.To):
+		return false
+	}
+	return true
+}
+
+func encodeAuditCursor(id int64) string {
+	return encodeCursor(pageCursor{ID: strconv.FormatInt(id, 10)})
+}
+
+func decodeAuditCursor(s string) (int64, error) {
+	c, err := decodeCursor(s)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(c.ID, 10, 64)
+	if err != nil {
+		return 0, errors.New("cursor inválido")
+	}
+	return id, nil
+}
+
+// clientAudit completa o registro de uma operação sobre a conta clientID;
+// before é nil na criação da conta
+func clientAudit(entry AuditEntry, clientID string, before *models.Money, after models.Money) AuditEntry {
+	entry.ClientID = clientID
+	switch {
+	case before == nil:
+		entry.BalanceAfter = &after
+	case before.Cmp(after) != 0:
+		entry.BalanceBefore, entry.BalanceAfter = before, &after
+	}
+	return entry
+}
+
+// chainEntry preenche os campos definidos pelo log a partir do último
+// registro. CreatedAt é truncado em microssegundos, a precisão do PostgreSQL,
+// para que o hash possa ser recalculado a partir do banco.
+func chainEntry(entry AuditEntry, last *AuditEntry, now time.Time) AuditEntry {
+	entry.ID, entry.PrevHash = 1, ""
+	if last != nil {
+		entry.ID, entry.PrevHash = last.ID+1, last.Hash
+	}
+	entry.CreatedAt = now.UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+	return entry
+}
+
+// auditVerifier confere os registros um a um, em ordem de ID
+type auditVerifier struct {
+	result AuditVerification
+	last   *AuditEntry
+}
+
+func (v *auditVerifier) check(e AuditEntry) bool {
+	switch {
+	case v.last != nil && e.PrevHash != v.last.Hash:
+		v.fail(e.ID, "prev_hash não corresponde ao registro anterior")
+	case v.last == nil && e.PrevHash != "":
+		v.fail(e.ID, "o primeiro registro não pode ter prev_hash")
+	case e.Hash != e.ComputeHash():
+		v.fail(e.ID, "hash não corresponde ao conteúdo do registro")
+	default:
+		v.result.Entries++
+		v.result.LastHash = e.Hash
+		v.last = &e
+		return true
+	}
+	return false
+}
+
+func (v *auditVerifier) fail(id int64, reason string) {
+	v.result.BrokenAt, v.result.Reason = id, reason
+}
+
+func (v *auditVerifier) verification() *AuditVerification {
+	v.result.Valid = v.result.BrokenAt == 0
+	return &v.result
+}
+
+// MemoryAuditLog é uma implementação de AuditLog em memória
+type MemoryAuditLog struct {
+	mutex   sync.RWMutex
+	entries []AuditEntry
+	now     func() time.Time
+}
+
+// NewMemoryAuditLog cria um log de auditoria vazio
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{now: time.Now}
+}
+
+func (m *MemoryAuditLog) Append(entry AuditEntry) (*AuditEntry, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var last *AuditEntry
+	if len(m.entries) > 0 {
+		last = &m.entries[len(m.entries)-1]
+	}
+	entry = chainEntry(copyAuditEntry(entry), last, m.now())
+	m.entries = append(m.entries, entry)
+	return &entry, nil
+}
+
+func (m *MemoryAuditLog) List(filter AuditFilter) (*AuditPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	var after int64
+	if filter.Cursor != "" {
+		after, _ = decodeAuditCursor(filter.Cursor)
+	}
+
+	m.mutex.RLock()
+	matched := make([]AuditEntry, 0)
+	for i := range m.entries {
+		e := &m.entries[i]
+		if filter.Cursor != "" && ((!filter.Descending && e.ID <= after) || (filter.Descending && e.ID >= after)) {
+			continue
+		}
+		if filter.matches(e) {
+			matched = append(matched, copyAuditEntry(*e))
+		}
+	}
+	m.mutex.RUnlock()
+
+	if filter.Descending {
+		sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+	}
+
+	page := &AuditPage{Entries: matched}
+	if len(matched) > filter.Limit {
+		page.Entries = matched[:filter.Limit]
+		page.NextCursor = encodeAuditCursor(page.Entries[filter.Limit-1].ID)
+	}
+	return page, nil
+}
+
+func (m *MemoryAuditLog) Verify() (*AuditVerification, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var v auditVerifier
+	for _, e := range m.entries {
+		if !v.check(e) {
+			break
+		}
+	}
+	return v.verification(), nil
+}
+
+func copyAuditEntry(e AuditEntry) AuditEntry {
+	if e.BalanceBefore != nil {
+		before := *e.BalanceBefore
+		e.BalanceBefore = &before
+	}
+	if e.BalanceAfter != nil {
+		after := *e.BalanceAfter
+		e.BalanceAfter = &after
+	}
+	return e
+}
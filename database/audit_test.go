@@ -0,0 +1,35 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+func TestMemoryAuditLogDetectsTampering(t *testing.T) {
+	log := NewMemoryAuditLog()
+	for i := 0; i < 3; i++ {
+		after := models.NewMoney(int64(i) * 100)
+		if _, err := log.Append(AuditEntry{Actor: "billing", Action: "accounts:deposit", BalanceAfter: &after}); err != nil {
+			t.Fatalf("Failed to append audit entry: %v", err)
+		}
+	}
+
+	forged := models.NewMoney(1_000_000)
+	log.entries[1].BalanceAfter = &forged
+
+	verification, err := log.Verify()
+	if err != nil {
+		t.Fatalf("Failed to verify audit log: %v", err)
+	}
+	if verification.Valid || verification.BrokenAt != 2 || verification.Entries != 1 {
+		t.Errorf("Expected the chain to break at entry 2, got %+v", verification)
+	}
+
+	// Recalcular o hash do registro alterado quebra o elo com o seguinte
+	log.entries[1].Hash = log.entries[1].ComputeHash()
+	verification, _ = log.Verify()
+	if verification.Valid || verification.BrokenAt != 3 {
+		t.Errorf("Expected the chain to break at entry 3, got %+v", verification)
+	}
+}
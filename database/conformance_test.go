@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/database/dbtest"
+)
+
+func TestMemoryDB_Conformance(t *testing.T) {
+	dbtest.RunConformance(t, func(t *testing.T) database.Database {
+		return database.NewMemoryDB()
+	})
+}
+
+func TestPostgresDB_Conformance(t *testing.T) {
+	dbtest.RunConformance(t, func(t *testing.T) database.Database {
+		return database.SetupTestDB(t)
+	})
+}
+
+func TestMemoryHolderStore_Conformance(t *testing.T) {
+	dbtest.RunHolderConformance(t, func(t *testing.T) dbtest.Stores {
+		clients, users := database.NewMemoryDB(), database.NewDatabase()
+		return dbtest.Stores{Clients: clients, Users: users, Holders: database.NewMemoryHolderStore(clients, users)}
+	})
+}
+
+func TestPostgresHolderStore_Conformance(t *testing.T) {
+	dbtest.RunHolderConformance(t, func(t *testing.T) dbtest.Stores {
+		db := database.SetupTestDB(t)
+		return dbtest.Stores{Clients: db, Users: db.Users(), Holders: db.Holders()}
+	})
+}
+
+func TestMemoryIdempotencyStore_Conformance(t *testing.T) {
+	dbtest.RunIdempotencyConformance(t, func(t *testing.T) database.IdempotencyStore {
+		return database.NewMemoryIdempotencyStore()
+	})
+}
+
+func TestPostgresIdempotencyStore_Conformance(t *testing.T) {
+	dbtest.RunIdempotencyConformance(t, func(t *testing.T) database.IdempotencyStore {
+		db := database.SetupTestDB(t)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		return db.Idempotency()
+	})
+}
+
+func TestMemoryAuditLog_Conformance(t *testing.T) {
+	dbtest.RunAuditConformance(t, func(t *testing.T) database.AuditLog {
+		return database.NewMemoryAuditLog()
+	})
+}
+
+func TestPostgresAuditLog_Conformance(t *testing.T) {
+	dbtest.RunAuditConformance(t, func(t *testing.T) database.AuditLog {
+		db := database.SetupTestDB(t)
+		t.Cleanup(func() {
+			db.Close()
+		})
+		return db.Audit()
+	})
+}
+
+func TestMemoryScheduleStore_Conformance(t *testing.T) {
+	dbtest.RunScheduleConformance(t, func(t *testing.T) dbtest.ScheduleStores {
+		return dbtest.ScheduleStores{Clients: database.NewMemoryDB(), Schedules: database.NewMemoryScheduleStore()}
+	})
+}
+
+func TestPostgresScheduleStore_Conformance(t *testing.T) {
+	dbtest.RunScheduleConformance(t, func(t *testing.T) dbtest.ScheduleStores {
+		db := database.SetupTestDB(t)
+		return dbtest.ScheduleStores{Clients: db, Schedules: db.Schedules()}
+	})
+}
@@ -1,25 +1,68 @@
 package database
 
 import (
-	"errors"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/Luis-Andrei/api-users/models"
 	"github.com/google/uuid"
 )
 
-// Database é a interface que define os métodos que um banco de dados deve implementar
+// Database é a interface que define os métodos que um banco de dados deve
+// implementar. GetClient, UpdateClientTx e Transfer carregam o cliente sem o
+// histórico, com o uso dos limites de saque em LimitUsage; as transações
+// gravadas só são lidas por GetStatement e pelas consultas pontuais abaixo.
+//
+// As operações que alteram clientes recebem audit, o registro do log de
+// auditoria gravado na mesma transação: a operação e o registro são
+// confirmados ou desfeitos juntos. O banco completa ClientID e, se o saldo
+// mudou, BalanceBefore e BalanceAfter; na criação, BalanceAfter é o saldo
+// inicial. Com audit nil nada é registrado.
 type Database interface {
-	CreatePersonalClient(client *models.PersonalClient) error
-	CreateCorporateClient(client *models.CorporateClient) error
+	CreatePersonalClient(client *models.PersonalClient, audit *AuditEntry) error
+	CreateCorporateClient(client *models.CorporateClient, audit *AuditEntry) error
 	GetClient(id string) (models.Client, error)
-	UpdateClient(client models.Client) error
-	ListClients() ([]models.Client, error)
+	// UpdateClientTx aplica fn ao cliente bloqueado e grava o resultado; um
+	// segundo estorno da mesma transação é recusado com
+	// models.ErrAlreadyReversed
+	UpdateClientTx(id string, audit *AuditEntry, fn func(models.Client) error) (models.Client, error)
+	// GetTransaction retorna a transação txID do cliente ou
+	// models.ErrTransactionNotFound
+	GetTransaction(id, txID string) (*models.Transaction, error)
+	// HasPosting indica se o cliente já tem um lançamento com a chave
+	// informada (models.Transaction.PostingKey)
+	HasPosting(id, key string) (bool, error)
+	// BalanceAt retorna o saldo do cliente no instante at, incluindo as
+	// transações feitas exatamente em at
+	BalanceAt(id string, at time.Time) (models.Money, error)
+	// DeleteClient encerra a conta, que precisa estar com saldo zero; o
+	// cliente continua disponível em GetClient com closed_at preenchido
+	DeleteClient(id string, audit *AuditEntry) error
+	// Transfer move o valor entre as duas contas de forma atômica e retorna
+	// os saldos de ambas antes e depois, lidos com as contas bloqueadas. O
+	// registro de audit é gravado uma vez para cada conta.
+	Transfer(fromID, toID string, amount models.Money, audit *AuditEntry) (*models.Transfer, error)
+	// TransferWithDescription é Transfer com a descrição das duas transações
+	TransferWithDescription(fromID, toID string, amount models.Money, description string, audit *AuditEntry) (*models.Transfer, error)
+	GetStatement(id string, filter StatementFilter) (*Statement, error)
+	ListClients(filter ClientFilter) (*ClientPage, error)
+	// Audit retorna o log de auditoria em que as operações são registradas
+	Audit() AuditLog
 	Close() error
 	InitTables() error
 }
 
-// Database representa o banco de dados em memória
+// UserStore é a interface dos repositórios de usuários
+type UserStore interface {
+	FindAll() ([]*models.User, error)
+	FindByID(id string) (*models.User, error)
+	Insert(user *models.User) (*models.User, error)
+	Update(id string, user *models.User) (*models.User, error)
+	Delete(id string) (*models.User, error)
+}
+
+// DatabaseStruct é o repositório de usuários em memória
 type DatabaseStruct struct {
 	users map[string]*models.User
 	mutex sync.RWMutex
@@ -32,8 +75,8 @@ func NewDatabase() *DatabaseStruct {
 	}
 }
 
-// FindAll retorna todos os usuários
-func (db *DatabaseStruct) FindAll() []*models.User {
+// FindAll retorna todos os usuários ordenados por nome, sobrenome e ID
+func (db *DatabaseStruct) FindAll() ([]*models.User, error) {
 	db.mutex.RLock()
 	defer db.mutex.RUnlock()
 
@@ -41,7 +84,17 @@ func (db *DatabaseStruct) FindAll() []*models.User {
 	for _, user := range db.users {
 		users = append(users, user)
 	}
-	return users
+	sort.Slice(users, func(i, j int) bool {
+		a, b := users[i], users[j]
+		if a.FirstName != b.FirstName {
+			return a.FirstName < b.FirstName
+		}
+		if a.LastName != b.LastName {
+			return a.LastName < b.LastName
+		}
+		return a.ID < b.ID
+	})
+	return users, nil
 }
 
 // FindByID retorna um usuário pelo ID
@@ -93,8 +146,3 @@ func (db *DatabaseStruct) Delete(id string) (*models.User, error) {
 	delete(db.users, id)
 	return user, nil
 }
-
-// Erros do banco de dados
-var (
-	ErrUserNotFound = errors.New("usuário não encontrado")
-)
@@ -35,7 +35,10 @@ func TestDatabase(t *testing.T) {
 	}
 
 	// Test FindAll
-	users := db.FindAll()
+	users, err := db.FindAll()
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
 	if len(users) != 1 {
 		t.Errorf("FindAll returned wrong number of users: got %v want %v", len(users), 1)
 	}
@@ -0,0 +1,806 @@
+// Package dbtest contém o conjunto de testes de contrato que toda
+// implementação de database.Database deve passar. Cada backend o executa a
+// partir dos próprios testes:
+//
+//	func TestMemoryDB_Conformance(t *testing.T) {
+//		dbtest.RunConformance(t, func(t *testing.T) database.Database {
+//			return database.NewMemoryDB()
+//		})
+//	}
+package dbtest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// Factory cria um banco vazio e pronto para uso. É chamada uma vez por
+// subteste; o banco retornado é fechado ao fim do subteste.
+type Factory func(t *testing.T) database.Database
+
+// missingID é um ID válido que nenhum teste cadastra
+const missingID = "00000000-0000-0000-0000-000000000000"
+
+// RunConformance executa o conjunto de testes de contrato contra a
+// implementação de database.Database criada por newDB
+func RunConformance(t *testing.T, newDB Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("1000.00"))
+		if err := db.CreatePersonalClient(client, nil); err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+
+		got, err := db.GetClient(client.ID)
+		if err != nil {
+			t.Fatalf("Failed to get client: %v", err)
+		}
+		personal, ok := got.(*models.PersonalClient)
+		if !ok {
+			t.Fatalf("Expected *models.PersonalClient, got %T", got)
+		}
+		if personal.Name != client.Name || personal.CPF != client.CPF || personal.Balance != client.Balance {
+			t.Errorf("Expected %+v, got %+v", client, personal)
+		}
+		// TIMESTAMPTZ guarda microssegundos
+		if personal.OpenedAt.Sub(client.OpenedAt).Abs() > time.Microsecond {
+			t.Errorf("Expected opened_at %v, got %v", client.OpenedAt, personal.OpenedAt)
+		}
+
+		if _, err := db.GetClient(missingID); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected database.ErrClientNotFound, got %v", err)
+		}
+	})
+
+	t.Run("DuplicateDocument", func(t *testing.T) {
+		db := open(t, newDB)
+
+		if err := db.CreateCorporateClient(newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.NewMoney(0)), nil); err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		err := db.CreateCorporateClient(newCorporateClient(t, "ACME Ltda", "11222333000181", models.NewMoney(0)), nil)
+		if !errors.Is(err, database.ErrDuplicateDocument) || !errors.Is(err, database.ErrConflict) {
+			t.Errorf("Expected database.ErrDuplicateDocument, got %v", err)
+		}
+	})
+
+	t.Run("UpdateClientTx", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("1000.00"))
+		if err := db.CreatePersonalClient(client, nil); err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+
+		_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("2000.00"))
+		})
+		if !errors.Is(err, models.ErrWithdrawLimit) {
+			t.Errorf("Expected ErrWithdrawLimit, got %v", err)
+		}
+
+		updated, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Deposit(models.MustParseMoney("50.00"), "")
+		})
+		if err != nil {
+			t.Fatalf("Failed to deposit: %v", err)
+		}
+		if updated.GetBalance() != models.MustParseMoney("1050.00") {
+			t.Errorf("Expected balance of 1050.00, got %v", updated.GetBalance())
+		}
+
+		// Alterações feitas fora de UpdateClientTx não chegam ao banco
+		if err := updated.Withdraw(models.MustParseMoney("100.00")); err != nil {
+			t.Fatalf("Failed to withdraw: %v", err)
+		}
+		if got, _ := db.GetClient(client.ID); got.GetBalance() != models.MustParseMoney("1050.00") || len(history(t, db, client.ID)) != 1 {
+			t.Errorf("Expected the local withdrawal not to be stored, got %v", got.GetBalance())
+		}
+
+		if _, err := db.UpdateClientTx(missingID, nil, func(models.Client) error { return nil }); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected database.ErrClientNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Transfer", func(t *testing.T) {
+		db := open(t, newDB)
+
+		from := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("10000.00"))
+		to := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(0))
+		if err := db.CreateCorporateClient(from, nil); err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		if err := db.CreatePersonalClient(to, nil); err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+
+		transfer, err := db.Transfer(from.ID, to.ID, models.MustParseMoney("2500.00"), nil)
+		if err != nil {
+			t.Fatalf("Failed to transfer: %v", err)
+		}
+		if transfer.FromBalanceBefore != models.MustParseMoney("10000.00") || transfer.FromBalanceAfter != models.MustParseMoney("7500.00") ||
+			transfer.ToBalanceBefore != models.NewMoney(0) || transfer.ToBalanceAfter != models.MustParseMoney("2500.00") {
+			t.Errorf("Expected balances 10000.00 -> 7500.00 and 0.00 -> 2500.00, got %+v", transfer)
+		}
+		if _, err := db.Transfer(from.ID, to.ID, models.MustParseMoney("6000.00"), nil); !errors.Is(err, models.ErrWithdrawLimit) {
+			t.Errorf("Expected ErrWithdrawLimit, got %v", err)
+		}
+		if _, err := db.Transfer(from.ID, missingID, models.MustParseMoney("1.00"), nil); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected database.ErrClientNotFound, got %v", err)
+		}
+
+		updatedFrom, _ := db.GetClient(from.ID)
+		updatedTo, _ := db.GetClient(to.ID)
+		if updatedFrom.GetBalance() != models.MustParseMoney("7500.00") || updatedTo.GetBalance() != models.MustParseMoney("2500.00") {
+			t.Errorf("Expected balances 7500.00 and 2500.00, got %v and %v", updatedFrom.GetBalance(), updatedTo.GetBalance())
+		}
+	})
+
+	t.Run("GetStatement", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("1000.00"))
+		if err := db.CreatePersonalClient(client, nil); err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		for _, amount := range []string{"100.00", "200.00", "300.00"} {
+			if _, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+				return c.Deposit(models.MustParseMoney(amount), "")
+			}); err != nil {
+				t.Fatalf("Failed to deposit: %v", err)
+			}
+		}
+
+		statement, err := db.GetStatement(client.ID, database.StatementFilter{Limit: 2})
+		if err != nil {
+			t.Fatalf("Failed to get statement: %v", err)
+		}
+		if statement.Total != 3 || len(statement.Transactions) != 2 || statement.NextCursor == "" {
+			t.Errorf("Expected first page of 2 out of 3 transactions, got %+v", statement)
+		}
+		if statement.OpeningBalance != models.MustParseMoney("1000.00") || statement.ClosingBalance != models.MustParseMoney("1600.00") {
+			t.Errorf("Expected balances 1000.00 -> 1600.00, got %v -> %v", statement.OpeningBalance, statement.ClosingBalance)
+		}
+
+		next, err := db.GetStatement(client.ID, database.StatementFilter{Limit: 2, Cursor: statement.NextCursor})
+		if err != nil {
+			t.Fatalf("Failed to get statement: %v", err)
+		}
+		if len(next.Transactions) != 1 || next.Transactions[0].Amount != models.MustParseMoney("300.00") {
+			t.Errorf("Expected last deposit on second page, got %+v", next.Transactions)
+		}
+
+		if _, err := db.GetStatement(missingID, database.StatementFilter{}); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected database.ErrClientNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ListClients", func(t *testing.T) {
+		db := open(t, newDB)
+
+		clients := []models.Client{
+			newPersonalClient(t, "Ana", "529.982.247-25", models.MustParseMoney("300.00")),
+			newPersonalClient(t, "Bruno", "123.456.789-09", models.MustParseMoney("100.00")),
+			newCorporateClient(t, "Carla Ltda", "11.222.333/0001-81", models.MustParseMoney("200.00")),
+		}
+		for _, c := range clients {
+			create(t, db, c)
+		}
+
+		var names []string
+		filter := database.ClientFilter{SortBy: database.SortByBalance, Limit: 2}
+		for {
+			page, err := db.ListClients(filter)
+			if err != nil {
+				t.Fatalf("Failed to list clients: %v", err)
+			}
+			if page.Total != 3 {
+				t.Errorf("Expected total of 3, got %d", page.Total)
+			}
+			for _, c := range page.Clients {
+				names = append(names, c.GetName())
+				if len(c.GetStatement()) != 0 {
+					t.Errorf("Expected listing without transactions, got %d", len(c.GetStatement()))
+				}
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			filter.Cursor = page.NextCursor
+		}
+		if len(names) != 3 || names[0] != "Bruno" || names[1] != "Carla Ltda" || names[2] != "Ana" {
+			t.Errorf("Expected clients ordered by balance, got %v", names)
+		}
+
+		page, err := db.ListClients(database.ClientFilter{ClientType: "personal", NamePrefix: "an"})
+		if err != nil {
+			t.Fatalf("Failed to list clients: %v", err)
+		}
+		if page.Total != 1 || page.Clients[0].GetName() != "Ana" {
+			t.Errorf("Expected only Ana, got %+v", page.Clients)
+		}
+	})
+	t.Run("UpdateProfile", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(0))
+		create(t, db, client)
+
+		name, email := "John Smith", "john@example.com"
+		_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.UpdateProfile(models.ProfileUpdate{Name: &name, Email: &email})
+		})
+		if err != nil {
+			t.Fatalf("Failed to update profile: %v", err)
+		}
+
+		got, _ := db.GetClient(client.ID)
+		want := models.Profile{Name: name, Email: email}
+		if got.GetProfile() != want {
+			t.Errorf("Expected %+v, got %+v", want, got.GetProfile())
+		}
+	})
+
+	t.Run("LimitOverride", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("5000.00"))
+		create(t, db, client)
+
+		perTransaction, daily := models.MustParseMoney("2000.00"), models.MustParseMoney("2500.00")
+		_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.SetLimitOverride(models.LimitOverride{PerTransaction: &perTransaction, Daily: &daily})
+		})
+		if err != nil {
+			t.Fatalf("Failed to set limits: %v", err)
+		}
+
+		got, _ := db.GetClient(client.ID)
+		limits := got.GetLimits()
+		if limits.PerTransaction != perTransaction || limits.Daily != daily || limits.Monthly != models.PersonalClientLimits.Monthly {
+			t.Errorf("Expected override to be persisted, got %+v", limits)
+		}
+
+		// O limite diário considera os saques já gravados
+		if _, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("2000.00"))
+		}); err != nil {
+			t.Fatalf("Failed to withdraw: %v", err)
+		}
+		_, err = db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("600.00"))
+		})
+		var limitErr *models.LimitExceededError
+		if !errors.As(err, &limitErr) || limitErr.Limit != models.LimitDaily {
+			t.Errorf("Expected daily limit error, got %v", err)
+		}
+
+		// Um override vazio volta aos limites padrão
+		updated, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.SetLimitOverride(models.LimitOverride{})
+		})
+		if err != nil {
+			t.Fatalf("Failed to reset limits: %v", err)
+		}
+		if updated.GetLimits() != models.PersonalClientLimits {
+			t.Errorf("Expected default limits, got %+v", updated.GetLimits())
+		}
+	})
+
+	t.Run("CreditLimit", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("1000.00"))
+		create(t, db, client)
+
+		_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.SetCreditLimit(models.MustParseMoney("2000.00"))
+		})
+		if err != nil {
+			t.Fatalf("Failed to set credit limit: %v", err)
+		}
+
+		updated, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("2500.00"))
+		})
+		if err != nil {
+			t.Fatalf("Failed to withdraw into overdraft: %v", err)
+		}
+		if updated.GetBalance() != models.MustParseMoney("-1500.00") || updated.GetAvailableBalance() != models.MustParseMoney("500.00") {
+			t.Errorf("Expected balance -1500.00 and 500.00 available, got %v and %v", updated.GetBalance(), updated.GetAvailableBalance())
+		}
+
+		got, _ := db.GetClient(client.ID)
+		if got.GetCreditLimit() != models.MustParseMoney("2000.00") || got.GetBalance() != models.MustParseMoney("-1500.00") {
+			t.Errorf("Expected credit limit and negative balance to be persisted, got %v and %v", got.GetCreditLimit(), got.GetBalance())
+		}
+		statement := history(t, db, client.ID)
+		if len(statement) != 1 || statement[0].OverdraftAmount == nil || *statement[0].OverdraftAmount != models.MustParseMoney("1500.00") {
+			t.Errorf("Expected 1500.00 of overdraft in the statement, got %+v", statement)
+		}
+
+		_, err = db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("500.01"))
+		})
+		if !errors.Is(err, models.ErrInsufficientFunds) {
+			t.Errorf("Expected ErrInsufficientFunds, got %v", err)
+		}
+	})
+
+	t.Run("Reversal", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("1000.00"))
+		create(t, db, client)
+
+		withdrawn, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("400.00"))
+		})
+		if err != nil {
+			t.Fatalf("Failed to withdraw: %v", err)
+		}
+		txID := withdrawn.GetStatement()[0].ID
+
+		original, err := db.GetTransaction(client.ID, txID)
+		if err != nil {
+			t.Fatalf("Failed to get transaction: %v", err)
+		}
+		if original.Type != models.TransactionTypeWithdrawal || original.Amount != models.MustParseMoney("400.00") {
+			t.Errorf("Expected the 400.00 withdrawal, got %+v", original)
+		}
+		if _, err := db.GetTransaction(client.ID, missingID); !errors.Is(err, models.ErrTransactionNotFound) {
+			t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+		}
+		if _, err := db.GetTransaction(missingID, txID); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected database.ErrClientNotFound, got %v", err)
+		}
+
+		_, err = db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			_, err := c.Reverse(*original, "Saque em duplicidade")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Failed to reverse: %v", err)
+		}
+
+		got, _ := db.GetClient(client.ID)
+		statement := history(t, db, client.ID)
+		if got.GetBalance() != models.MustParseMoney("1000.00") || len(statement) != 2 {
+			t.Fatalf("Expected balance 1000.00 and two transactions, got %v and %+v", got.GetBalance(), statement)
+		}
+		if statement[1].ReversedTransactionID != txID || statement[1].Type != models.TransactionTypeReversalCredit {
+			t.Errorf("Expected a reversal linked to %s, got %+v", txID, statement[1])
+		}
+
+		// O cliente carregado não traz o estorno anterior: quem o recusa é o banco
+		_, err = db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			_, err := c.Reverse(*original, "De novo")
+			return err
+		})
+		if !errors.Is(err, models.ErrAlreadyReversed) {
+			t.Errorf("Expected ErrAlreadyReversed, got %v", err)
+		}
+	})
+
+	t.Run("LimitUsage", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("10000.00"))
+		create(t, db, client)
+
+		var txID string
+		for i := 0; i < 4; i++ {
+			updated, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+				return c.Withdraw(models.MustParseMoney("1000.00"))
+			})
+			if err != nil {
+				t.Fatalf("Failed to withdraw: %v", err)
+			}
+			txID = updated.GetStatement()[0].ID
+		}
+		original, _ := db.GetTransaction(client.ID, txID)
+		if _, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			_, err := c.Reverse(*original, "Saque em duplicidade")
+			return err
+		}); err != nil {
+			t.Fatalf("Failed to reverse: %v", err)
+		}
+
+		// O uso vem do banco, sem o histórico, e ignora o saque estornado
+		got, _ := db.GetClient(client.ID)
+		want := models.MustParseMoney("3000.00")
+		if used := got.GetLimitUsage(); used.Daily != want || used.Monthly != want {
+			t.Errorf("Expected %v used today and this month, got %+v", want, used)
+		}
+
+		var limitErr *models.LimitExceededError
+		_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			for _, amount := range []string{"1000.00", "1000.00", "0.01"} {
+				if err := c.Withdraw(models.MustParseMoney(amount)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if !errors.As(err, &limitErr) || limitErr.Limit != models.LimitDaily || limitErr.Used != models.MustParseMoney("5000.00") {
+			t.Errorf("Expected daily limit error with 5000.00 used, got %v", err)
+		}
+	})
+
+	t.Run("Postings", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("1000.00"))
+		create(t, db, client)
+
+		// TIMESTAMPTZ guarda microssegundos
+		at := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+		post := func(c models.Client) error {
+			return c.CreditInterest(models.MustParseMoney("10.00"), "interest:test", at)
+		}
+		if _, err := db.UpdateClientTx(client.ID, nil, post); err != nil {
+			t.Fatalf("Failed to post interest: %v", err)
+		}
+		if _, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Deposit(models.MustParseMoney("50.00"), "")
+		}); err != nil {
+			t.Fatalf("Failed to deposit: %v", err)
+		}
+
+		if posted, err := db.HasPosting(client.ID, "interest:test"); err != nil || !posted {
+			t.Errorf("Expected the posting to be found, got %v and %v", posted, err)
+		}
+		if posted, err := db.HasPosting(client.ID, "interest:other"); err != nil || posted {
+			t.Errorf("Expected no other posting, got %v and %v", posted, err)
+		}
+		if _, err := db.HasPosting(missingID, "interest:test"); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected database.ErrClientNotFound, got %v", err)
+		}
+		if _, err := db.UpdateClientTx(client.ID, nil, post); !errors.Is(err, database.ErrConflict) {
+			t.Errorf("Expected a repeated posting key to conflict, got %v", err)
+		}
+
+		// O saldo em at inclui o lançamento feito exatamente em at
+		if balance, err := db.BalanceAt(client.ID, at); err != nil || balance != models.MustParseMoney("1010.00") {
+			t.Errorf("Expected balance 1010.00 at the posting, got %v and %v", balance, err)
+		}
+		if balance, _ := db.BalanceAt(client.ID, at.Add(-time.Microsecond)); balance != models.MustParseMoney("1000.00") {
+			t.Errorf("Expected balance 1000.00 before the posting, got %v", balance)
+		}
+		if _, err := db.BalanceAt(missingID, at); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected database.ErrClientNotFound, got %v", err)
+		}
+	})
+
+	t.Run("DeleteClient", func(t *testing.T) {
+		db := open(t, newDB)
+
+		rich := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("10.00"))
+		empty := newPersonalClient(t, "Jane Doe", "123.456.789-09", models.NewMoney(0))
+		create(t, db, rich)
+		create(t, db, empty)
+
+		if err := db.DeleteClient(rich.ID, nil); !errors.Is(err, models.ErrNonZeroBalance) {
+			t.Errorf("Expected ErrNonZeroBalance, got %v", err)
+		}
+		if err := db.DeleteClient(empty.ID, nil); err != nil {
+			t.Fatalf("Failed to close account: %v", err)
+		}
+		if err := db.DeleteClient(empty.ID, nil); !errors.Is(err, models.ErrAccountClosed) {
+			t.Errorf("Expected ErrAccountClosed, got %v", err)
+		}
+		if err := db.DeleteClient(missingID, nil); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected ErrClientNotFound, got %v", err)
+		}
+
+		// A conta encerrada continua consultável, mas não aceita operações
+		closed, err := db.GetClient(empty.ID)
+		if err != nil {
+			t.Fatalf("Failed to get closed client: %v", err)
+		}
+		if closed.GetClosedAt() == nil {
+			t.Error("Expected closed_at to be set")
+		}
+		if _, err := db.Transfer(rich.ID, empty.ID, models.MustParseMoney("5.00"), nil); !errors.Is(err, models.ErrAccountClosed) {
+			t.Errorf("Expected ErrAccountClosed, got %v", err)
+		}
+
+		page, err := db.ListClients(database.ClientFilter{})
+		if err != nil {
+			t.Fatalf("Failed to list clients: %v", err)
+		}
+		if page.Total != 1 || page.Clients[0].GetID() != rich.ID {
+			t.Errorf("Expected closed account to be hidden, got %d clients", page.Total)
+		}
+
+		page, err = db.ListClients(database.ClientFilter{IncludeClosed: true})
+		if err != nil {
+			t.Fatalf("Failed to list clients: %v", err)
+		}
+		if page.Total != 2 {
+			t.Errorf("Expected 2 clients including closed ones, got %d", page.Total)
+		}
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		db := open(t, newDB)
+
+		john := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("10.00"))
+		jane := newPersonalClient(t, "Jane Doe", "123.456.789-09", models.NewMoney(0))
+		if err := db.CreatePersonalClient(john, &database.AuditEntry{Actor: "tester", Action: "clients:create_personal"}); err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		create(t, db, jane)
+
+		withdraw := &database.AuditEntry{Actor: "tester", Action: "clients:withdraw"}
+		if _, err := db.UpdateClientTx(john.ID, withdraw, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("3.00"))
+		}); err != nil {
+			t.Fatalf("Failed to withdraw: %v", err)
+		}
+		// Uma operação que falha não deixa registro
+		if _, err := db.UpdateClientTx(john.ID, withdraw, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("500.00"))
+		}); err == nil {
+			t.Fatal("Expected the withdrawal to fail")
+		}
+		if _, err := db.Transfer(john.ID, jane.ID, models.MustParseMoney("2.00"), &database.AuditEntry{Actor: "tester", Action: "transfers:create"}); err != nil {
+			t.Fatalf("Failed to transfer: %v", err)
+		}
+
+		page, err := db.Audit().List(database.AuditFilter{Actor: "tester"})
+		if err != nil {
+			t.Fatalf("Failed to list audit entries: %v", err)
+		}
+		want := []struct {
+			action, clientID string
+			before, after    *models.Money
+		}{
+			{"clients:create_personal", john.ID, nil, moneyPtr("10.00")},
+			{"clients:withdraw", john.ID, moneyPtr("10.00"), moneyPtr("7.00")},
+			{"transfers:create", john.ID, moneyPtr("7.00"), moneyPtr("5.00")},
+			{"transfers:create", jane.ID, moneyPtr("0.00"), moneyPtr("2.00")},
+		}
+		if len(page.Entries) != len(want) {
+			t.Fatalf("Expected %d audit entries, got %+v", len(want), page.Entries)
+		}
+		for i, w := range want {
+			got := page.Entries[i]
+			if got.Action != w.action || got.ClientID != w.clientID || !equalMoney(got.BalanceBefore, w.before) || !equalMoney(got.BalanceAfter, w.after) {
+				t.Errorf("Entry %d: expected %s on %s (%v -> %v), got %+v", i, w.action, w.clientID, w.before, w.after, got)
+			}
+		}
+	})
+
+	t.Run("InitTables", func(t *testing.T) {
+		db := open(t, newDB)
+
+		// InitTables já foi chamada pela factory e deve poder ser repetida
+		if err := db.InitTables(); err != nil {
+			t.Errorf("Expected InitTables to be idempotent, got %v", err)
+		}
+	})
+
+	t.Run("IsolatedCopies", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("100.00"))
+		create(t, db, client)
+		if err := client.Deposit(models.MustParseMoney("50.00"), ""); err != nil {
+			t.Fatalf("Failed to deposit: %v", err)
+		}
+
+		got, _ := db.GetClient(client.ID)
+		if got.GetBalance() != models.MustParseMoney("100.00") || len(history(t, db, client.ID)) != 0 {
+			t.Errorf("Expected stored client to ignore changes made after creation, got %v", got.GetBalance())
+		}
+
+		if err := got.Deposit(models.MustParseMoney("10.00"), ""); err != nil {
+			t.Fatalf("Failed to deposit: %v", err)
+		}
+		if stored := history(t, db, client.ID); len(stored) != 0 {
+			t.Errorf("Expected unsaved transactions to stay out of the store, got %d", len(stored))
+		}
+
+		// Os ponteiros dos limites e do cheque especial também são copiados
+		daily := models.MustParseMoney("1500.00")
+		updated, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			if err := c.SetLimitOverride(models.LimitOverride{Daily: &daily}); err != nil {
+				return err
+			}
+			if err := c.SetCreditLimit(models.MustParseMoney("100.00")); err != nil {
+				return err
+			}
+			return c.Withdraw(models.MustParseMoney("150.00"))
+		})
+		if err != nil {
+			t.Fatalf("Failed to update client: %v", err)
+		}
+		txID := updated.GetStatement()[0].ID
+		got, _ = db.GetClient(client.ID)
+		*got.GetLimitOverride().Daily = models.MustParseMoney("1.00")
+		tx, _ := db.GetTransaction(client.ID, txID)
+		*tx.OverdraftAmount = models.MustParseMoney("1.00")
+
+		again, _ := db.GetClient(client.ID)
+		stored, _ := db.GetTransaction(client.ID, txID)
+		if again.GetLimits().Daily != daily || *stored.OverdraftAmount != models.MustParseMoney("50.00") {
+			t.Errorf("Expected changes to a returned client not to reach the store, got limits %+v and transaction %+v",
+				again.GetLimits(), stored)
+		}
+	})
+
+	t.Run("SameClientTransfer", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("100.00"))
+		create(t, db, client)
+
+		if _, err := db.Transfer(client.ID, client.ID, models.MustParseMoney("10.00"), nil); !errors.Is(err, models.ErrSameClient) {
+			t.Errorf("Expected ErrSameClient, got %v", err)
+		}
+	})
+
+	t.Run("ConcurrentUpdateClientTx", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("1000.00"))
+		create(t, db, client)
+
+		const withdrawals = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, withdrawals)
+		for i := 0; i < withdrawals; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+					return c.Withdraw(models.MustParseMoney("10.00"))
+				})
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("Failed to withdraw: %v", err)
+			}
+		}
+
+		// Nenhum débito pode ter sido perdido
+		updated, _ := db.GetClient(client.ID)
+		if updated.GetBalance() != models.MustParseMoney("800.00") {
+			t.Errorf("Expected balance of 800.00, got %v", updated.GetBalance())
+		}
+		if stored := history(t, db, client.ID); len(stored) != withdrawals {
+			t.Errorf("Expected %d transactions, got %d", withdrawals, len(stored))
+		}
+	})
+
+	t.Run("LargeHistory", func(t *testing.T) {
+		db := open(t, newDB)
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(0))
+		create(t, db, client)
+
+		const deposits = 250
+		for i := 1; i <= deposits; i++ {
+			_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+				return c.Deposit(models.NewMoney(int64(i)), fmt.Sprintf("Depósito %d", i))
+			})
+			if err != nil {
+				t.Fatalf("Failed to deposit %d: %v", i, err)
+			}
+		}
+
+		// GetClient não carrega o histórico
+		got, err := db.GetClient(client.ID)
+		if err != nil {
+			t.Fatalf("Failed to get client: %v", err)
+		}
+		if len(got.GetStatement()) != 0 {
+			t.Errorf("Expected no transactions on the client, got %d", len(got.GetStatement()))
+		}
+		if got.GetBalance() != models.NewMoney(deposits*(deposits+1)/2) {
+			t.Errorf("Expected balance of %v, got %v", models.NewMoney(deposits*(deposits+1)/2), got.GetBalance())
+		}
+
+		// Percorre o extrato inteiro em ordem decrescente, página a página
+		seen := 0
+		filter := database.StatementFilter{Descending: true, Limit: database.MaxPageSize}
+		for {
+			page, err := db.GetStatement(client.ID, filter)
+			if err != nil {
+				t.Fatalf("Failed to get statement: %v", err)
+			}
+			for _, tx := range page.Transactions {
+				if tx.Amount != models.NewMoney(int64(deposits-seen)) {
+					t.Fatalf("Expected descending order, got %v after %d transactions", tx.Amount, seen)
+				}
+				seen++
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			filter.Cursor = page.NextCursor
+		}
+		if seen != deposits {
+			t.Errorf("Expected %d transactions across pages, got %d", deposits, seen)
+		}
+	})
+}
+
+// history retorna as transações gravadas do cliente, até database.MaxPageSize
+func history(t *testing.T, db database.Database, id string) []models.Transaction {
+	t.Helper()
+	statement, err := db.GetStatement(id, database.StatementFilter{Limit: database.MaxPageSize})
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	return statement.Transactions
+}
+
+// open cria o banco pela factory e o fecha ao fim do teste
+func open(t *testing.T, newDB Factory) database.Database {
+	t.Helper()
+	db := newDB(t)
+	t.Cleanup(func() {
+		db.Close()
+	})
+	return db
+}
+
+func create(t *testing.T, db database.Database, client models.Client) {
+	t.Helper()
+	var err error
+	switch c := client.(type) {
+	case *models.PersonalClient:
+		err = db.CreatePersonalClient(c, nil)
+	case *models.CorporateClient:
+		err = db.CreateCorporateClient(c, nil)
+	default:
+		t.Fatalf("Unknown client type %T", client)
+	}
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+}
+
+func newPersonalClient(t *testing.T, name, cpf string, balance models.Money) *models.PersonalClient {
+	t.Helper()
+	client, err := models.NewPersonalClient(name, cpf, balance)
+	if err != nil {
+		t.Fatalf("Failed to build personal client: %v", err)
+	}
+	return client
+}
+
+func newCorporateClient(t *testing.T, name, cnpj string, balance models.Money) *models.CorporateClient {
+	t.Helper()
+	client, err := models.NewCorporateClient(name, cnpj, balance)
+	if err != nil {
+		t.Fatalf("Failed to build corporate client: %v", err)
+	}
+	return client
+}
+
+func moneyPtr(s string) *models.Money {
+	m := models.MustParseMoney(s)
+	return &m
+}
+
+func equalMoney(a, b *models.Money) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,187 @@
+package dbtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// Stores agrupa os repositórios que um HolderStore consulta
+type Stores struct {
+	Clients database.Database
+	Users   database.UserStore
+	Holders database.HolderStore
+}
+
+// StoresFactory cria repositórios vazios que compartilham o mesmo banco
+type StoresFactory func(t *testing.T) Stores
+
+// RunHolderConformance executa os testes de contrato de database.HolderStore
+func RunHolderConformance(t *testing.T, newStores StoresFactory) {
+	setup := func(t *testing.T) (Stores, *models.PersonalClient, *models.User, *models.User) {
+		stores := newStores(t)
+		t.Cleanup(func() {
+			stores.Clients.Close()
+		})
+
+		client := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(0))
+		create(t, stores.Clients, client)
+		return stores, client, insertUser(t, stores.Users, "John"), insertUser(t, stores.Users, "Jane")
+	}
+
+	t.Run("GrantAndList", func(t *testing.T) {
+		stores, client, john, jane := setup(t)
+
+		if _, err := stores.Holders.GrantAccess(client.ID, john.ID, models.RoleOwner); err != nil {
+			t.Fatalf("Failed to grant access: %v", err)
+		}
+		if _, err := stores.Holders.GrantAccess(client.ID, jane.ID, models.RoleViewer); err != nil {
+			t.Fatalf("Failed to grant access: %v", err)
+		}
+
+		// Conceder de novo altera o papel sem duplicar o acesso
+		holder, err := stores.Holders.GrantAccess(client.ID, jane.ID, models.RoleOperator)
+		if err != nil {
+			t.Fatalf("Failed to change role: %v", err)
+		}
+		if holder.Role != models.RoleOperator || holder.ClientID != client.ID || holder.UserID != jane.ID {
+			t.Errorf("Unexpected holder %+v", holder)
+		}
+
+		holders, err := stores.Holders.ListHolders(client.ID)
+		if err != nil {
+			t.Fatalf("Failed to list holders: %v", err)
+		}
+		if len(holders) != 2 || holders[0].UserID != john.ID || holders[1].Role != models.RoleOperator {
+			t.Errorf("Expected john as owner and jane as operator, got %+v", holders)
+		}
+
+		accounts, err := stores.Holders.ListUserAccounts(jane.ID)
+		if err != nil {
+			t.Fatalf("Failed to list accounts: %v", err)
+		}
+		if len(accounts) != 1 || accounts[0].Client.GetID() != client.ID || accounts[0].Role != models.RoleOperator {
+			t.Errorf("Expected jane to operate %s, got %+v", client.ID, accounts)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		stores, client, john, _ := setup(t)
+
+		if _, err := stores.Holders.GrantAccess(client.ID, john.ID, "admin"); !errors.Is(err, models.ErrInvalidRole) {
+			t.Errorf("Expected ErrInvalidRole, got %v", err)
+		}
+		if _, err := stores.Holders.GrantAccess(missingID, john.ID, models.RoleOwner); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected ErrClientNotFound, got %v", err)
+		}
+		if _, err := stores.Holders.GrantAccess(client.ID, missingID, models.RoleOwner); !errors.Is(err, database.ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+		if err := stores.Holders.RevokeAccess(client.ID, john.ID); !errors.Is(err, database.ErrHolderNotFound) {
+			t.Errorf("Expected ErrHolderNotFound, got %v", err)
+		}
+		if _, err := stores.Holders.ListHolders(missingID); !errors.Is(err, database.ErrClientNotFound) {
+			t.Errorf("Expected ErrClientNotFound, got %v", err)
+		}
+		if _, err := stores.Holders.ListUserAccounts(missingID); !errors.Is(err, database.ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("LastOwner", func(t *testing.T) {
+		stores, client, john, jane := setup(t)
+
+		if _, err := stores.Holders.GrantAccess(client.ID, john.ID, models.RoleOwner); err != nil {
+			t.Fatalf("Failed to grant access: %v", err)
+		}
+		if err := stores.Holders.RevokeAccess(client.ID, john.ID); !errors.Is(err, database.ErrLastOwner) || !errors.Is(err, database.ErrConflict) {
+			t.Errorf("Expected ErrLastOwner, got %v", err)
+		}
+		if _, err := stores.Holders.GrantAccess(client.ID, john.ID, models.RoleViewer); !errors.Is(err, database.ErrLastOwner) {
+			t.Errorf("Expected ErrLastOwner on downgrade, got %v", err)
+		}
+
+		// Com um segundo titular, o primeiro pode sair
+		if _, err := stores.Holders.GrantAccess(client.ID, jane.ID, models.RoleOwner); err != nil {
+			t.Fatalf("Failed to grant access: %v", err)
+		}
+		if err := stores.Holders.RevokeAccess(client.ID, john.ID); err != nil {
+			t.Errorf("Failed to revoke access: %v", err)
+		}
+		if accounts, _ := stores.Holders.ListUserAccounts(john.ID); len(accounts) != 0 {
+			t.Errorf("Expected john to have no accounts, got %+v", accounts)
+		}
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		stores, client, john, jane := setup(t)
+
+		if _, err := stores.Holders.GrantAccess(client.ID, john.ID, models.RoleOwner); err != nil {
+			t.Fatalf("Failed to grant access: %v", err)
+		}
+		if _, err := stores.Holders.GrantAccess(client.ID, jane.ID, models.RoleViewer); err != nil {
+			t.Fatalf("Failed to grant access: %v", err)
+		}
+
+		// O último titular não pode ser removido
+		if _, err := stores.Holders.DeleteUser(john.ID); !errors.Is(err, database.ErrLastOwner) {
+			t.Errorf("Expected ErrLastOwner, got %v", err)
+		}
+		if _, err := stores.Users.FindByID(john.ID); err != nil {
+			t.Errorf("Expected john to be kept, got %v", err)
+		}
+
+		// Outros usuários são removidos junto com os seus acessos
+		deleted, err := stores.Holders.DeleteUser(jane.ID)
+		if err != nil {
+			t.Fatalf("Failed to delete user: %v", err)
+		}
+		if deleted.ID != jane.ID {
+			t.Errorf("Expected the deleted user to be returned, got %+v", deleted)
+		}
+		holders, err := stores.Holders.ListHolders(client.ID)
+		if err != nil {
+			t.Fatalf("Failed to list holders: %v", err)
+		}
+		if len(holders) != 1 || holders[0].UserID != john.ID {
+			t.Errorf("Expected only john to be left, got %+v", holders)
+		}
+
+		// Com outro titular, john pode sair
+		if _, err := stores.Holders.GrantAccess(client.ID, insertUser(t, stores.Users, "Mary").ID, models.RoleOwner); err != nil {
+			t.Fatalf("Failed to grant access: %v", err)
+		}
+		if _, err := stores.Holders.DeleteUser(john.ID); err != nil {
+			t.Errorf("Failed to delete user: %v", err)
+		}
+		if _, err := stores.Holders.DeleteUser(missingID); !errors.Is(err, database.ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ClosedAccount", func(t *testing.T) {
+		stores, client, john, _ := setup(t)
+
+		if err := stores.Clients.DeleteClient(client.ID, nil); err != nil {
+			t.Fatalf("Failed to close account: %v", err)
+		}
+		if _, err := stores.Holders.GrantAccess(client.ID, john.ID, models.RoleOwner); !errors.Is(err, models.ErrAccountClosed) {
+			t.Errorf("Expected ErrAccountClosed, got %v", err)
+		}
+	})
+}
+
+func insertUser(t *testing.T, users database.UserStore, firstName string) *models.User {
+	t.Helper()
+	user, err := models.NewUser(firstName, "Doe", "A biography with more than twenty characters")
+	if err != nil {
+		t.Fatalf("Failed to build user: %v", err)
+	}
+	user, err = users.Insert(user)
+	if err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,118 @@
+package dbtest
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+)
+
+// IdempotencyFactory cria um repositório de chaves de idempotência vazio
+type IdempotencyFactory func(t *testing.T) database.IdempotencyStore
+
+// RunIdempotencyConformance executa os testes de contrato de
+// database.IdempotencyStore
+func RunIdempotencyConformance(t *testing.T, newStore IdempotencyFactory) {
+	t.Run("BeginCompleteReplay", func(t *testing.T) {
+		store := newStore(t)
+
+		record, started, err := store.Begin("billing", "key-1", "hash-1")
+		if err != nil {
+			t.Fatalf("Failed to begin: %v", err)
+		}
+		if !started || record.RequestHash != "hash-1" || record.Response != nil {
+			t.Fatalf("Expected a new pending record, got %+v (started %v)", record, started)
+		}
+
+		// Enquanto pendente, a chave é devolvida sem resposta
+		record, started, err = store.Begin("billing", "key-1", "hash-1")
+		if err != nil {
+			t.Fatalf("Failed to begin: %v", err)
+		}
+		if started || record.Response != nil {
+			t.Errorf("Expected the pending record, got %+v (started %v)", record, started)
+		}
+
+		response := database.IdempotentResponse{StatusCode: 201, ContentType: "application/json", Body: []byte(`{"id":"1"}`)}
+		if err := store.Complete("billing", "key-1", response); err != nil {
+			t.Fatalf("Failed to complete: %v", err)
+		}
+
+		record, started, err = store.Begin("billing", "key-1", "hash-2")
+		if err != nil {
+			t.Fatalf("Failed to begin: %v", err)
+		}
+		if started || record.RequestHash != "hash-1" || record.Response == nil {
+			t.Fatalf("Expected the completed record, got %+v (started %v)", record, started)
+		}
+		if record.Response.StatusCode != 201 || record.Response.ContentType != "application/json" || !bytes.Equal(record.Response.Body, response.Body) {
+			t.Errorf("Expected stored response %+v, got %+v", response, record.Response)
+		}
+
+		// Completar não pode ser desfeito por Release
+		if err := store.Release("billing", "key-1"); err != nil {
+			t.Fatalf("Failed to release: %v", err)
+		}
+		if _, started, _ := store.Begin("billing", "key-1", "hash-1"); started {
+			t.Error("Expected a completed key to survive Release")
+		}
+	})
+
+	t.Run("Scopes", func(t *testing.T) {
+		store := newStore(t)
+
+		if _, started, err := store.Begin("billing", "key-1", "hash-1"); err != nil || !started {
+			t.Fatalf("Failed to begin: %v", err)
+		}
+		if _, started, err := store.Begin("mobile", "key-1", "hash-1"); err != nil || !started {
+			t.Errorf("Expected the same key in another scope to start, got started %v err %v", started, err)
+		}
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		store := newStore(t)
+
+		if _, _, err := store.Begin("billing", "key-1", "hash-1"); err != nil {
+			t.Fatalf("Failed to begin: %v", err)
+		}
+		if err := store.Release("billing", "key-1"); err != nil {
+			t.Fatalf("Failed to release: %v", err)
+		}
+		if _, started, err := store.Begin("billing", "key-1", "hash-2"); err != nil || !started {
+			t.Errorf("Expected a released key to start again, got started %v err %v", started, err)
+		}
+	})
+
+	t.Run("ConcurrentBegin", func(t *testing.T) {
+		store := newStore(t)
+
+		const workers = 10
+		var (
+			wg      sync.WaitGroup
+			mutex   sync.Mutex
+			started int
+		)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, ok, err := store.Begin("billing", "key-1", "hash-1")
+				if err != nil {
+					t.Errorf("Failed to begin: %v", err)
+					return
+				}
+				if ok {
+					mutex.Lock()
+					started++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if started != 1 {
+			t.Errorf("Expected exactly one request to reserve the key, got %d", started)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package dbtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/google/uuid"
+)
+
+// ScheduleStores agrupa o repositório de agendamentos e o banco dos clientes
+// aos quais eles pertencem
+type ScheduleStores struct {
+	Clients   database.Database
+	Schedules database.ScheduleStore
+}
+
+// ScheduleFactory cria repositórios vazios que compartilham o mesmo banco
+type ScheduleFactory func(t *testing.T) ScheduleStores
+
+// RunScheduleConformance executa os testes de contrato de
+// database.ScheduleStore
+func RunScheduleConformance(t *testing.T, newStores ScheduleFactory) {
+	// Os horários são arredondados à precisão do TIMESTAMPTZ
+	now := time.Now().Truncate(time.Microsecond)
+
+	setup := func(t *testing.T) (ScheduleStores, *models.CorporateClient) {
+		stores := newStores(t)
+		t.Cleanup(func() {
+			stores.Clients.Close()
+		})
+
+		client := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("1000.00"))
+		create(t, stores.Clients, client)
+		return stores, client
+	}
+
+	schedule := func(t *testing.T, store database.ScheduleStore, clientID string, runAt time.Time) *models.ScheduledOperation {
+		t.Helper()
+		op, err := models.NewScheduledOperation(clientID, models.ScheduleSpec{
+			Type:   models.ScheduleTypeWithdrawal,
+			Amount: models.MustParseMoney("10.00"),
+			RunAt:  &runAt,
+		}, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to build scheduled operation: %v", err)
+		}
+		if err := store.CreateSchedule(op); err != nil {
+			t.Fatalf("Failed to create scheduled operation: %v", err)
+		}
+		return op
+	}
+
+	t.Run("CreateGetList", func(t *testing.T) {
+		stores, client := setup(t)
+
+		op := schedule(t, stores.Schedules, client.ID, now.Add(time.Hour))
+		schedule(t, stores.Schedules, client.ID, now.Add(2*time.Hour))
+
+		got, err := stores.Schedules.GetSchedule(op.ID)
+		if err != nil {
+			t.Fatalf("Failed to get scheduled operation: %v", err)
+		}
+		if got.ClientID != client.ID || got.Amount != op.Amount || got.Status != models.ScheduleStatusActive ||
+			got.NextRunAt == nil || !got.NextRunAt.Equal(*op.NextRunAt) {
+			t.Errorf("Expected %+v, got %+v", op, got)
+		}
+
+		if _, err := stores.Schedules.GetSchedule(missingID); !errors.Is(err, database.ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+
+		list, err := stores.Schedules.ListSchedules(client.ID)
+		if err != nil || len(list) != 2 {
+			t.Fatalf("Expected two scheduled operations, got %d (%v)", len(list), err)
+		}
+		if list, _ := stores.Schedules.ListSchedules(missingID); len(list) != 0 {
+			t.Errorf("Expected no scheduled operations for another client, got %d", len(list))
+		}
+	})
+
+	t.Run("UpdateChecksVersion", func(t *testing.T) {
+		stores, client := setup(t)
+		op := schedule(t, stores.Schedules, client.ID, now.Add(time.Hour))
+
+		stale, _ := stores.Schedules.GetSchedule(op.ID)
+		op.Status = models.ScheduleStatusRunning
+		op.ClaimedAt = &now
+		if err := stores.Schedules.UpdateSchedule(op); err != nil {
+			t.Fatalf("Failed to update scheduled operation: %v", err)
+		}
+
+		if err := stale.Cancel(now); err != nil {
+			t.Fatalf("Failed to cancel stale copy: %v", err)
+		}
+		if err := stores.Schedules.UpdateSchedule(stale); !errors.Is(err, database.ErrConflict) {
+			t.Errorf("Expected ErrConflict for a stale copy, got %v", err)
+		}
+
+		got, _ := stores.Schedules.GetSchedule(op.ID)
+		if got.Status != models.ScheduleStatusRunning || got.ClaimedAt == nil || !got.ClaimedAt.Equal(now) {
+			t.Errorf("Expected the running schedule to be kept, got %+v", got)
+		}
+
+		missing := *op
+		missing.ID = missingID
+		if err := stores.Schedules.UpdateSchedule(&missing); !errors.Is(err, database.ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ListDue", func(t *testing.T) {
+		stores, client := setup(t)
+
+		late := schedule(t, stores.Schedules, client.ID, now.Add(-10*time.Minute))
+		early := schedule(t, stores.Schedules, client.ID, now.Add(-20*time.Minute))
+		schedule(t, stores.Schedules, client.ID, now.Add(time.Hour))
+
+		// Uma nova tentativa vence em RetryAt, e não em NextRunAt
+		retry := schedule(t, stores.Schedules, client.ID, now.Add(-30*time.Minute))
+		retryAt := now.Add(5 * time.Minute)
+		retry.Attempts, retry.RetryAt = 1, &retryAt
+		if err := stores.Schedules.UpdateSchedule(retry); err != nil {
+			t.Fatalf("Failed to update scheduled operation: %v", err)
+		}
+
+		running := schedule(t, stores.Schedules, client.ID, now.Add(time.Hour))
+		running.Status, running.ClaimedAt = models.ScheduleStatusRunning, &now
+		if err := stores.Schedules.UpdateSchedule(running); err != nil {
+			t.Fatalf("Failed to update scheduled operation: %v", err)
+		}
+
+		due, err := stores.Schedules.ListDueSchedules(now, 10)
+		if err != nil {
+			t.Fatalf("Failed to list due scheduled operations: %v", err)
+		}
+		if len(due) != 3 || due[0].ID != early.ID || due[1].ID != late.ID || due[2].ID != running.ID {
+			t.Errorf("Expected early, late and running operations, got %+v", due)
+		}
+
+		if due, _ := stores.Schedules.ListDueSchedules(now, 1); len(due) != 2 || due[0].ID != early.ID {
+			t.Errorf("Expected the limit to apply to due operations only, got %+v", due)
+		}
+	})
+
+	t.Run("Runs", func(t *testing.T) {
+		stores, client := setup(t)
+		op := schedule(t, stores.Schedules, client.ID, now.Add(time.Hour))
+
+		for attempt := 1; attempt <= 3; attempt++ {
+			run := models.ScheduleRun{
+				ID:           uuid.New().String(),
+				ScheduleID:   op.ID,
+				ScheduledFor: *op.NextRunAt,
+				ExecutedAt:   now.Add(time.Duration(attempt) * time.Minute),
+				Attempt:      attempt,
+				Status:       models.ScheduleRunFailed,
+				Error:        "saldo insuficiente",
+			}
+			if err := stores.Schedules.RecordScheduleRun(op, run); err != nil {
+				t.Fatalf("Failed to record schedule run: %v", err)
+			}
+		}
+
+		runs, err := stores.Schedules.ListScheduleRuns(op.ID, 2)
+		if err != nil {
+			t.Fatalf("Failed to list schedule runs: %v", err)
+		}
+		if len(runs) != 2 || runs[0].Attempt != 3 || runs[1].Attempt != 2 || runs[0].Error != "saldo insuficiente" {
+			t.Errorf("Expected the two latest runs, got %+v", runs)
+		}
+	})
+
+	t.Run("RecordRun", func(t *testing.T) {
+		stores, client := setup(t)
+		op := schedule(t, stores.Schedules, client.ID, now.Add(-time.Minute))
+		stale := *op
+
+		run := models.ScheduleRun{
+			ID:           uuid.New().String(),
+			ScheduleID:   op.ID,
+			ScheduledFor: *op.NextRunAt,
+			ExecutedAt:   now,
+			Attempt:      1,
+			Status:       models.ScheduleRunFailed,
+			Error:        "saldo insuficiente",
+		}
+		op.LastError = run.Error
+		if err := stores.Schedules.RecordScheduleRun(op, run); err != nil {
+			t.Fatalf("Failed to record schedule run: %v", err)
+		}
+		if op.Version != 2 {
+			t.Errorf("Expected version 2, got %d", op.Version)
+		}
+
+		// Uma cópia desatualizada não grava o agendamento nem a execução
+		again := run
+		again.ID = uuid.New().String()
+		if err := stores.Schedules.RecordScheduleRun(&stale, again); !errors.Is(err, database.ErrConflict) {
+			t.Errorf("Expected ErrConflict, got %v", err)
+		}
+		if stale.Version != 1 {
+			t.Errorf("Expected the stale version to be kept, got %d", stale.Version)
+		}
+
+		runs, _ := stores.Schedules.ListScheduleRuns(op.ID, 10)
+		if len(runs) != 1 || runs[0].ID != run.ID {
+			t.Errorf("Expected only the first run, got %+v", runs)
+		}
+		got, _ := stores.Schedules.GetSchedule(op.ID)
+		if got.Version != 2 || got.LastError != run.Error {
+			t.Errorf("Expected the first update to be kept, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+// Erros do banco de dados. Os erros retornados pelas implementações de
+// Database podem estar embrulhados e devem ser comparados com errors.Is.
+var (
+	ErrUserNotFound   = errors.New("usuário não encontrado")
+	ErrClientNotFound = errors.New("cliente não encontrado")
+
+	// ErrConflict indica que a operação conflita com o estado atual dos dados,
+	// como um documento duplicado ou uma transação serializável abortada
+	ErrConflict = errors.New("conflito com os dados atuais")
+
+	// ErrUnavailable indica que o banco não pôde ser alcançado; a operação
+	// pode ser repetida mais tarde
+	ErrUnavailable = errors.New("banco de dados indisponível")
+
+	ErrHolderNotFound   = errors.New("titular não encontrado")
+	ErrScheduleNotFound = errors.New("agendamento não encontrado")
+
+	ErrDuplicateDocument error = &conflictError{"já existe um cliente com este documento"}
+	ErrLastOwner         error = &conflictError{"a conta precisa manter ao menos um titular owner"}
+)
+
+// conflictError é um conflito específico que também satisfaz errors.Is(err, ErrConflict)
+type conflictError struct {
+	message string
+}
+
+func (e *conflictError) Error() string {
+	return e.message
+}
+
+func (e *conflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// classify acrescenta ErrUnavailable ou ErrConflict aos erros do driver que
+// indicam falha de conexão ou conflito entre transações, preservando o erro
+// original na cadeia. Outros erros são retornados sem alteração.
+func classify(err error) error {
+	if err == nil || errors.Is(err, ErrUnavailable) || errors.Is(err, ErrConflict) {
+		return err
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		// 23505: violação de unicidade, 40001: falha de serialização, 40P01: deadlock
+		case pqErr.Code == "23505", pqErr.Code == "40001", pqErr.Code == "40P01":
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		// 08: exceção de conexão, 53: recursos insuficientes, 57: intervenção do operador
+		case pqErr.Code.Class() == "08", pqErr.Code.Class() == "53", pqErr.Code.Class() == "57":
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	return err
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{"unique violation", &pq.Error{Code: "23505"}, ErrConflict},
+		{"serialization failure", &pq.Error{Code: "40001"}, ErrConflict},
+		{"connection failure", &pq.Error{Code: "08006"}, ErrUnavailable},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, ErrUnavailable},
+		{"bad connection", driver.ErrBadConn, ErrUnavailable},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("error getting client: %w", classify(tt.err))
+			if !errors.Is(err, tt.target) {
+				t.Errorf("Expected %v to match %v", err, tt.target)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v to keep the original error", err)
+			}
+		})
+	}
+
+	if err := classify(&pq.Error{Code: "42P01"}); errors.Is(err, ErrConflict) || errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected undefined_table to stay unclassified, got %v", err)
+	}
+	if !errors.Is(ErrDuplicateDocument, ErrConflict) {
+		t.Error("Expected ErrDuplicateDocument to match ErrConflict")
+	}
+}
@@ -0,0 +1,4 @@
+package database
+
+// SetupTestDB expõe setupTestDB aos testes externos (package database_test)
+var SetupTestDB = setupTestDB
@@ -0,0 +1,280 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// Limites de paginação de ListClients
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Campos aceitos em ClientFilter.SortBy
+const (
+	SortByName    = "name"
+	SortByBalance = "balance"
+	SortByID      = "id"
+)
+
+// ClientFilter descreve os filtros, a ordenação e a paginação de ListClients
+type ClientFilter struct {
+	ClientType    string        // "personal" ou "corporate"
+	NamePrefix    string        // início do nome, sem diferenciar maiúsculas
+	MinBalance    *models.Money // saldo mínimo, inclusivo
+	MaxBalance    *models.Money // saldo máximo, inclusivo
+	Document      string        // CPF ou CNPJ
+	IncludeClosed bool          // inclui contas encerradas, omitidas por padrão
+	SortBy        string        // SortByName, SortByBalance ou SortByID
+	Descending    bool
+	Limit         int
+	Cursor        string // valor de ClientPage.NextCursor da página anterior
+}
+
+// ClientPage é uma página de resultados de ListClients
+type ClientPage struct {
+	Clients    []models.Client `json:"clients"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Total      int             `json:"total"`
+}
+
+// Validate verifica o filtro e preenche os valores padrão de ordenação e limite
+func (f *ClientFilter) Validate() error {
+	switch f.ClientType {
+	case "", "personal", "corporate":
+	default:
+		return errors.New("client_type deve ser personal ou corporate")
+	}
+
+	switch f.SortBy {
+	case "":
+		f.SortBy = SortByName
+	case SortByName, SortByBalance, SortByID:
+	default:
+		return errors.New("sort deve ser name, balance ou id")
+	}
+
+	if f.Limit == 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		return errors.New("limit deve estar entre 1 e 200")
+	}
+
+	if f.MinBalance != nil && f.MaxBalance != nil && f.MinBalance.Cmp(*f.MaxBalance) > 0 {
+		return errors.New("min_balance não pode ser maior que max_balance")
+	}
+
+	if f.Cursor != "" {
+		if _, err := decodeCursor(f.Cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pageCursor guarda a posição do último cliente retornado: o valor do campo
+// de ordenação e o ID usado como desempate
+type pageCursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.ID == "" {
+		return pageCursor{}, errors.New("cursor inválido")
+	}
+	return c, nil
+}
+
+// sortValue retorna o valor do campo de ordenação de um cliente
+func sortValue(client models.Client, sortBy string) string {
+	switch sortBy {
+	case SortByName:
+		return client.GetName()
+	case SortByBalance:
+		return client.GetBalance().String()
+	default:
+		return client.GetID()
+	}
+}
+
+// StatementFilter descreve o período, os filtros e a paginação de GetStatement
+type StatementFilter struct {
+	From       *time.Time // início do período, inclusivo
+	To         *time.Time // fim do período, inclusivo
+	Types      []string   // tipos de transação; vazio aceita todos
+	MinAmount  *models.Money
+	MaxAmount  *models.Money
+	Descending bool
+	Limit      int
+	Cursor     string // valor de Statement.NextCursor da página anterior
+}
+
+// Statement é uma página do extrato com os saldos do período consultado.
+// OpeningBalance é o saldo imediatamente antes de From e ClosingBalance o
+// saldo ao final de To, considerando todas as transações e não só as filtradas.
+type Statement struct {
+	OpeningBalance models.Money         `json:"opening_balance"`
+	ClosingBalance models.Money         `json:"closing_balance"`
+	Transactions   []models.Transaction `json:"transactions"`
+	NextCursor     string               `json:"next_cursor,omitempty"`
+	Total          int                  `json:"total"`
+}
+
+// Validate verifica o filtro e preenche o limite padrão
+func (f *StatementFilter) Validate() error {
+	if f.From != nil && f.To != nil && f.From.After(*f.To) {
+		return errors.New("from não pode ser posterior a to")
+	}
+
+	for _, t := range f.Types {
+		if !models.IsValidTransactionType(t) {
+			return fmt.Errorf("tipo de transação desconhecido: %s", t)
+		}
+	}
+
+	if f.MinAmount != nil && f.MaxAmount != nil && f.MinAmount.Cmp(*f.MaxAmount) > 0 {
+		return errors.New("min_amount não pode ser maior que max_amount")
+	}
+
+	if f.Limit == 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		return errors.New("limit deve estar entre 1 e 200")
+	}
+
+	if f.Cursor != "" {
+		if _, _, err := decodeStatementCursor(f.Cursor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matches indica se a transação atende aos filtros de tipo e valor
+func (f *StatementFilter) matches(t models.Transaction) bool {
+	if f.From != nil && t.CreatedAt.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && t.CreatedAt.After(*f.To) {
+		return false
+	}
+	if len(f.Types) > 0 {
+		found := false
+		for _, typ := range f.Types {
+			if typ == t.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.MinAmount != nil && t.Amount.Cmp(*f.MinAmount) < 0 {
+		return false
+	}
+	if f.MaxAmount != nil && t.Amount.Cmp(*f.MaxAmount) > 0 {
+		return false
+	}
+	return true
+}
+
+func encodeStatementCursor(t models.Transaction) string {
+	return encodeCursor(pageCursor{Value: t.CreatedAt.UTC().Format(time.RFC3339Nano), ID: t.ID})
+}
+
+func decodeStatementCursor(s string) (time.Time, string, error) {
+	c, err := decodeCursor(s)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return time.Time{}, "", errors.New("cursor inválido")
+	}
+	return createdAt, c.ID, nil
+}
+
+// buildStatement monta o extrato em memória a partir do saldo atual e do
+// histórico completo em ordem cronológica
+func buildStatement(balance models.Money, transactions []models.Transaction, filter StatementFilter) (*Statement, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	statement := &Statement{
+		OpeningBalance: balance,
+		ClosingBalance: balance,
+		Transactions:   make([]models.Transaction, 0),
+	}
+
+	var matched []models.Transaction
+	for _, t := range transactions {
+		if filter.From == nil || !t.CreatedAt.Before(*filter.From) {
+			statement.OpeningBalance = statement.OpeningBalance.Sub(t.SignedAmount())
+		}
+		if filter.To != nil && t.CreatedAt.After(*filter.To) {
+			statement.ClosingBalance = statement.ClosingBalance.Sub(t.SignedAmount())
+		}
+		if filter.matches(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if filter.Descending {
+			return transactionAfter(matched[i], matched[j])
+		}
+		return transactionAfter(matched[j], matched[i])
+	})
+	statement.Total = len(matched)
+
+	if filter.Cursor != "" {
+		createdAt, id, _ := decodeStatementCursor(filter.Cursor)
+		cursor := models.Transaction{ID: id, CreatedAt: createdAt}
+		for len(matched) > 0 {
+			if filter.Descending && transactionAfter(cursor, matched[0]) {
+				break
+			}
+			if !filter.Descending && transactionAfter(matched[0], cursor) {
+				break
+			}
+			matched = matched[1:]
+		}
+	}
+
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+		statement.NextCursor = encodeStatementCursor(matched[len(matched)-1])
+	}
+	statement.Transactions = append(statement.Transactions, matched...)
+
+	return statement, nil
+}
+
+// transactionAfter ordena por (created_at, id), a mesma ordem usada no SQL
+func transactionAfter(a, b models.Transaction) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+func TestBuildStatement(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	transactions := []models.Transaction{
+		{ID: "a", Type: models.TransactionTypeDeposit, Amount: models.MustParseMoney("500.00"), CreatedAt: start},
+		{ID: "b", Type: models.TransactionTypeWithdrawal, Amount: models.MustParseMoney("100.00"), CreatedAt: start.AddDate(0, 1, 0)},
+		{ID: "c", Type: models.TransactionTypeWithdrawal, Amount: models.MustParseMoney("50.00"), CreatedAt: start.AddDate(0, 1, 5)},
+		{ID: "d", Type: models.TransactionTypeTransferIn, Amount: models.MustParseMoney("25.00"), CreatedAt: start.AddDate(0, 2, 0)},
+	}
+	balance := models.MustParseMoney("1375.00")
+
+	// Fevereiro: saldo de abertura 1500, fechamento 1350
+	from := start.AddDate(0, 1, 0)
+	to := start.AddDate(0, 2, 0).Add(-time.Nanosecond)
+	statement, err := buildStatement(balance, transactions, StatementFilter{From: &from, To: &to})
+	if err != nil {
+		t.Fatalf("buildStatement failed: %v", err)
+	}
+	if statement.OpeningBalance != models.MustParseMoney("1500.00") {
+		t.Errorf("Expected opening balance of 1500.00, got %v", statement.OpeningBalance)
+	}
+	if statement.ClosingBalance != models.MustParseMoney("1350.00") {
+		t.Errorf("Expected closing balance of 1350.00, got %v", statement.ClosingBalance)
+	}
+	if statement.Total != 2 || statement.Transactions[0].ID != "b" || statement.Transactions[1].ID != "c" {
+		t.Errorf("Expected transactions b and c, got %+v", statement.Transactions)
+	}
+
+	// Filtros de tipo e valor não alteram os saldos do período
+	minAmount := models.MustParseMoney("60.00")
+	statement, err = buildStatement(balance, transactions, StatementFilter{
+		Types:     []string{models.TransactionTypeWithdrawal},
+		MinAmount: &minAmount,
+	})
+	if err != nil {
+		t.Fatalf("buildStatement failed: %v", err)
+	}
+	if statement.Total != 1 || statement.Transactions[0].ID != "b" {
+		t.Errorf("Expected only transaction b, got %+v", statement.Transactions)
+	}
+	if statement.OpeningBalance != models.MustParseMoney("1000.00") || statement.ClosingBalance != balance {
+		t.Errorf("Expected balances 1000.00 -> %v, got %v -> %v", balance, statement.OpeningBalance, statement.ClosingBalance)
+	}
+
+	// Paginação em ordem decrescente
+	var ids []string
+	filter := StatementFilter{Descending: true, Limit: 3}
+	for {
+		page, err := buildStatement(balance, transactions, filter)
+		if err != nil {
+			t.Fatalf("buildStatement failed: %v", err)
+		}
+		for _, transaction := range page.Transactions {
+			ids = append(ids, transaction.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	if len(ids) != 4 || ids[0] != "d" || ids[3] != "a" {
+		t.Errorf("Expected d, c, b, a, got %v", ids)
+	}
+}
+
+func TestStatementFilterValidate(t *testing.T) {
+	from := time.Now()
+	to := from.Add(-time.Hour)
+
+	invalid := []StatementFilter{
+		{From: &from, To: &to},
+		{Types: []string{"unknown"}},
+		{Limit: MaxPageSize + 1},
+		{Cursor: "not-a-cursor"},
+	}
+	for _, filter := range invalid {
+		if err := filter.Validate(); err == nil {
+			t.Errorf("Expected error for filter %+v", filter)
+		}
+	}
+}
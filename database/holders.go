@@ -0,0 +1,199 @@
+package database
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// HolderStore guarda quais usuários têm acesso a quais contas e com qual papel
+type HolderStore interface {
+	// GrantAccess concede o acesso do usuário à conta ou altera o seu papel
+	GrantAccess(clientID, userID, role string) (*models.AccountHolder, error)
+	// RevokeAccess remove o acesso do usuário à conta
+	RevokeAccess(clientID, userID string) error
+	// ListHolders retorna os usuários com acesso à conta
+	ListHolders(clientID string) ([]models.AccountHolder, error)
+	// ListUserAccounts retorna as contas às quais o usuário tem acesso
+	ListUserAccounts(userID string) ([]UserAccount, error)
+	// DeleteUser remove o usuário e os seus acessos e o retorna como estava
+	// antes da remoção. Retorna ErrLastOwner se ele for o último titular de
+	// alguma conta.
+	DeleteUser(userID string) (*models.User, error)
+}
+
+// UserAccount é uma conta acessível por um usuário, sem o histórico de
+// transações, e o papel do usuário nela
+type UserAccount struct {
+	Role      string        `json:"role"`
+	GrantedAt time.Time     `json:"granted_at"`
+	Client    models.Client `json:"client"`
+}
+
+// removesLastOwner indica se trocar o papel de userID para newRole (ou
+// remover o acesso, com newRole vazio) deixaria a conta sem titulares.
+// Contas que ainda não têm titular não são afetadas.
+func removesLastOwner(holders []models.AccountHolder, userID, newRole string) bool {
+	if newRole == models.RoleOwner {
+		return false
+	}
+
+	owners, isOwner := 0, false
+	for _, h := range holders {
+		if h.Role == models.RoleOwner {
+			owners++
+			isOwner = isOwner || h.UserID == userID
+		}
+	}
+	return isOwner && owners == 1
+}
+
+// MemoryHolderStore é a implementação de HolderStore em memória, que valida
+// clientes e usuários nos repositórios informados
+type MemoryHolderStore struct {
+	mutex   sync.Mutex
+	clients Database
+	users   UserStore
+	holders map[string]map[string]models.AccountHolder // cliente -> usuário -> acesso
+}
+
+// NewMemoryHolderStore cria um repositório de acessos vazio
+func NewMemoryHolderStore(clients Database, users UserStore) *MemoryHolderStore {
+	return &MemoryHolderStore{
+		clients: clients,
+		users:   users,
+		holders: make(map[string]map[string]models.AccountHolder),
+	}
+}
+
+func (s *MemoryHolderStore) GrantAccess(clientID, userID, role string) (*models.AccountHolder, error) {
+	if !models.IsValidRole(role) {
+		return nil, models.ErrInvalidRole
+	}
+
+	client, err := s.clients.GetClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.GetClosedAt() != nil {
+		return nil, models.ErrAccountClosed
+	}
+	if _, err := s.users.FindByID(userID); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if removesLastOwner(s.list(clientID), userID, role) {
+		return nil, ErrLastOwner
+	}
+
+	holder, exists := s.holders[clientID][userID]
+	if !exists {
+		holder = models.AccountHolder{UserID: userID, ClientID: clientID, GrantedAt: time.Now()}
+	}
+	holder.Role = role
+
+	if s.holders[clientID] == nil {
+		s.holders[clientID] = make(map[string]models.AccountHolder)
+	}
+	s.holders[clientID][userID] = holder
+	return &holder, nil
+}
+
+func (s *MemoryHolderStore) RevokeAccess(clientID, userID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.holders[clientID][userID]; !exists {
+		return ErrHolderNotFound
+	}
+	if removesLastOwner(s.list(clientID), userID, "") {
+		return ErrLastOwner
+	}
+
+	delete(s.holders[clientID], userID)
+	return nil
+}
+
+func (s *MemoryHolderStore) ListHolders(clientID string) ([]models.AccountHolder, error) {
+	if _, err := s.clients.GetClient(clientID); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.list(clientID), nil
+}
+
+func (s *MemoryHolderStore) ListUserAccounts(userID string) ([]UserAccount, error) {
+	if _, err := s.users.FindByID(userID); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	var holdings []models.AccountHolder
+	for _, byUser := range s.holders {
+		if h, ok := byUser[userID]; ok {
+			holdings = append(holdings, h)
+		}
+	}
+	s.mutex.Unlock()
+
+	sortHolders(holdings, func(h models.AccountHolder) string { return h.ClientID })
+
+	accounts := make([]UserAccount, 0, len(holdings))
+	for _, h := range holdings {
+		client, err := s.clients.GetClient(h.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, UserAccount{Role: h.Role, GrantedAt: h.GrantedAt, Client: copyClient(client, false)})
+	}
+	return accounts, nil
+}
+
+func (s *MemoryHolderStore) DeleteUser(userID string) (*models.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for clientID, byUser := range s.holders {
+		if _, ok := byUser[userID]; ok && removesLastOwner(s.list(clientID), userID, "") {
+			return nil, ErrLastOwner
+		}
+	}
+
+	user, err := s.users.Delete(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, byUser := range s.holders {
+		delete(byUser, userID)
+	}
+	return user, nil
+}
+
+// list retorna os acessos da conta ordenados por data de concessão. Deve ser
+// chamada com o mutex bloqueado.
+func (s *MemoryHolderStore) list(clientID string) []models.AccountHolder {
+	holders := make([]models.AccountHolder, 0, len(s.holders[clientID]))
+	for _, h := range s.holders[clientID] {
+		holders = append(holders, h)
+	}
+	sortHolders(holders, func(h models.AccountHolder) string { return h.UserID })
+	return holders
+}
+
+// sortHolders ordena por data de concessão e usa key como desempate
+func sortHolders(holders []models.AccountHolder, key func(models.AccountHolder) string) {
+	sort.Slice(holders, func(i, j int) bool {
+		if !holders[i].GrantedAt.Equal(holders[j].GrantedAt) {
+			return holders[i].GrantedAt.Before(holders[j].GrantedAt)
+		}
+		return key(holders[i]) < key(holders[j])
+	})
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"sync"
+	"time"
+)
+
+// IdempotencyTTL é por quanto tempo uma chave de idempotência é lembrada;
+// depois disso ela pode ser reutilizada
+const IdempotencyTTL = 24 * time.Hour
+
+// IdempotencyLease é por quanto tempo uma chave fica reservada para uma
+// requisição que ainda não terminou. Se o processo cair durante a requisição,
+// a chave nunca é concluída nem liberada; passado esse prazo ela pode ser
+// reservada de novo.
+const IdempotencyLease = 5 * time.Minute
+
+// idempotencyPruneInterval é o intervalo mínimo entre as remoções das chaves
+// expiradas em memória
+const idempotencyPruneInterval = time.Minute
+
+// IdempotencyStore guarda as chaves de idempotência e as respostas das
+// requisições que as usaram. As chaves são separadas por escopo, o chamador
+// que as enviou.
+type IdempotencyStore interface {
+	// Begin reserva a chave para uma requisição com o hash informado. Se a
+	// chave já está em uso, retorna o registro existente e started false.
+	// Uma chave sem resposta reservada há mais de IdempotencyLease é
+	// reservada de novo.
+	Begin(scope, key, requestHash string) (record *IdempotencyRecord, started bool, err error)
+	// Complete grava a resposta da requisição que reservou a chave; se outra
+	// requisição já gravou uma resposta, ela é mantida
+	Complete(scope, key string, response IdempotentResponse) error
+	// Release libera a chave de uma requisição que não foi concluída, para
+	// que ela possa ser repetida
+	Release(scope, key string) error
+}
+
+// IdempotencyRecord é uma chave de idempotência já usada. Response é nil
+// enquanto a requisição original não termina. CreatedAt é quando a chave foi
+// reservada.
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	Response    *IdempotentResponse
+	CreatedAt   time.Time
+}
+
+// IdempotentResponse é a resposta devolvida quando a requisição é repetida
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// MemoryIdempotencyStore é uma implementação de IdempotencyStore em memória
+type MemoryIdempotencyStore struct {
+	mutex   sync.Mutex
+	records map[[2]string]*IdempotencyRecord // (escopo, chave) -> registro
+	now     func() time.Time
+	pruned  time.Time
+}
+
+// NewMemoryIdempotencyStore cria um repositório de chaves vazio
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[[2]string]*IdempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryIdempotencyStore) Begin(scope, key, requestHash string) (*IdempotencyRecord, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.prune(now)
+	if existing, ok := s.records[[2]string{scope, key}]; ok && !reclaimable(existing, now) {
+		return copyRecord(existing), false, nil
+	}
+
+	record := &IdempotencyRecord{Scope: scope, Key: key, RequestHash: requestHash, CreatedAt: now}
+	s.records[[2]string{scope, key}] = record
+	return copyRecord(record), true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(scope, key string, response IdempotentResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, ok := s.records[[2]string{scope, key}]
+	if !ok || record.Response != nil {
+		return nil
+	}
+	response.Body = append([]byte(nil), response.Body...)
+	record.Response = &response
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(scope, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, ok := s.records[[2]string{scope, key}]
+	if ok && record.Response == nil {
+		delete(s.records, [2]string{scope, key})
+	}
+	return nil
+}
+
+// reclaimable indica se a chave pode ser reservada de novo: ela expirou ou a
+// requisição que a reservou não terminou dentro de IdempotencyLease
+func reclaimable(record *IdempotencyRecord, now time.Time) bool {
+	age := now.Sub(record.CreatedAt)
+	return age >= IdempotencyTTL || (record.Response == nil && age >= IdempotencyLease)
+}
+
+// prune remove as chaves expiradas, no máximo uma vez por
+// idempotencyPruneInterval. Deve ser chamada com o mutex bloqueado.
+func (s *MemoryIdempotencyStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < idempotencyPruneInterval {
+		return
+	}
+	s.pruned = now
+
+	for id, record := range s.records {
+		if now.Sub(record.CreatedAt) >= IdempotencyTTL {
+			delete(s.records, id)
+		}
+	}
+}
+
+func copyRecord(record *IdempotencyRecord) *IdempotencyRecord {
+	copied := *record
+	if record.Response != nil {
+		response := *record.Response
+		response.Body = append([]byte(nil), response.Body...)
+		copied.Response = &response
+	}
+	return &copied
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStorePrunesExpiredKeys(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	clock := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return clock }
+
+	for _, key := range []string{"old-1", "old-2"} {
+		if _, _, err := store.Begin("billing", key, "hash"); err != nil {
+			t.Fatalf("Failed to reserve key: %v", err)
+		}
+		if err := store.Complete("billing", key, IdempotentResponse{StatusCode: 200, Body: []byte("{}")}); err != nil {
+			t.Fatalf("Failed to complete key: %v", err)
+		}
+	}
+
+	clock = clock.Add(IdempotencyTTL - time.Minute)
+	if _, _, err := store.Begin("billing", "recent", "hash"); err != nil {
+		t.Fatalf("Failed to reserve key: %v", err)
+	}
+	if len(store.records) != 3 {
+		t.Fatalf("Expected keys within the TTL to be kept, got %d", len(store.records))
+	}
+
+	clock = clock.Add(time.Minute)
+	if _, _, err := store.Begin("billing", "new", "hash"); err != nil {
+		t.Fatalf("Failed to reserve key: %v", err)
+	}
+	if len(store.records) != 2 {
+		t.Errorf("Expected the expired keys to be removed, got %d", len(store.records))
+	}
+	if _, ok := store.records[[2]string{"billing", "old-1"}]; ok {
+		t.Error("Expected old-1 to be removed")
+	}
+}
+
+func TestMemoryIdempotencyStoreLeaseExpires(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	clock := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return clock }
+
+	if _, started, err := store.Begin("billing", "key", "hash"); err != nil || !started {
+		t.Fatalf("Failed to reserve key: %v", err)
+	}
+
+	// A requisição original não terminou: a chave continua reservada até o
+	// fim do prazo
+	clock = clock.Add(IdempotencyLease - time.Second)
+	if record, started, _ := store.Begin("billing", "key", "hash"); started || record.Response != nil {
+		t.Fatalf("Expected the key to stay in progress, got %+v", record)
+	}
+
+	clock = clock.Add(time.Second)
+	if _, started, err := store.Begin("billing", "key", "hash"); err != nil || !started {
+		t.Fatalf("Expected the abandoned key to be reserved again, got %v", err)
+	}
+
+	// A primeira resposta gravada é a que fica
+	store.Complete("billing", "key", IdempotentResponse{StatusCode: 201})
+	store.Complete("billing", "key", IdempotentResponse{StatusCode: 200})
+	clock = clock.Add(IdempotencyLease)
+	record, started, _ := store.Begin("billing", "key", "hash")
+	if started || record.Response == nil || record.Response.StatusCode != 201 {
+		t.Errorf("Expected the completed key to keep the first response, got %+v", record)
+	}
+}
@@ -0,0 +1,458 @@
+package database
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// MemoryDB é uma implementação de Database em memória, segura para uso
+// concorrente. Os clientes são copiados na entrada e na saída, de modo que
+// alterações feitas pelo chamador só são persistidas via UpdateClientTx, e
+// saem sem o histórico, como em PostgresDB.
+type MemoryDB struct {
+	mutex     sync.RWMutex
+	clients   map[string]models.Client
+	documents map[string]string // CPF ou CNPJ -> ID do cliente
+	audit     *MemoryAuditLog
+}
+
+// NewMemoryDB cria um banco de dados em memória vazio, com o próprio log de
+// auditoria
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		clients:   make(map[string]models.Client),
+		documents: make(map[string]string),
+		audit:     NewMemoryAuditLog(),
+	}
+}
+
+func (m *MemoryDB) Audit() AuditLog {
+	return m.audit
+}
+
+// auditClient grava o registro de audit sobre a conta clientID, se houver.
+// O log em memória não falha, e o registro é gravado com o mutex bloqueado,
+// junto com a operação.
+func (m *MemoryDB) auditClient(audit *AuditEntry, clientID string, before *models.Money, after models.Money) {
+	if audit != nil {
+		m.audit.Append(clientAudit(*audit, clientID, before, after))
+	}
+}
+
+func (m *MemoryDB) InitTables() error {
+	return nil
+}
+
+func (m *MemoryDB) Close() error {
+	return nil
+}
+
+func (m *MemoryDB) CreatePersonalClient(client *models.PersonalClient, audit *AuditEntry) error {
+	return m.create(client, client.CPF, audit)
+}
+
+func (m *MemoryDB) CreateCorporateClient(client *models.CorporateClient, audit *AuditEntry) error {
+	return m.create(client, client.CNPJ, audit)
+}
+
+func (m *MemoryDB) create(client models.Client, document string, audit *AuditEntry) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.documents[document]; exists {
+		return ErrDuplicateDocument
+	}
+	if _, exists := m.clients[client.GetID()]; exists {
+		return ErrConflict
+	}
+
+	stored := copyClient(client, true)
+	stored.MarkSaved()
+	m.clients[client.GetID()] = stored
+	m.documents[document] = client.GetID()
+	m.auditClient(audit, client.GetID(), nil, client.GetBalance())
+
+	client.MarkSaved()
+	return nil
+}
+
+func (m *MemoryDB) GetClient(id string) (models.Client, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stored, exists := m.clients[id]
+	if !exists {
+		return nil, ErrClientNotFound
+	}
+	return load(stored), nil
+}
+
+// UpdateClientTx aplica fn a uma cópia do cliente com o banco bloqueado para
+// escrita, o equivalente em memória ao SELECT ... FOR UPDATE
+func (m *MemoryDB) UpdateClientTx(id string, audit *AuditEntry, fn func(models.Client) error) (models.Client, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stored, exists := m.clients[id]
+	if !exists {
+		return nil, ErrClientNotFound
+	}
+
+	client := load(stored)
+	before := client.GetBalance()
+	if err := fn(client); err != nil {
+		return nil, err
+	}
+	if err := m.update(client); err != nil {
+		return nil, err
+	}
+	m.auditClient(audit, id, &before, client.GetBalance())
+
+	client.MarkSaved()
+	return client, nil
+}
+
+// update grava o saldo e acrescenta as transações ainda não gravadas, como
+// updateClient faz no PostgreSQL. Deve ser chamada com o mutex bloqueado.
+func (m *MemoryDB) update(client models.Client) error {
+	stored, exists := m.clients[client.GetID()]
+	if !exists || clientType(stored) != clientType(client) {
+		return ErrClientNotFound
+	}
+	base := baseClient(stored)
+	// Equivalente aos índices únicos de transactions (client_id, posting_key)
+	// e (reversed_transaction_id)
+	for _, t := range client.UnsavedTransactions() {
+		if t.PostingKey != "" && findTransaction(base.Transactions, func(s models.Transaction) bool { return s.PostingKey == t.PostingKey }) != nil {
+			return ErrConflict
+		}
+		if t.ReversedTransactionID != "" && findTransaction(base.Transactions, func(s models.Transaction) bool { return s.ReversedTransactionID == t.ReversedTransactionID }) != nil {
+			return models.ErrAlreadyReversed
+		}
+	}
+
+	profile := client.GetProfile()
+	base.Balance = client.GetBalance()
+	base.Name, base.Email, base.Phone = profile.Name, profile.Email, profile.Phone
+	base.ClosedAt = copyTime(client.GetClosedAt())
+	base.LimitOverride = client.GetLimitOverride()
+	base.CreditLimit = client.GetCreditLimit()
+	base.Transactions = append(base.Transactions, client.UnsavedTransactions()...)
+	return nil
+}
+
+// DeleteClient encerra a conta, mantendo o cliente e seu histórico
+func (m *MemoryDB) DeleteClient(id string, audit *AuditEntry) error {
+	_, err := m.UpdateClientTx(id, audit, func(client models.Client) error {
+		return client.Close()
+	})
+	return err
+}
+
+func (m *MemoryDB) Transfer(fromID, toID string, amount models.Money, audit *AuditEntry) (*models.Transfer, error) {
+	return m.TransferWithDescription(fromID, toID, amount, "", audit)
+}
+
+func (m *MemoryDB) TransferWithDescription(fromID, toID string, amount models.Money, description string, audit *AuditEntry) (*models.Transfer, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	loaded := make(map[string]models.Client, 2)
+	for _, id := range []string{fromID, toID} {
+		if _, ok := loaded[id]; ok {
+			continue
+		}
+		stored, exists := m.clients[id]
+		if !exists {
+			return nil, ErrClientNotFound
+		}
+		loaded[id] = load(stored)
+	}
+
+	transfer, err := models.ExecuteTransferWithDescription(loaded[fromID], loaded[toID], amount, description)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.update(loaded[fromID]); err != nil {
+		return nil, err
+	}
+	if err := m.update(loaded[toID]); err != nil {
+		return nil, err
+	}
+	m.auditClient(audit, fromID, &transfer.FromBalanceBefore, transfer.FromBalanceAfter)
+	m.auditClient(audit, toID, &transfer.ToBalanceBefore, transfer.ToBalanceAfter)
+
+	return transfer, nil
+}
+
+func (m *MemoryDB) GetTransaction(id, txID string) (*models.Transaction, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stored, exists := m.clients[id]
+	if !exists {
+		return nil, ErrClientNotFound
+	}
+	t := findTransaction(baseClient(stored).Transactions, func(s models.Transaction) bool { return s.ID == txID })
+	if t == nil {
+		return nil, models.ErrTransactionNotFound
+	}
+	found := *t
+	found.OverdraftAmount = copyMoney(found.OverdraftAmount)
+	return &found, nil
+}
+
+func (m *MemoryDB) HasPosting(id, key string) (bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stored, exists := m.clients[id]
+	if !exists {
+		return false, ErrClientNotFound
+	}
+	return findTransaction(baseClient(stored).Transactions, func(s models.Transaction) bool { return s.PostingKey == key }) != nil, nil
+}
+
+// BalanceAt desconta do saldo atual as transações posteriores a at
+func (m *MemoryDB) BalanceAt(id string, at time.Time) (models.Money, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stored, exists := m.clients[id]
+	if !exists {
+		return models.Money{}, ErrClientNotFound
+	}
+	balance := stored.GetBalance()
+	for _, t := range baseClient(stored).Transactions {
+		if t.CreatedAt.After(at) {
+			balance = balance.Sub(t.SignedAmount())
+		}
+	}
+	return balance, nil
+}
+
+// findTransaction retorna a primeira transação que atende a match
+func findTransaction(transactions []models.Transaction, match func(models.Transaction) bool) *models.Transaction {
+	for i := range transactions {
+		if match(transactions[i]) {
+			return &transactions[i]
+		}
+	}
+	return nil
+}
+
+func (m *MemoryDB) GetStatement(id string, filter StatementFilter) (*Statement, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stored, exists := m.clients[id]
+	if !exists {
+		return nil, ErrClientNotFound
+	}
+	return buildStatement(stored.GetBalance(), copyClient(stored, true).GetStatement(), filter)
+}
+
+// ListClients retorna uma página de clientes sem o histórico de transações,
+// com a mesma ordenação e paginação por cursor de PostgresDB.ListClients
+func (m *MemoryDB) ListClients(filter ClientFilter) (*ClientPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.RLock()
+	matched := make([]models.Client, 0)
+	for _, stored := range m.clients {
+		if filter.matches(stored) {
+			matched = append(matched, copyClient(stored, false))
+		}
+	}
+	m.mutex.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if filter.Descending {
+			return clientAfter(matched[i], matched[j], filter.SortBy)
+		}
+		return clientAfter(matched[j], matched[i], filter.SortBy)
+	})
+
+	page := &ClientPage{Clients: make([]models.Client, 0, filter.Limit), Total: len(matched)}
+
+	if filter.Cursor != "" {
+		cursor, _ := decodeCursor(filter.Cursor)
+		last := cursorClient(cursor, filter.SortBy)
+		for len(matched) > 0 {
+			if filter.Descending && clientAfter(last, matched[0], filter.SortBy) {
+				break
+			}
+			if !filter.Descending && clientAfter(matched[0], last, filter.SortBy) {
+				break
+			}
+			matched = matched[1:]
+		}
+	}
+
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+		last := matched[len(matched)-1]
+		page.NextCursor = encodeCursor(pageCursor{Value: sortValue(last, filter.SortBy), ID: last.GetID()})
+	}
+	page.Clients = append(page.Clients, matched...)
+
+	return page, nil
+}
+
+// matches indica se o cliente atende aos filtros de ListClients
+func (f *ClientFilter) matches(client models.Client) bool {
+	if !f.IncludeClosed && client.GetClosedAt() != nil {
+		return false
+	}
+	if f.ClientType != "" && clientType(client) != f.ClientType {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(client.GetName()), strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+	if f.MinBalance != nil && client.GetBalance().Cmp(*f.MinBalance) < 0 {
+		return false
+	}
+	if f.MaxBalance != nil && client.GetBalance().Cmp(*f.MaxBalance) > 0 {
+		return false
+	}
+	if f.Document != "" && clientDocument(client) != f.Document {
+		return false
+	}
+	return true
+}
+
+// clientAfter ordena por (campo de ordenação, id), a mesma ordem usada no SQL
+func clientAfter(a, b models.Client, sortBy string) bool {
+	if c := compareSortValue(a, b, sortBy); c != 0 {
+		return c > 0
+	}
+	return a.GetID() > b.GetID()
+}
+
+func compareSortValue(a, b models.Client, sortBy string) int {
+	switch sortBy {
+	case SortByName:
+		return strings.Compare(a.GetName(), b.GetName())
+	case SortByBalance:
+		return a.GetBalance().Cmp(b.GetBalance())
+	default:
+		return 0
+	}
+}
+
+// cursorClient reconstrói, a partir do cursor, um cliente com apenas o ID e o
+// campo de ordenação preenchidos
+func cursorClient(cursor pageCursor, sortBy string) models.Client {
+	client := &models.PersonalClient{BaseClient: models.BaseClient{ID: cursor.ID}}
+	switch sortBy {
+	case SortByName:
+		client.Name = cursor.Value
+	case SortByBalance:
+		client.Balance, _ = models.ParseMoney(cursor.Value)
+	}
+	return client
+}
+
+// load copia um cliente gravado sem o histórico, com o uso dos limites
+// calculado sobre as transações gravadas, como PostgresDB faz em SQL
+func load(stored models.Client) models.Client {
+	client := copyClient(stored, false)
+	baseClient(client).LimitUsage = models.ComputeLimitUsage(baseClient(stored).Transactions, time.Now())
+	return client
+}
+
+// copyClient retorna uma cópia independente do cliente; com transactions
+// false o histórico não é copiado, como em PostgresDB.ListClients
+func copyClient(client models.Client, transactions bool) models.Client {
+	var clone models.Client
+	switch c := client.(type) {
+	case *models.PersonalClient:
+		copied := *c
+		clone = &copied
+	case *models.CorporateClient:
+		copied := *c
+		clone = &copied
+	default:
+		return client
+	}
+
+	base := baseClient(clone)
+	base.ClosedAt = copyTime(base.ClosedAt)
+	base.LimitOverride = models.LimitOverride{
+		PerTransaction: copyMoney(base.LimitOverride.PerTransaction),
+		Daily:          copyMoney(base.LimitOverride.Daily),
+		Monthly:        copyMoney(base.LimitOverride.Monthly),
+	}
+	if transactions {
+		copied := make([]models.Transaction, len(base.Transactions))
+		for i, tx := range base.Transactions {
+			tx.OverdraftAmount = copyMoney(tx.OverdraftAmount)
+			copied[i] = tx
+		}
+		base.Transactions = copied
+	} else {
+		base.Transactions = make([]models.Transaction, 0)
+	}
+	return clone
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+func copyMoney(m *models.Money) *models.Money {
+	if m == nil {
+		return nil
+	}
+	copied := *m
+	return &copied
+}
+
+func baseClient(client models.Client) *models.BaseClient {
+	switch c := client.(type) {
+	case *models.PersonalClient:
+		return &c.BaseClient
+	case *models.CorporateClient:
+		return &c.BaseClient
+	default:
+		return nil
+	}
+}
+
+func clientType(client models.Client) string {
+	switch client.(type) {
+	case *models.PersonalClient:
+		return "personal"
+	case *models.CorporateClient:
+		return "corporate"
+	default:
+		return ""
+	}
+}
+
+func clientDocument(client models.Client) string {
+	switch c := client.(type) {
+	case *models.PersonalClient:
+		return c.CPF
+	case *models.CorporateClient:
+		return c.CNPJ
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,254 @@
+// Package migrations aplica e reverte as alterações versionadas do schema do
+// PostgreSQL. Cada versão é um par de arquivos NNNN_nome.up.sql e
+// NNNN_nome.down.sql embutidos no binário; as versões aplicadas ficam
+// registradas na tabela schema_migrations.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID identifica o advisory lock que impede instâncias concorrentes de
+// migrarem o mesmo banco ao mesmo tempo
+const lockID int64 = 7263846592
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration é uma versão do schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status descreve se uma migração já foi aplicada
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load lê as migrações embutidas, ordenadas pela versão
+func Load() ([]Migration, error) {
+	return load(files, "sql")
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up aplica todas as migrações pendentes e retorna as que foram aplicadas
+func Up(db *sql.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := run(conn, m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+				return fmt.Errorf("error applying migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverte as últimas steps migrações aplicadas e retorna as revertidas
+func Down(db *sql.DB, steps int) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = withLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if err := run(conn, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+				return fmt.Errorf("error reverting migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// GetStatus retorna todas as migrações conhecidas indicando quais já foram aplicadas
+func GetStatus(db *sql.DB) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	err = withLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			appliedAt, ok := done[m.Version]
+			statuses = append(statuses, Status{Migration: m, Applied: ok, AppliedAt: appliedAt})
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+// withLock reserva uma conexão, garante a tabela schema_migrations e mantém o
+// advisory lock enquanto fn é executada
+func withLock(db *sql.DB, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func appliedVersions(conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations: %w", err)
+		}
+		done[version] = appliedAt
+	}
+
+	return done, rows.Err()
+}
+
+// run executa o script e atualiza schema_migrations na mesma transação
+func run(conn *sql.Conn, script, bookkeeping string, args ...interface{}) error {
+	ctx := context.Background()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
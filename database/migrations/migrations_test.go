@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+
+	for i, m := range migrations {
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("Migration %d_%s is missing up or down SQL", m.Version, m.Name)
+		}
+		if i > 0 && migrations[i-1].Version >= m.Version {
+			t.Errorf("Migrations are not strictly ordered: %d before %d", migrations[i-1].Version, m.Version)
+		}
+	}
+}
+
+func TestLoadInvalidFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"sql/0001_init.up.sql": {Data: []byte("SELECT 1")},
+		},
+		"invalid name": {
+			"sql/init.sql": {Data: []byte("SELECT 1")},
+		},
+		"conflicting names": {
+			"sql/0001_init.up.sql":    {Data: []byte("SELECT 1")},
+			"sql/0001_other.down.sql": {Data: []byte("SELECT 1")},
+		},
+	}
+
+	for name, fsys := range cases {
+		if _, err := load(fsys, "sql"); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS clients (
+	id VARCHAR(36) PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	balance DECIMAL(15,2) NOT NULL,
+	client_type VARCHAR(10) NOT NULL,
+	cpf VARCHAR(14),
+	cnpj VARCHAR(18)
+);
@@ -0,0 +1,19 @@
+-- Devolve o histórico para a coluna JSONB clients.transactions
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS transactions JSONB;
+
+UPDATE clients c
+SET transactions = COALESCE((
+	SELECT jsonb_agg(jsonb_build_object(
+		'id', t.id,
+		'amount', t.amount,
+		'type', t.type,
+		'description', t.description,
+		'created_at', t.created_at,
+		'transfer_id', t.transfer_id,
+		'counterparty_id', t.counterparty_id
+	) ORDER BY t.created_at, t.id)
+	FROM transactions t
+	WHERE t.client_id = c.id
+), '[]'::jsonb);
+
+DROP TABLE IF EXISTS transactions;
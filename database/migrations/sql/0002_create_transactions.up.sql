@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS transactions (
+	id VARCHAR(36) PRIMARY KEY,
+	client_id VARCHAR(36) NOT NULL REFERENCES clients(id),
+	type VARCHAR(20) NOT NULL,
+	amount DECIMAL(15,2) NOT NULL,
+	description VARCHAR(255) NOT NULL DEFAULT '',
+	transfer_id VARCHAR(36),
+	counterparty_id VARCHAR(36),
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_client_created
+	ON transactions (client_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer
+	ON transactions (transfer_id) WHERE transfer_id IS NOT NULL;
+
+-- Migra o histórico da antiga coluna JSONB clients.transactions
+DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema()
+			AND table_name = 'clients' AND column_name = 'transactions'
+	) THEN
+		INSERT INTO transactions
+			(id, client_id, type, amount, description, transfer_id, counterparty_id, created_at)
+		SELECT
+			t->>'id',
+			c.id,
+			t->>'type',
+			(t->>'amount')::DECIMAL(15,2),
+			COALESCE(t->>'description', ''),
+			NULLIF(t->>'transfer_id', ''),
+			NULLIF(t->>'counterparty_id', ''),
+			(t->>'created_at')::TIMESTAMPTZ
+		FROM clients c,
+			jsonb_array_elements(CASE WHEN jsonb_typeof(c.transactions) = 'array'
+				THEN c.transactions ELSE '[]'::jsonb END) AS t
+		ON CONFLICT (id) DO NOTHING;
+
+		ALTER TABLE clients DROP COLUMN transactions;
+	END IF;
+END $$;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_clients_cnpj;
+DROP INDEX IF EXISTS idx_clients_cpf;
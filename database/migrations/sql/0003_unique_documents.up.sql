@@ -0,0 +1,33 @@
+-- Normaliza CPF e CNPJ para o formato com pontuação usado pela aplicação
+UPDATE clients SET cpf = NULL WHERE cpf = '';
+UPDATE clients SET cnpj = NULL WHERE cnpj = '';
+
+UPDATE clients
+SET cpf = regexp_replace(regexp_replace(cpf, '\D', '', 'g'),
+	'^(\d{3})(\d{3})(\d{3})(\d{2})$', '\1.\2.\3-\4')
+WHERE cpf IS NOT NULL;
+
+UPDATE clients
+SET cnpj = regexp_replace(regexp_replace(cnpj, '\D', '', 'g'),
+	'^(\d{2})(\d{3})(\d{3})(\d{4})(\d{2})$', '\1.\2.\3/\4-\5')
+WHERE cnpj IS NOT NULL;
+
+-- Documentos duplicados precisam ser resolvidos manualmente antes da migração
+DO $$
+DECLARE
+	duplicated TEXT;
+BEGIN
+	SELECT string_agg(document, ', ') INTO duplicated
+	FROM (
+		SELECT cpf AS document FROM clients WHERE cpf IS NOT NULL GROUP BY cpf HAVING COUNT(*) > 1
+		UNION ALL
+		SELECT cnpj FROM clients WHERE cnpj IS NOT NULL GROUP BY cnpj HAVING COUNT(*) > 1
+	) d;
+
+	IF duplicated IS NOT NULL THEN
+		RAISE EXCEPTION 'duplicated client documents: %', duplicated;
+	END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clients_cpf ON clients (cpf) WHERE cpf IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clients_cnpj ON clients (cnpj) WHERE cnpj IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_clients_open;
+
+ALTER TABLE clients DROP COLUMN IF EXISTS closed_at;
+ALTER TABLE clients DROP COLUMN IF EXISTS phone;
+ALTER TABLE clients DROP COLUMN IF EXISTS email;
@@ -0,0 +1,6 @@
+-- Dados de contato e encerramento de conta (soft delete)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS email VARCHAR(254);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS phone VARCHAR(16);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_clients_open ON clients (name, id) WHERE closed_at IS NULL;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS users (
+	id VARCHAR(36) PRIMARY KEY,
+	first_name VARCHAR(20) NOT NULL,
+	last_name VARCHAR(20) NOT NULL,
+	biography VARCHAR(450) NOT NULL
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS account_holders (
+	user_id VARCHAR(36) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	client_id VARCHAR(36) NOT NULL REFERENCES clients (id),
+	role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'operator', 'viewer')),
+	granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, client_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_holders_client ON account_holders (client_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	scope VARCHAR(255) NOT NULL,
+	key VARCHAR(255) NOT NULL,
+	request_hash CHAR(64) NOT NULL,
+	status_code INTEGER,
+	content_type VARCHAR(255),
+	response_body BYTEA,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	completed_at TIMESTAMPTZ,
+	PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys (created_at);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGINT PRIMARY KEY,
+	actor VARCHAR(255) NOT NULL,
+	actor_type VARCHAR(20) NOT NULL,
+	action VARCHAR(64) NOT NULL,
+	client_id VARCHAR(36),
+	user_id VARCHAR(36),
+	balance_before DECIMAL(15,2),
+	balance_after DECIMAL(15,2),
+	source_ip VARCHAR(45) NOT NULL,
+	request_id VARCHAR(128) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	prev_hash VARCHAR(64) NOT NULL,
+	hash VARCHAR(64) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_client ON audit_log (client_id, id) WHERE client_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+
+-- O log só aceita inserções
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+	BEFORE UPDATE OR DELETE ON audit_log
+	FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
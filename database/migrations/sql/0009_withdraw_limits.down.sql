@@ -0,0 +1,3 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS limit_monthly;
+ALTER TABLE clients DROP COLUMN IF EXISTS limit_daily;
+ALTER TABLE clients DROP COLUMN IF EXISTS limit_per_transaction;
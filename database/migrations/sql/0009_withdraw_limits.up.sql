@@ -0,0 +1,4 @@
+-- Limites de saque específicos do cliente; NULL usa o padrão do tipo de cliente
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS limit_per_transaction DECIMAL(15,2);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS limit_daily DECIMAL(15,2);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS limit_monthly DECIMAL(15,2);
@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS overdraft_amount;
+ALTER TABLE clients DROP COLUMN IF EXISTS credit_limit;
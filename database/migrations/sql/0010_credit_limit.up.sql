@@ -0,0 +1,3 @@
+-- Limite de crédito (cheque especial) e a parte de cada débito coberta por ele
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS credit_limit DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS overdraft_amount DECIMAL(15,2);
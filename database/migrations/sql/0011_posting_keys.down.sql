@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_posting_key;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS posting_key;
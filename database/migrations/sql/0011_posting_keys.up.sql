@@ -0,0 +1,5 @@
+-- Lançamentos automáticos (juros e tarifas) são feitos uma vez por conta e chave
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS posting_key VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_posting_key
+	ON transactions (client_id, posting_key) WHERE posting_key IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_reversed;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversed_transaction_id;
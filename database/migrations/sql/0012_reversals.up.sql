@@ -0,0 +1,5 @@
+-- Um estorno aponta para a transação estornada, que só pode ser estornada uma vez
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversed_transaction_id VARCHAR(36) REFERENCES transactions(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reversed
+	ON transactions (reversed_transaction_id) WHERE reversed_transaction_id IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS schedule_runs;
+
+DROP TABLE IF EXISTS scheduled_operations;
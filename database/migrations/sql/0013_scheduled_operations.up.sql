@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS scheduled_operations (
+	id VARCHAR(36) PRIMARY KEY,
+	client_id VARCHAR(36) NOT NULL REFERENCES clients (id),
+	type VARCHAR(20) NOT NULL CHECK (type IN ('withdrawal', 'transfer')),
+	amount DECIMAL(15,2) NOT NULL,
+	to_client_id VARCHAR(36) REFERENCES clients (id),
+	description VARCHAR(255) NOT NULL DEFAULT '',
+	run_at TIMESTAMPTZ,
+	recurrence VARCHAR(100) NOT NULL DEFAULT '',
+	next_run_at TIMESTAMPTZ,
+	status VARCHAR(20) NOT NULL CHECK (status IN ('active', 'running', 'completed', 'failed', 'canceled')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	retry_at TIMESTAMPTZ,
+	last_run_at TIMESTAMPTZ,
+	last_error TEXT NOT NULL DEFAULT '',
+	claimed_at TIMESTAMPTZ,
+	version INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_operations_client ON scheduled_operations (client_id, created_at);
+
+-- O worker procura os agendamentos ativos vencidos e os em execução
+CREATE INDEX IF NOT EXISTS idx_scheduled_operations_due
+	ON scheduled_operations ((COALESCE(retry_at, next_run_at)))
+	WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS schedule_runs (
+	id VARCHAR(36) PRIMARY KEY,
+	schedule_id VARCHAR(36) NOT NULL REFERENCES scheduled_operations (id),
+	scheduled_for TIMESTAMPTZ NOT NULL,
+	executed_at TIMESTAMPTZ NOT NULL,
+	attempt INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL CHECK (status IN ('succeeded', 'failed')),
+	error TEXT NOT NULL DEFAULT '',
+	transaction_id VARCHAR(36),
+	transfer_id VARCHAR(36)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_runs_schedule ON schedule_runs (schedule_id, executed_at);
@@ -0,0 +1,3 @@
+ALTER TABLE account_holders DROP CONSTRAINT IF EXISTS account_holders_user_id_fkey;
+ALTER TABLE account_holders ADD CONSTRAINT account_holders_user_id_fkey
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- Um usuário só é removido junto com os seus acessos, depois de conferido que
+-- ele não é o último titular de nenhuma conta
+ALTER TABLE account_holders DROP CONSTRAINT IF EXISTS account_holders_user_id_fkey;
+ALTER TABLE account_holders ADD CONSTRAINT account_holders_user_id_fkey
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
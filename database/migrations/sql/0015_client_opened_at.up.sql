@@ -0,0 +1,10 @@
+-- Data de abertura da conta; as contas existentes recebem a da primeira
+-- transação ou, sem transações, a data da migração
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS opened_at TIMESTAMPTZ;
+
+UPDATE clients c
+SET opened_at = COALESCE((SELECT MIN(t.created_at) FROM transactions t WHERE t.client_id = c.id), NOW())
+WHERE opened_at IS NULL;
+
+ALTER TABLE clients ALTER COLUMN opened_at SET DEFAULT NOW();
+ALTER TABLE clients ALTER COLUMN opened_at SET NOT NULL;
@@ -0,0 +1,2 @@
+-- Agendamento cuja execução gerou o registro
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS schedule_id VARCHAR(36);
@@ -1,28 +1,64 @@
 package database
 
-import "github.com/Luis-Andrei/api-users/models"
+import (
+	"sync"
+	"time"
 
-// MockDB é uma implementação mock do banco de dados para testes
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// MockDB é uma implementação mock do banco de dados para testes. Os registros
+// de auditoria das operações bem-sucedidas vão para AuditLog, ou para um log
+// em memória se ele não for definido.
 type MockDB struct {
 	OnCreatePersonalClient  func(client *models.PersonalClient) error
 	OnCreateCorporateClient func(client *models.CorporateClient) error
 	OnGetClient             func(id string) (models.Client, error)
-	OnUpdateClient          func(client models.Client) error
-	OnListClients           func() ([]models.Client, error)
+	OnUpdateClientTx        func(id string, fn func(models.Client) error) (models.Client, error)
+	OnGetTransaction        func(id, txID string) (*models.Transaction, error)
+	OnDeleteClient          func(id string) error
+	OnTransfer              func(fromID, toID string, amount models.Money) (*models.Transfer, error)
+	OnGetStatement          func(id string, filter StatementFilter) (*Statement, error)
+	OnListClients           func(filter ClientFilter) (*ClientPage, error)
+	AuditLog                AuditLog
+
+	auditOnce sync.Once
+}
+
+func (m *MockDB) Audit() AuditLog {
+	m.auditOnce.Do(func() {
+		if m.AuditLog == nil {
+			m.AuditLog = NewMemoryAuditLog()
+		}
+	})
+	return m.AuditLog
+}
+
+// auditClient grava o registro de audit sobre a conta clientID, se houver
+func (m *MockDB) auditClient(audit *AuditEntry, clientID string, before *models.Money, after models.Money) error {
+	if audit == nil {
+		return nil
+	}
+	_, err := m.Audit().Append(clientAudit(*audit, clientID, before, after))
+	return err
 }
 
-func (m *MockDB) CreatePersonalClient(client *models.PersonalClient) error {
+func (m *MockDB) CreatePersonalClient(client *models.PersonalClient, audit *AuditEntry) error {
 	if m.OnCreatePersonalClient != nil {
-		return m.OnCreatePersonalClient(client)
+		if err := m.OnCreatePersonalClient(client); err != nil {
+			return err
+		}
 	}
-	return nil
+	return m.auditClient(audit, client.ID, nil, client.Balance)
 }
 
-func (m *MockDB) CreateCorporateClient(client *models.CorporateClient) error {
+func (m *MockDB) CreateCorporateClient(client *models.CorporateClient, audit *AuditEntry) error {
 	if m.OnCreateCorporateClient != nil {
-		return m.OnCreateCorporateClient(client)
+		if err := m.OnCreateCorporateClient(client); err != nil {
+			return err
+		}
 	}
-	return nil
+	return m.auditClient(audit, client.ID, nil, client.Balance)
 }
 
 func (m *MockDB) GetClient(id string) (models.Client, error) {
@@ -32,18 +68,161 @@ func (m *MockDB) GetClient(id string) (models.Client, error) {
 	return nil, nil
 }
 
-func (m *MockDB) UpdateClient(client models.Client) error {
-	if m.OnUpdateClient != nil {
-		return m.OnUpdateClient(client)
+// UpdateClientTx usa OnUpdateClientTx se definido; caso contrário aplica fn ao
+// cliente devolvido por GetClient
+func (m *MockDB) UpdateClientTx(id string, audit *AuditEntry, fn func(models.Client) error) (models.Client, error) {
+	var before models.Money
+	apply := func(client models.Client) error {
+		before = client.GetBalance()
+		return fn(client)
 	}
-	return nil
+
+	var (
+		client models.Client
+		err    error
+	)
+	if m.OnUpdateClientTx != nil {
+		client, err = m.OnUpdateClientTx(id, apply)
+	} else {
+		client, err = m.updateClient(id, apply)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := m.auditClient(audit, id, &before, client.GetBalance()); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (m *MockDB) updateClient(id string, fn func(models.Client) error) (models.Client, error) {
+	client, err := m.mockClient(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(client); err != nil {
+		return nil, err
+	}
+	client.MarkSaved()
+	return client, nil
+}
+
+// GetTransaction usa OnGetTransaction se definido; caso contrário procura a
+// transação no histórico do cliente devolvido por GetClient
+func (m *MockDB) GetTransaction(id, txID string) (*models.Transaction, error) {
+	if m.OnGetTransaction != nil {
+		return m.OnGetTransaction(id, txID)
+	}
+
+	client, err := m.mockClient(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range client.GetStatement() {
+		if t.ID == txID {
+			return &t, nil
+		}
+	}
+	return nil, models.ErrTransactionNotFound
+}
+
+// HasPosting procura a chave no histórico do cliente devolvido por GetClient
+func (m *MockDB) HasPosting(id, key string) (bool, error) {
+	client, err := m.mockClient(id)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range client.GetStatement() {
+		if t.PostingKey == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// BalanceAt calcula o saldo a partir do cliente devolvido por GetClient
+func (m *MockDB) BalanceAt(id string, at time.Time) (models.Money, error) {
+	statement, err := m.GetStatement(id, StatementFilter{To: &at, Limit: 1})
+	if err != nil {
+		return models.Money{}, err
+	}
+	return statement.ClosingBalance, nil
+}
+
+// mockClient retorna o cliente de GetClient, ou ErrClientNotFound se for nil
+func (m *MockDB) mockClient(id string) (models.Client, error) {
+	client, err := m.GetClient(id)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, ErrClientNotFound
+	}
+	return client, nil
+}
+
+// DeleteClient usa OnDeleteClient se definido; caso contrário encerra a conta
+// via UpdateClientTx
+func (m *MockDB) DeleteClient(id string, audit *AuditEntry) error {
+	if m.OnDeleteClient != nil {
+		if err := m.OnDeleteClient(id); err != nil {
+			return err
+		}
+		if audit == nil {
+			return nil
+		}
+		_, err := m.Audit().Append(clientAudit(*audit, id, nil, models.Money{}))
+		return err
+	}
+
+	_, err := m.UpdateClientTx(id, audit, func(client models.Client) error {
+		return client.Close()
+	})
+	return err
+}
+
+func (m *MockDB) Transfer(fromID, toID string, amount models.Money, audit *AuditEntry) (*models.Transfer, error) {
+	return m.TransferWithDescription(fromID, toID, amount, "", audit)
 }
 
-func (m *MockDB) ListClients() ([]models.Client, error) {
+// TransferWithDescription usa OnTransfer, que não recebe a descrição
+func (m *MockDB) TransferWithDescription(fromID, toID string, amount models.Money, description string, audit *AuditEntry) (*models.Transfer, error) {
+	if m.OnTransfer == nil {
+		return nil, nil
+	}
+
+	transfer, err := m.OnTransfer(fromID, toID, amount)
+	if err != nil || transfer == nil {
+		return transfer, err
+	}
+	if err := m.auditClient(audit, fromID, &transfer.FromBalanceBefore, transfer.FromBalanceAfter); err != nil {
+		return nil, err
+	}
+	if err := m.auditClient(audit, toID, &transfer.ToBalanceBefore, transfer.ToBalanceAfter); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// GetStatement usa OnGetStatement se definido; caso contrário monta o
+// extrato a partir do cliente devolvido por GetClient
+func (m *MockDB) GetStatement(id string, filter StatementFilter) (*Statement, error) {
+	if m.OnGetStatement != nil {
+		return m.OnGetStatement(id, filter)
+	}
+
+	client, err := m.mockClient(id)
+	if err != nil {
+		return nil, err
+	}
+	return buildStatement(client.GetBalance(), client.GetStatement(), filter)
+}
+
+func (m *MockDB) ListClients(filter ClientFilter) (*ClientPage, error) {
 	if m.OnListClients != nil {
-		return m.OnListClients()
+		return m.OnListClients(filter)
 	}
-	return nil, nil
+	return &ClientPage{Clients: []models.Client{}}, nil
 }
 
 func (m *MockDB) Close() error {
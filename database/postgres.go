@@ -2,12 +2,14 @@ package database
 
 import (
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Luis-Andrei/api-users/database/migrations"
 	"github.com/Luis-Andrei/api-users/models"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresDB struct {
@@ -15,279 +17,475 @@ type PostgresDB struct {
 }
 
 func NewPostgresDB(host, port, user, password, dbname string) (Database, error) {
+	db, err := OpenPostgres(host, port, user, password, dbname)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresDB{db: db}, nil
+}
+
+// OpenPostgres abre e valida uma conexão com o PostgreSQL
+func OpenPostgres(host, port, user, password, dbname string) (*sql.DB, error) {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		host, port, user, password, dbname)
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
-		return nil, fmt.Errorf("error connecting to database: %v", err)
+		return nil, fmt.Errorf("error connecting to database: %w", classify(err))
 	}
 
 	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("error pinging database: %v", err)
+		db.Close()
+		return nil, fmt.Errorf("error pinging database: %w", classify(err))
 	}
 
-	return &PostgresDB{db: db}, nil
+	return db, nil
 }
 
+// InitTables aplica as migrações pendentes do pacote migrations
 func (p *PostgresDB) InitTables() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS clients (
-			id VARCHAR(36) PRIMARY KEY,
-			name VARCHAR(100) NOT NULL,
-			balance DECIMAL(15,2) NOT NULL,
-			client_type VARCHAR(10) NOT NULL,
-			cpf VARCHAR(14),
-			cnpj VARCHAR(18),
-			transactions JSONB
-		)`,
-	}
-
-	for _, query := range queries {
-		_, err := p.db.Exec(query)
-		if err != nil {
-			return fmt.Errorf("error creating tables: %v", err)
-		}
+	if _, err := migrations.Up(p.db); err != nil {
+		return fmt.Errorf("error creating tables: %w", classify(err))
 	}
 
 	return nil
 }
 
-func (p *PostgresDB) CreatePersonalClient(client *models.PersonalClient) error {
-	transactions, err := json.Marshal(client.Transactions)
-	if err != nil {
-		return fmt.Errorf("error marshaling transactions: %v", err)
-	}
-
+func (p *PostgresDB) CreatePersonalClient(client *models.PersonalClient, audit *AuditEntry) error {
 	query := `
-		INSERT INTO clients (id, name, balance, client_type, cpf, transactions)
-		VALUES ($1, $2, $3, $4, $5, $6)`
-
-	_, err = p.db.Exec(query,
-		client.ID,
-		client.Name,
-		client.Balance,
-		"personal",
-		client.CPF,
-		transactions)
+		INSERT INTO clients (id, name, balance, client_type, cpf, email, phone, credit_limit, opened_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
+	err := p.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(query, client.ID, client.Name, client.Balance, "personal", client.CPF, nullString(client.Email), nullString(client.Phone), client.CreditLimit, client.OpenedAt); err != nil {
+			return err
+		}
+		if err := insertTransactions(tx, client.ID, client.UnsavedTransactions()); err != nil {
+			return err
+		}
+		return auditClient(tx, audit, client.ID, nil, client.Balance)
+	})
+	if isUniqueViolation(err) {
+		return ErrDuplicateDocument
+	}
 	if err != nil {
-		return fmt.Errorf("error creating personal client: %v", err)
+		return fmt.Errorf("error creating personal client: %w", classify(err))
 	}
 
+	client.MarkSaved()
 	return nil
 }
 
-func (p *PostgresDB) CreateCorporateClient(client *models.CorporateClient) error {
-	transactions, err := json.Marshal(client.Transactions)
+func (p *PostgresDB) CreateCorporateClient(client *models.CorporateClient, audit *AuditEntry) error {
+	query := `
+		INSERT INTO clients (id, name, balance, client_type, cnpj, email, phone, credit_limit, opened_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	err := p.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(query, client.ID, client.Name, client.Balance, "corporate", client.CNPJ, nullString(client.Email), nullString(client.Phone), client.CreditLimit, client.OpenedAt); err != nil {
+			return err
+		}
+		if err := insertTransactions(tx, client.ID, client.UnsavedTransactions()); err != nil {
+			return err
+		}
+		return auditClient(tx, audit, client.ID, nil, client.Balance)
+	})
+	if isUniqueViolation(err) {
+		return ErrDuplicateDocument
+	}
 	if err != nil {
-		return fmt.Errorf("error marshaling transactions: %v", err)
+		return fmt.Errorf("error creating corporate client: %w", classify(err))
 	}
 
-	query := `
-		INSERT INTO clients (id, name, balance, client_type, cnpj, transactions)
-		VALUES ($1, $2, $3, $4, $5, $6)`
+	client.MarkSaved()
+	return nil
+}
 
-	_, err = p.db.Exec(query,
-		client.ID,
-		client.Name,
-		client.Balance,
-		"corporate",
-		client.CNPJ,
-		transactions)
+// isUniqueViolation indica se o erro é uma violação de chave única (23505)
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// queryer é satisfeita tanto por *sql.DB quanto por *sql.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
 
+// inTx executa fn dentro de uma transação, confirmando-a apenas se fn não falhar
+func (p *PostgresDB) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := p.db.Begin()
 	if err != nil {
-		return fmt.Errorf("error creating corporate client: %v", err)
+		return fmt.Errorf("error starting transaction: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", classify(err))
+	}
 	return nil
 }
 
 func (p *PostgresDB) GetClient(id string) (models.Client, error) {
+	return getClient(p.db, id, false)
+}
+
+// getClient busca um cliente sem o histórico, com o uso dos limites somado em
+// SQL; com forUpdate a linha fica bloqueada até o fim da transação
+func getClient(q queryer, id string, forUpdate bool) (models.Client, error) {
 	query := `
-		SELECT id, name, balance, client_type, cpf, cnpj, transactions
+		SELECT ` + clientColumns + `
 		FROM clients
 		WHERE id = $1`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	client, err := scanClient(q.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", classify(err))
+	}
+
+	usage, err := getLimitUsage(q, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	setLimitUsage(client, usage)
 
+	return client, nil
+}
+
+const clientColumns = `id, name, balance, client_type, cpf, cnpj, email, phone, opened_at, closed_at, limit_per_transaction, limit_daily, limit_monthly, credit_limit`
+
+// rowScanner é satisfeita tanto por *sql.Row quanto por *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanClient monta um cliente a partir das colunas de clientColumns, sem
+// carregar as transações
+func scanClient(row rowScanner) (models.Client, error) {
 	var (
-		clientID     string
-		name         string
-		balance      float64
-		clientType   string
-		cpf          sql.NullString
-		cnpj         sql.NullString
-		transactions []byte
+		id         string
+		name       string
+		balance    models.Money
+		clientType string
+		cpf        sql.NullString
+		cnpj       sql.NullString
+		email      sql.NullString
+		phone      sql.NullString
+		openedAt   time.Time
+		closedAt   sql.NullTime
+		limits     [3]sql.NullString
+		credit     models.Money
 	)
 
-	err := p.db.QueryRow(query, id).Scan(
-		&clientID,
-		&name,
-		&balance,
-		&clientType,
-		&cpf,
-		&cnpj,
-		&transactions)
-
-	if err == sql.ErrNoRows {
-		return nil, errors.New("client not found")
-	}
+	err := row.Scan(&id, &name, &balance, &clientType, &cpf, &cnpj, &email, &phone, &openedAt, &closedAt,
+		&limits[0], &limits[1], &limits[2], &credit)
 	if err != nil {
-		return nil, fmt.Errorf("error getting client: %v", err)
+		return nil, err
 	}
 
-	var transactionsList []models.Transaction
-	if err := json.Unmarshal(transactions, &transactionsList); err != nil {
-		return nil, fmt.Errorf("error unmarshaling transactions: %v", err)
+	base := models.BaseClient{
+		ID:           id,
+		Name:         name,
+		Balance:      balance,
+		CreditLimit:  credit,
+		Email:        email.String,
+		Phone:        phone.String,
+		OpenedAt:     openedAt,
+		Transactions: make([]models.Transaction, 0),
+	}
+	if closedAt.Valid {
+		base.ClosedAt = &closedAt.Time
+	}
+	override := &base.LimitOverride
+	for i, dest := range []**models.Money{&override.PerTransaction, &override.Daily, &override.Monthly} {
+		if *dest, err = nullMoney(limits[i]); err != nil {
+			return nil, err
+		}
 	}
 
 	switch clientType {
 	case "personal":
-		return &models.PersonalClient{
-			BaseClient: models.BaseClient{
-				ID:           clientID,
-				Name:         name,
-				Balance:      balance,
-				Transactions: transactionsList,
-			},
-			CPF: cpf.String,
-		}, nil
+		return &models.PersonalClient{BaseClient: base, CPF: cpf.String}, nil
 	case "corporate":
-		return &models.CorporateClient{
-			BaseClient: models.BaseClient{
-				ID:           clientID,
-				Name:         name,
-				Balance:      balance,
-				Transactions: transactionsList,
-			},
-			CNPJ: cnpj.String,
-		}, nil
+		return &models.CorporateClient{BaseClient: base, CNPJ: cnpj.String}, nil
 	default:
 		return nil, fmt.Errorf("unknown client type: %s", clientType)
 	}
 }
 
-func (p *PostgresDB) UpdateClient(client models.Client) error {
-	var transactions []byte
-	var err error
-
+func setLimitUsage(client models.Client, usage models.LimitUsage) {
 	switch c := client.(type) {
 	case *models.PersonalClient:
-		transactions, err = json.Marshal(c.Transactions)
-		if err != nil {
-			return fmt.Errorf("error marshaling transactions: %v", err)
-		}
-
-		query := `
-			UPDATE clients
-			SET balance = $1, transactions = $2
-			WHERE id = $3 AND client_type = 'personal'`
+		c.LimitUsage = usage
+	case *models.CorporateClient:
+		c.LimitUsage = usage
+	}
+}
 
-		result, err := p.db.Exec(query, c.Balance, transactions, c.ID)
+// UpdateClientTx bloqueia a linha do cliente com SELECT ... FOR UPDATE, aplica fn
+// e grava o resultado e o registro de auditoria na mesma transação, evitando
+// que operações concorrentes sobre a mesma conta se sobrescrevam
+func (p *PostgresDB) UpdateClientTx(id string, audit *AuditEntry, fn func(models.Client) error) (models.Client, error) {
+	var client models.Client
+	err := p.inTx(func(tx *sql.Tx) error {
+		var err error
+		client, err = getClient(tx, id, true)
 		if err != nil {
-			return fmt.Errorf("error updating personal client: %v", err)
+			return err
 		}
-
-		rows, err := result.RowsAffected()
-		if err != nil {
-			return fmt.Errorf("error getting rows affected: %v", err)
+		before := client.GetBalance()
+		if err := fn(client); err != nil {
+			return err
 		}
-		if rows == 0 {
-			return errors.New("client not found")
+		if err := updateClient(tx, client); err != nil {
+			return err
 		}
+		return auditClient(tx, audit, id, &before, client.GetBalance())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client.MarkSaved()
+	return client, nil
+}
+
+// updateClient grava o saldo, os dados cadastrais, os limites, o limite de
+// crédito e o encerramento e insere apenas as transações ainda não gravadas
+func updateClient(q queryer, client models.Client) error {
+	var (
+		id         string
+		clientType string
+	)
 
+	switch c := client.(type) {
+	case *models.PersonalClient:
+		id, clientType = c.ID, "personal"
 	case *models.CorporateClient:
-		transactions, err = json.Marshal(c.Transactions)
-		if err != nil {
-			return fmt.Errorf("error marshaling transactions: %v", err)
-		}
+		id, clientType = c.ID, "corporate"
+	default:
+		return errors.New("invalid client type")
+	}
 
-		query := `
-			UPDATE clients
-			SET balance = $1, transactions = $2
-			WHERE id = $3 AND client_type = 'corporate'`
+	query := `
+		UPDATE clients
+		SET balance = $1, name = $2, email = $3, phone = $4, closed_at = $5,
+			limit_per_transaction = $6, limit_daily = $7, limit_monthly = $8, credit_limit = $9
+		WHERE id = $10 AND client_type = $11`
+
+	profile := client.GetProfile()
+	override := client.GetLimitOverride()
+	result, err := q.Exec(query,
+		client.GetBalance(),
+		profile.Name,
+		nullString(profile.Email),
+		nullString(profile.Phone),
+		nullTime(client.GetClosedAt()),
+		override.PerTransaction,
+		override.Daily,
+		override.Monthly,
+		client.GetCreditLimit(),
+		id,
+		clientType)
+	if err != nil {
+		return fmt.Errorf("error updating %s client: %w", clientType, classify(err))
+	}
 
-		result, err := p.db.Exec(query, c.Balance, transactions, c.ID)
-		if err != nil {
-			return fmt.Errorf("error updating corporate client: %v", err)
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", classify(err))
+	}
+	if rows == 0 {
+		return ErrClientNotFound
+	}
+
+	return insertTransactions(q, id, client.UnsavedTransactions())
+}
+
+// DeleteClient encerra a conta sem remover a linha: closed_at é preenchido e
+// o histórico continua disponível
+func (p *PostgresDB) DeleteClient(id string, audit *AuditEntry) error {
+	_, err := p.UpdateClientTx(id, audit, func(client models.Client) error {
+		return client.Close()
+	})
+	return err
+}
+
+func (p *PostgresDB) Transfer(fromID, toID string, amount models.Money, audit *AuditEntry) (*models.Transfer, error) {
+	return p.TransferWithDescription(fromID, toID, amount, "", audit)
+}
+
+func (p *PostgresDB) TransferWithDescription(fromID, toID string, amount models.Money, description string, audit *AuditEntry) (*models.Transfer, error) {
+	// Bloqueia as duas contas sempre na mesma ordem para evitar deadlocks
+	first, second := fromID, toID
+	if second < first {
+		first, second = second, first
+	}
+
+	var transfer *models.Transfer
+	locked := make(map[string]models.Client, 2)
+	err := p.inTx(func(tx *sql.Tx) error {
+		for _, id := range []string{first, second} {
+			if _, ok := locked[id]; ok {
+				continue
+			}
+			client, err := getClient(tx, id, true)
+			if err != nil {
+				return err
+			}
+			locked[id] = client
 		}
 
-		rows, err := result.RowsAffected()
+		var err error
+		transfer, err = models.ExecuteTransferWithDescription(locked[fromID], locked[toID], amount, description)
 		if err != nil {
-			return fmt.Errorf("error getting rows affected: %v", err)
-		}
-		if rows == 0 {
-			return errors.New("client not found")
+			return err
 		}
 
-	default:
-		return errors.New("invalid client type")
+		if err := updateClient(tx, locked[fromID]); err != nil {
+			return err
+		}
+		if err := updateClient(tx, locked[toID]); err != nil {
+			return err
+		}
+		if err := auditClient(tx, audit, fromID, &transfer.FromBalanceBefore, transfer.FromBalanceAfter); err != nil {
+			return err
+		}
+		return auditClient(tx, audit, toID, &transfer.ToBalanceBefore, transfer.ToBalanceAfter)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return transfer, nil
 }
 
-func (p *PostgresDB) ListClients() ([]models.Client, error) {
+// ListClients retorna uma página de clientes sem o histórico de transações,
+// aplicando filtros, ordenação e paginação por cursor diretamente no SQL
+func (p *PostgresDB) ListClients(filter ClientFilter) (*ClientPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	var conditions sqlConditions
+	add := conditions.add
+
+	if !filter.IncludeClosed {
+		add("closed_at IS NULL")
+	}
+	if filter.ClientType != "" {
+		add("client_type = $%d", filter.ClientType)
+	}
+	if filter.NamePrefix != "" {
+		add(`name ILIKE $%d ESCAPE '\'`, likePrefix(filter.NamePrefix))
+	}
+	if filter.MinBalance != nil {
+		add("balance >= $%d", *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		add("balance <= $%d", *filter.MaxBalance)
+	}
+	if filter.Document != "" {
+		add("(cpf = $%d OR cnpj = $%d)", filter.Document, filter.Document)
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM clients" + conditions.where()
+	if err := p.db.QueryRow(countQuery, conditions.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("error counting clients: %w", classify(err))
+	}
+
+	// Paginação por keyset: continua a partir do último (valor, id) retornado
+	column := filter.SortBy
+	cast := ""
+	if column == SortByBalance {
+		cast = "::DECIMAL(15,2)"
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		cursor, _ := decodeCursor(filter.Cursor)
+		add(fmt.Sprintf("(%s, id) %s ($%%d%s, $%%d)", column, comparison, cast), cursor.Value, cursor.ID)
+	}
+
 	query := `
-		SELECT id, name, balance, client_type, cpf, cnpj, transactions
-		FROM clients`
+		SELECT ` + clientColumns + `
+		FROM clients` + conditions.where() +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", column, direction, direction, filter.Limit+1)
 
-	rows, err := p.db.Query(query)
+	rows, err := p.db.Query(query, conditions.args...)
 	if err != nil {
-		return nil, fmt.Errorf("error listing clients: %v", err)
+		return nil, fmt.Errorf("error listing clients: %w", classify(err))
 	}
 	defer rows.Close()
 
-	var clients []models.Client
+	clients := make([]models.Client, 0, filter.Limit)
 
 	for rows.Next() {
-		var (
-			id           string
-			name         string
-			balance      float64
-			clientType   string
-			cpf          sql.NullString
-			cnpj         sql.NullString
-			transactions []byte
-		)
-
-		if err := rows.Scan(&id, &name, &balance, &clientType, &cpf, &cnpj, &transactions); err != nil {
-			return nil, fmt.Errorf("error scanning client: %v", err)
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning client: %w", classify(err))
 		}
+		clients = append(clients, client)
+	}
 
-		var transactionsList []models.Transaction
-		if err := json.Unmarshal(transactions, &transactionsList); err != nil {
-			return nil, fmt.Errorf("error unmarshaling transactions: %v", err)
-		}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating clients: %w", classify(err))
+	}
 
-		switch clientType {
-		case "personal":
-			clients = append(clients, &models.PersonalClient{
-				BaseClient: models.BaseClient{
-					ID:           id,
-					Name:         name,
-					Balance:      balance,
-					Transactions: transactionsList,
-				},
-				CPF: cpf.String,
-			})
-		case "corporate":
-			clients = append(clients, &models.CorporateClient{
-				BaseClient: models.BaseClient{
-					ID:           id,
-					Name:         name,
-					Balance:      balance,
-					Transactions: transactionsList,
-				},
-				CNPJ: cnpj.String,
-			})
-		}
+	page := &ClientPage{Clients: clients, Total: total}
+	if len(clients) > filter.Limit {
+		page.Clients = clients[:filter.Limit]
+		last := page.Clients[filter.Limit-1]
+		page.NextCursor = encodeCursor(pageCursor{Value: sortValue(last, filter.SortBy), ID: last.GetID()})
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating clients: %v", err)
+	return page, nil
+}
+
+// sqlConditions acumula condições de WHERE e seus argumentos posicionais
+type sqlConditions struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add acrescenta uma condição; cada %d em condition recebe o número do
+// placeholder do valor correspondente
+func (c *sqlConditions) add(condition string, values ...interface{}) {
+	placeholders := make([]interface{}, len(values))
+	for i, v := range values {
+		c.args = append(c.args, v)
+		placeholders[i] = len(c.args)
 	}
+	c.conditions = append(c.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+func (c *sqlConditions) where() string {
+	if len(c.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.conditions, " AND ")
+}
 
-	return clients, nil
+// likePrefix escapa os curingas do LIKE e acrescenta % ao final
+func likePrefix(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(prefix) + "%"
 }
 
 func (p *PostgresDB) Close() error {
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// auditLockID é a chave do advisory lock que serializa Append, para que cada
+// registro seja encadeado ao último gravado
+const auditLockID = 7_294_011
+
+// PostgresAuditLog grava o log de auditoria na tabela audit_log, que rejeita
+// UPDATE e DELETE
+type PostgresAuditLog struct {
+	pg *PostgresDB
+}
+
+// Audit retorna o log de auditoria que compartilha a conexão do banco
+func (p *PostgresDB) Audit() AuditLog {
+	return &PostgresAuditLog{pg: p}
+}
+
+const auditColumns = `id, actor, actor_type, action, client_id, user_id, schedule_id, balance_before, balance_after,
+	source_ip, request_id, created_at, prev_hash, hash`
+
+func (l *PostgresAuditLog) Append(entry AuditEntry) (*AuditEntry, error) {
+	var appended *AuditEntry
+	err := l.pg.inTx(func(tx *sql.Tx) error {
+		var err error
+		appended, err = appendAudit(tx, entry)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appended, nil
+}
+
+// appendAudit encadeia o registro ao último e o grava dentro de q. O advisory
+// lock fica com a transação até o fim dela; por isso a gravação deve ser o
+// último passo de uma transação que também bloqueia contas, evitando que ela
+// espere por uma conta segurando o log.
+func appendAudit(q queryer, entry AuditEntry) (*AuditEntry, error) {
+	if _, err := q.Exec(`SELECT pg_advisory_xact_lock($1)`, auditLockID); err != nil {
+		return nil, fmt.Errorf("error locking audit log: %w", classify(err))
+	}
+
+	last, err := scanAuditEntry(q.QueryRow(`SELECT ` + auditColumns + ` FROM audit_log ORDER BY id DESC LIMIT 1`))
+	if errors.Is(err, sql.ErrNoRows) {
+		last, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting last audit entry: %w", classify(err))
+	}
+
+	entry = chainEntry(entry, last, time.Now())
+	_, err = q.Exec(`INSERT INTO audit_log (`+auditColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		entry.ID, entry.Actor, entry.ActorType, entry.Action, nullString(entry.ClientID), nullString(entry.UserID),
+		nullString(entry.ScheduleID), entry.BalanceBefore, entry.BalanceAfter, entry.SourceIP, entry.RequestID, entry.CreatedAt,
+		entry.PrevHash, entry.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting audit entry: %w", classify(err))
+	}
+	return &entry, nil
+}
+
+// auditClient grava o registro de audit sobre a conta clientID, se houver
+func auditClient(q queryer, audit *AuditEntry, clientID string, before *models.Money, after models.Money) error {
+	if audit == nil {
+		return nil
+	}
+	_, err := appendAudit(q, clientAudit(*audit, clientID, before, after))
+	return err
+}
+
+func (l *PostgresAuditLog) List(filter AuditFilter) (*AuditPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	var conditions sqlConditions
+	add := conditions.add
+
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.ClientID != "" {
+		add("client_id = $%d", filter.ClientID)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at <= $%d", *filter.To)
+	}
+
+	order, comparison := "ASC", ">"
+	if filter.Descending {
+		order, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		after, _ := decodeAuditCursor(filter.Cursor)
+		add("id "+comparison+" $%d", after)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log` + conditions.where() +
+		fmt.Sprintf(` ORDER BY id %s LIMIT %d`, order, filter.Limit+1)
+
+	rows, err := l.pg.db.Query(query, conditions.args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing audit entries: %w", classify(err))
+	}
+	defer rows.Close()
+
+	page := &AuditPage{Entries: make([]AuditEntry, 0, filter.Limit)}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning audit entry: %w", classify(err))
+		}
+		page.Entries = append(page.Entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit entries: %w", classify(err))
+	}
+
+	if len(page.Entries) > filter.Limit {
+		page.Entries = page.Entries[:filter.Limit]
+		page.NextCursor = encodeAuditCursor(page.Entries[filter.Limit-1].ID)
+	}
+	return page, nil
+}
+
+func (l *PostgresAuditLog) Verify() (*AuditVerification, error) {
+	rows, err := l.pg.db.Query(`SELECT ` + auditColumns + ` FROM audit_log ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading audit log: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var v auditVerifier
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning audit entry: %w", classify(err))
+		}
+		if !v.check(*entry) {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit entries: %w", classify(err))
+	}
+	return v.verification(), nil
+}
+
+func scanAuditEntry(row rowScanner) (*AuditEntry, error) {
+	var (
+		entry                        AuditEntry
+		clientID, userID, scheduleID sql.NullString
+		before, after                sql.NullString
+	)
+	err := row.Scan(&entry.ID, &entry.Actor, &entry.ActorType, &entry.Action, &clientID, &userID, &scheduleID,
+		&before, &after, &entry.SourceIP, &entry.RequestID, &entry.CreatedAt, &entry.PrevHash, &entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.ClientID, entry.UserID, entry.ScheduleID = clientID.String, userID.String, scheduleID.String
+	entry.CreatedAt = entry.CreatedAt.UTC()
+	if entry.BalanceBefore, err = nullMoney(before); err != nil {
+		return nil, err
+	}
+	if entry.BalanceAfter, err = nullMoney(after); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func nullMoney(s sql.NullString) (*models.Money, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	money, err := models.ParseMoney(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &money, nil
+}
@@ -0,0 +1,263 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// PostgresHolderStore guarda os acessos na tabela account_holders
+type PostgresHolderStore struct {
+	pg *PostgresDB
+}
+
+// Holders retorna o repositório de acessos que compartilha a conexão do banco
+func (p *PostgresDB) Holders() *PostgresHolderStore {
+	return &PostgresHolderStore{pg: p}
+}
+
+func (s *PostgresHolderStore) GrantAccess(clientID, userID, role string) (*models.AccountHolder, error) {
+	if !models.IsValidRole(role) {
+		return nil, models.ErrInvalidRole
+	}
+
+	var holder models.AccountHolder
+	err := s.pg.inTx(func(tx *sql.Tx) error {
+		holders, err := lockHolders(tx, clientID, true)
+		if err != nil {
+			return err
+		}
+
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+			return fmt.Errorf("error getting user: %w", classify(err))
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+
+		if removesLastOwner(holders, userID, role) {
+			return ErrLastOwner
+		}
+
+		query := `
+			INSERT INTO account_holders (user_id, client_id, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, client_id) DO UPDATE SET role = EXCLUDED.role
+			RETURNING user_id, client_id, role, granted_at`
+
+		err = tx.QueryRow(query, userID, clientID, role).Scan(&holder.UserID, &holder.ClientID, &holder.Role, &holder.GrantedAt)
+		if err != nil {
+			return fmt.Errorf("error granting access: %w", classify(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &holder, nil
+}
+
+func (s *PostgresHolderStore) RevokeAccess(clientID, userID string) error {
+	return s.pg.inTx(func(tx *sql.Tx) error {
+		holders, err := lockHolders(tx, clientID, false)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, h := range holders {
+			found = found || h.UserID == userID
+		}
+		if !found {
+			return ErrHolderNotFound
+		}
+		if removesLastOwner(holders, userID, "") {
+			return ErrLastOwner
+		}
+
+		if _, err := tx.Exec(`DELETE FROM account_holders WHERE client_id = $1 AND user_id = $2`, clientID, userID); err != nil {
+			return fmt.Errorf("error revoking access: %w", classify(err))
+		}
+		return nil
+	})
+}
+
+func (s *PostgresHolderStore) ListHolders(clientID string) ([]models.AccountHolder, error) {
+	var exists bool
+	if err := s.pg.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM clients WHERE id = $1)`, clientID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error getting client: %w", classify(err))
+	}
+	if !exists {
+		return nil, ErrClientNotFound
+	}
+
+	return getHolders(s.pg.db, clientID)
+}
+
+func (s *PostgresHolderStore) ListUserAccounts(userID string) ([]UserAccount, error) {
+	var exists bool
+	if err := s.pg.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error getting user: %w", classify(err))
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	query := `
+		SELECT h.role, h.granted_at, ` + prefixColumns("c.", clientColumns) + `
+		FROM account_holders h
+		JOIN clients c ON c.id = h.client_id
+		WHERE h.user_id = $1
+		ORDER BY h.granted_at, h.client_id`
+
+	rows, err := s.pg.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing accounts: %w", classify(err))
+	}
+	defer rows.Close()
+
+	accounts := make([]UserAccount, 0)
+	for rows.Next() {
+		var account UserAccount
+		account.Client, err = scanClient(prefixScanner{row: rows, dest: []interface{}{&account.Role, &account.GrantedAt}})
+		if err != nil {
+			return nil, fmt.Errorf("error scanning account: %w", classify(err))
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating accounts: %w", classify(err))
+	}
+
+	return accounts, nil
+}
+
+func (s *PostgresHolderStore) DeleteUser(userID string) (*models.User, error) {
+	var user *models.User
+	err := s.pg.inTx(func(tx *sql.Tx) error {
+		// Bloqueia as contas do usuário na mesma ordem para não concorrer com
+		// GrantAccess e RevokeAccess
+		rows, err := tx.Query(`
+			SELECT c.id FROM clients c
+			JOIN account_holders h ON h.client_id = c.id
+			WHERE h.user_id = $1
+			ORDER BY c.id
+			FOR UPDATE OF c`, userID)
+		if err != nil {
+			return fmt.Errorf("error locking accounts: %w", classify(err))
+		}
+		var clientIDs []string
+		for rows.Next() {
+			var clientID string
+			if err := rows.Scan(&clientID); err != nil {
+				rows.Close()
+				return fmt.Errorf("error scanning account: %w", classify(err))
+			}
+			clientIDs = append(clientIDs, clientID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating accounts: %w", classify(err))
+		}
+
+		for _, clientID := range clientIDs {
+			holders, err := getHolders(tx, clientID)
+			if err != nil {
+				return err
+			}
+			if removesLastOwner(holders, userID, "") {
+				return ErrLastOwner
+			}
+		}
+
+		if _, err := tx.Exec(`DELETE FROM account_holders WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("error revoking access: %w", classify(err))
+		}
+		user, err = scanUser(tx.QueryRow(`DELETE FROM users WHERE id = $1 RETURNING `+userColumns, userID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error deleting user: %w", classify(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// lockHolders bloqueia a linha do cliente, serializando as alterações de
+// acesso à mesma conta, e retorna os acessos atuais. Com open exige que a
+// conta não esteja encerrada.
+func lockHolders(tx *sql.Tx, clientID string, open bool) ([]models.AccountHolder, error) {
+	var closedAt sql.NullTime
+	err := tx.QueryRow(`SELECT closed_at FROM clients WHERE id = $1 FOR UPDATE`, clientID).Scan(&closedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error locking client: %w", classify(err))
+	}
+	if open && closedAt.Valid {
+		return nil, models.ErrAccountClosed
+	}
+
+	return getHolders(tx, clientID)
+}
+
+func getHolders(q queryer, clientID string) ([]models.AccountHolder, error) {
+	query := `
+		SELECT user_id, client_id, role, granted_at
+		FROM account_holders
+		WHERE client_id = $1
+		ORDER BY granted_at, user_id`
+
+	rows, err := q.Query(query, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing holders: %w", classify(err))
+	}
+	defer rows.Close()
+
+	holders := make([]models.AccountHolder, 0)
+	for rows.Next() {
+		var h models.AccountHolder
+		if err := rows.Scan(&h.UserID, &h.ClientID, &h.Role, &h.GrantedAt); err != nil {
+			return nil, fmt.Errorf("error scanning holder: %w", classify(err))
+		}
+		holders = append(holders, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating holders: %w", classify(err))
+	}
+
+	return holders, nil
+}
+
+// prefixColumns qualifica cada coluna da lista com prefix
+func prefixColumns(prefix, columns string) string {
+	names := strings.Split(columns, ", ")
+	for i, name := range names {
+		names[i] = prefix + name
+	}
+	return strings.Join(names, ", ")
+}
+
+// prefixScanner lê colunas extras antes das colunas repassadas a scanClient
+type prefixScanner struct {
+	row  rowScanner
+	dest []interface{}
+}
+
+func (s prefixScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(s.dest, dest...)...)
+}
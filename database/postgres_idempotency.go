@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// PostgresIdempotencyStore guarda as chaves de idempotência na tabela
+// idempotency_keys
+type PostgresIdempotencyStore struct {
+	db *sql.DB
+}
+
+// Idempotency retorna o repositório de chaves de idempotência que compartilha
+// a conexão do banco
+func (p *PostgresDB) Idempotency() *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{db: p.db}
+}
+
+// Begin insere a chave ou, se ela já expirou ou ficou sem resposta além de
+// IdempotencyLease, a reaproveita. A chave pode ser liberada por outra
+// requisição entre o INSERT e o SELECT, e por isso a reserva é tentada mais
+// de uma vez.
+func (s *PostgresIdempotencyStore) Begin(scope, key, requestHash string) (*IdempotencyRecord, bool, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		record := &IdempotencyRecord{Scope: scope, Key: key, RequestHash: requestHash}
+		err := s.db.QueryRow(`
+			INSERT INTO idempotency_keys (scope, key, request_hash)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (scope, key) DO UPDATE SET
+				request_hash = EXCLUDED.request_hash,
+				status_code = NULL,
+				content_type = NULL,
+				response_body = NULL,
+				created_at = now(),
+				completed_at = NULL
+			WHERE idempotency_keys.created_at < now() - make_interval(secs => $4)
+				OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < now() - make_interval(secs => $5))
+			RETURNING created_at`,
+			scope, key, requestHash, IdempotencyTTL.Seconds(), IdempotencyLease.Seconds(),
+		).Scan(&record.CreatedAt)
+		if err == nil {
+			return record, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, fmt.Errorf("error reserving idempotency key: %w", classify(err))
+		}
+
+		existing, err := s.get(scope, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting idempotency key: %w", classify(err))
+		}
+		return existing, false, nil
+	}
+
+	return nil, false, fmt.Errorf("error reserving idempotency key: %w", ErrConflict)
+}
+
+func (s *PostgresIdempotencyStore) get(scope, key string) (*IdempotencyRecord, error) {
+	var (
+		record      = &IdempotencyRecord{Scope: scope, Key: key}
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+		body        []byte
+	)
+	err := s.db.QueryRow(`
+		SELECT request_hash, status_code, content_type, response_body, created_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2`,
+		scope, key,
+	).Scan(&record.RequestHash, &statusCode, &contentType, &body, &record.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode.Valid {
+		record.Response = &IdempotentResponse{
+			StatusCode:  int(statusCode.Int64),
+			ContentType: contentType.String,
+			Body:        body,
+		}
+	}
+	return record, nil
+}
+
+func (s *PostgresIdempotencyStore) Complete(scope, key string, response IdempotentResponse) error {
+	_, err := s.db.Exec(`
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5, completed_at = now()
+		WHERE scope = $1 AND key = $2 AND completed_at IS NULL`,
+		scope, key, response.StatusCode, response.ContentType, response.Body,
+	)
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key: %w", classify(err))
+	}
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) Release(scope, key string) error {
+	_, err := s.db.Exec(`
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND completed_at IS NULL`,
+		scope, key,
+	)
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", classify(err))
+	}
+	return nil
+}
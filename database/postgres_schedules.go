@@ -0,0 +1,221 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// PostgresScheduleStore guarda os agendamentos na tabela scheduled_operations
+// e as execuções em schedule_runs
+type PostgresScheduleStore struct {
+	pg *PostgresDB
+}
+
+// Schedules retorna o repositório de agendamentos que compartilha a conexão
+// do banco
+func (p *PostgresDB) Schedules() *PostgresScheduleStore {
+	return &PostgresScheduleStore{pg: p}
+}
+
+const scheduleColumns = `id, client_id, type, amount, to_client_id, description, run_at, recurrence, next_run_at,
+	status, attempts, retry_at, last_run_at, last_error, claimed_at, version, created_at, updated_at`
+
+const scheduleRunColumns = `id, schedule_id, scheduled_for, executed_at, attempt, status, error, transaction_id, transfer_id`
+
+func (s *PostgresScheduleStore) CreateSchedule(op *models.ScheduledOperation) error {
+	_, err := s.pg.db.Exec(`INSERT INTO scheduled_operations (`+scheduleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, 1, $16, $17)`,
+		op.ID, op.ClientID, op.Type, op.Amount, nullString(op.ToClientID), op.Description, nullTime(op.RunAt),
+		op.Recurrence, nullTime(op.NextRunAt), op.Status, op.Attempts, nullTime(op.RetryAt), nullTime(op.LastRunAt),
+		op.LastError, nullTime(op.ClaimedAt), op.CreatedAt, op.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting scheduled operation: %w", classify(err))
+	}
+	op.Version = 1
+	return nil
+}
+
+func (s *PostgresScheduleStore) GetSchedule(id string) (*models.ScheduledOperation, error) {
+	return getSchedule(s.pg.db, id)
+}
+
+func getSchedule(q queryer, id string) (*models.ScheduledOperation, error) {
+	op, err := scanSchedule(q.QueryRow(`SELECT `+scheduleColumns+` FROM scheduled_operations WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting scheduled operation: %w", classify(err))
+	}
+	return op, nil
+}
+
+func (s *PostgresScheduleStore) ListSchedules(clientID string) ([]models.ScheduledOperation, error) {
+	return s.query(`SELECT `+scheduleColumns+` FROM scheduled_operations
+		WHERE client_id = $1 ORDER BY created_at, id COLLATE "C"`, clientID)
+}
+
+func (s *PostgresScheduleStore) ListDueSchedules(now time.Time, limit int) ([]models.ScheduledOperation, error) {
+	due, err := s.query(`SELECT `+scheduleColumns+` FROM scheduled_operations
+		WHERE status = 'active' AND COALESCE(retry_at, next_run_at) <= $1
+		ORDER BY COALESCE(retry_at, next_run_at), id COLLATE "C"
+		LIMIT $2`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	running, err := s.query(`SELECT ` + scheduleColumns + ` FROM scheduled_operations WHERE status = 'running'`)
+	if err != nil {
+		return nil, err
+	}
+	return append(due, running...), nil
+}
+
+func (s *PostgresScheduleStore) UpdateSchedule(op *models.ScheduledOperation) error {
+	if err := updateSchedule(s.pg.db, op); err != nil {
+		return err
+	}
+	op.Version++
+	return nil
+}
+
+// RecordScheduleRun só incrementa op.Version depois do commit, para que uma
+// falha ao inserir a execução não deixe op com uma versão que não foi gravada
+func (s *PostgresScheduleStore) RecordScheduleRun(op *models.ScheduledOperation, run models.ScheduleRun) error {
+	if run.ScheduleID != op.ID {
+		return ErrScheduleNotFound
+	}
+	err := s.pg.inTx(func(tx *sql.Tx) error {
+		if err := updateSchedule(tx, op); err != nil {
+			return err
+		}
+		return insertScheduleRun(tx, run)
+	})
+	if err != nil {
+		return err
+	}
+	op.Version++
+	return nil
+}
+
+// updateSchedule grava op se a versão guardada for op.Version, sem alterar op
+func updateSchedule(q queryer, op *models.ScheduledOperation) error {
+	result, err := q.Exec(`
+		UPDATE scheduled_operations
+		SET type = $1, amount = $2, to_client_id = $3, description = $4, run_at = $5, recurrence = $6,
+			next_run_at = $7, status = $8, attempts = $9, retry_at = $10, last_run_at = $11, last_error = $12,
+			claimed_at = $13, updated_at = $14, version = version + 1
+		WHERE id = $15 AND client_id = $16 AND version = $17`,
+		op.Type, op.Amount, nullString(op.ToClientID), op.Description, nullTime(op.RunAt), op.Recurrence,
+		nullTime(op.NextRunAt), op.Status, op.Attempts, nullTime(op.RetryAt), nullTime(op.LastRunAt), op.LastError,
+		nullTime(op.ClaimedAt), op.UpdatedAt, op.ID, op.ClientID, op.Version)
+	if err != nil {
+		return fmt.Errorf("error updating scheduled operation: %w", classify(err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", classify(err))
+	}
+	if rows == 0 {
+		// Distingue um agendamento inexistente de uma versão desatualizada
+		stored, err := getSchedule(q, op.ID)
+		if err != nil {
+			return err
+		}
+		if stored.ClientID != op.ClientID {
+			return ErrScheduleNotFound
+		}
+		return fmt.Errorf("scheduled operation %s was changed concurrently: %w", op.ID, ErrConflict)
+	}
+	return nil
+}
+
+func insertScheduleRun(q queryer, run models.ScheduleRun) error {
+	_, err := q.Exec(`INSERT INTO schedule_runs (`+scheduleRunColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		run.ID, run.ScheduleID, run.ScheduledFor, run.ExecutedAt, run.Attempt, run.Status, run.Error,
+		nullString(run.TransactionID), nullString(run.TransferID))
+	if err != nil {
+		return fmt.Errorf("error inserting schedule run: %w", classify(err))
+	}
+	return nil
+}
+
+func (s *PostgresScheduleStore) ListScheduleRuns(scheduleID string, limit int) ([]models.ScheduleRun, error) {
+	rows, err := s.pg.db.Query(`SELECT `+scheduleRunColumns+` FROM schedule_runs
+		WHERE schedule_id = $1 ORDER BY executed_at DESC, id COLLATE "C" DESC LIMIT $2`, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing schedule runs: %w", classify(err))
+	}
+	defer rows.Close()
+
+	runs := make([]models.ScheduleRun, 0)
+	for rows.Next() {
+		var (
+			run                       models.ScheduleRun
+			transactionID, transferID sql.NullString
+		)
+		err := rows.Scan(&run.ID, &run.ScheduleID, &run.ScheduledFor, &run.ExecutedAt, &run.Attempt, &run.Status,
+			&run.Error, &transactionID, &transferID)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning schedule run: %w", classify(err))
+		}
+		run.TransactionID, run.TransferID = transactionID.String, transferID.String
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schedule runs: %w", classify(err))
+	}
+	return runs, nil
+}
+
+func (s *PostgresScheduleStore) query(query string, args ...interface{}) ([]models.ScheduledOperation, error) {
+	rows, err := s.pg.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing scheduled operations: %w", classify(err))
+	}
+	defer rows.Close()
+
+	schedules := make([]models.ScheduledOperation, 0)
+	for rows.Next() {
+		op, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning scheduled operation: %w", classify(err))
+		}
+		schedules = append(schedules, *op)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scheduled operations: %w", classify(err))
+	}
+	return schedules, nil
+}
+
+func scanSchedule(row rowScanner) (*models.ScheduledOperation, error) {
+	var (
+		op                                              models.ScheduledOperation
+		toClientID                                      sql.NullString
+		runAt, nextRunAt, retryAt, lastRunAt, claimedAt sql.NullTime
+	)
+	err := row.Scan(&op.ID, &op.ClientID, &op.Type, &op.Amount, &toClientID, &op.Description, &runAt,
+		&op.Recurrence, &nextRunAt, &op.Status, &op.Attempts, &retryAt, &lastRunAt, &op.LastError, &claimedAt,
+		&op.Version, &op.CreatedAt, &op.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	op.ToClientID = toClientID.String
+	op.RunAt, op.NextRunAt, op.RetryAt = timePtr(runAt), timePtr(nextRunAt), timePtr(retryAt)
+	op.LastRunAt, op.ClaimedAt = timePtr(lastRunAt), timePtr(claimedAt)
+	return &op, nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -1,12 +1,33 @@
 package database
 
 import (
+	"errors"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Luis-Andrei/api-users/models"
 )
 
+func newPersonalClient(t *testing.T, name, cpf string, balance models.Money) *models.PersonalClient {
+	t.Helper()
+	client, err := models.NewPersonalClient(name, cpf, balance)
+	if err != nil {
+		t.Fatalf("Failed to build personal client: %v", err)
+	}
+	return client
+}
+
+func newCorporateClient(t *testing.T, name, cnpj string, balance models.Money) *models.CorporateClient {
+	t.Helper()
+	client, err := models.NewCorporateClient(name, cnpj, balance)
+	if err != nil {
+		t.Fatalf("Failed to build corporate client: %v", err)
+	}
+	return client
+}
+
 func setupTestDB(t *testing.T) *PostgresDB {
 	host := os.Getenv("TEST_DB_HOST")
 	if host == "" {
@@ -38,15 +59,44 @@ func setupTestDB(t *testing.T) *PostgresDB {
 		t.Fatalf("Failed to initialize tables: %v", err)
 	}
 
-	// Limpa a tabela de clientes antes de cada teste
+	// Limpa as tabelas de auditoria, chaves de idempotência, acessos, agendamentos, transações, clientes e usuários antes de cada teste
 	postgresDB, ok := db.(*PostgresDB)
 	if !ok {
 		t.Fatal("Failed to convert database to PostgresDB type")
 	}
+	// audit_log rejeita DELETE; TRUNCATE não dispara o trigger de linha
+	_, err = postgresDB.db.Exec("TRUNCATE audit_log")
+	if err != nil {
+		t.Fatalf("Failed to clean audit_log table: %v", err)
+	}
+	_, err = postgresDB.db.Exec("DELETE FROM idempotency_keys")
+	if err != nil {
+		t.Fatalf("Failed to clean idempotency_keys table: %v", err)
+	}
+	_, err = postgresDB.db.Exec("DELETE FROM account_holders")
+	if err != nil {
+		t.Fatalf("Failed to clean account_holders table: %v", err)
+	}
+	_, err = postgresDB.db.Exec("DELETE FROM schedule_runs")
+	if err != nil {
+		t.Fatalf("Failed to clean schedule_runs table: %v", err)
+	}
+	_, err = postgresDB.db.Exec("DELETE FROM scheduled_operations")
+	if err != nil {
+		t.Fatalf("Failed to clean scheduled_operations table: %v", err)
+	}
+	_, err = postgresDB.db.Exec("DELETE FROM transactions")
+	if err != nil {
+		t.Fatalf("Failed to clean transactions table: %v", err)
+	}
 	_, err = postgresDB.db.Exec("DELETE FROM clients")
 	if err != nil {
 		t.Fatalf("Failed to clean clients table: %v", err)
 	}
+	_, err = postgresDB.db.Exec("DELETE FROM users")
+	if err != nil {
+		t.Fatalf("Failed to clean users table: %v", err)
+	}
 
 	return postgresDB
 }
@@ -56,10 +106,10 @@ func TestPostgresDB_PersonalClient(t *testing.T) {
 	defer db.Close()
 
 	// Cria um cliente pessoa física
-	client := models.NewPersonalClient("John Doe", "123.456.789-00", 2000.0)
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("2000.00"))
 
 	// Testa a criação do cliente
-	err := db.CreatePersonalClient(client)
+	err := db.CreatePersonalClient(client, nil)
 	if err != nil {
 		t.Fatalf("Failed to create personal client: %v", err)
 	}
@@ -80,24 +130,20 @@ func TestPostgresDB_PersonalClient(t *testing.T) {
 	}
 
 	// Testa o saque
-	err = personalClient.Withdraw(500.0)
+	_, err = db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+		return c.Withdraw(models.MustParseMoney("500.00"))
+	})
 	if err != nil {
 		t.Fatalf("Failed to withdraw: %v", err)
 	}
 
-	// Testa a atualização do cliente
-	err = db.UpdateClient(personalClient)
-	if err != nil {
-		t.Fatalf("Failed to update client: %v", err)
-	}
-
 	// Verifica se o saldo foi atualizado
 	updatedClient, err := db.GetClient(client.ID)
 	if err != nil {
 		t.Fatalf("Failed to get updated client: %v", err)
 	}
 
-	if updatedClient.GetBalance() != 1500.0 {
+	if updatedClient.GetBalance() != models.MustParseMoney("1500.00") {
 		t.Errorf("Expected balance of 1500.0, got %v", updatedClient.GetBalance())
 	}
 }
@@ -107,10 +153,10 @@ func TestPostgresDB_CorporateClient(t *testing.T) {
 	defer db.Close()
 
 	// Cria um cliente pessoa jurídica
-	client := models.NewCorporateClient("ACME Corp", "12.345.678/0001-00", 10000.0)
+	client := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("10000.00"))
 
 	// Testa a criação do cliente
-	err := db.CreateCorporateClient(client)
+	err := db.CreateCorporateClient(client, nil)
 	if err != nil {
 		t.Fatalf("Failed to create corporate client: %v", err)
 	}
@@ -131,24 +177,20 @@ func TestPostgresDB_CorporateClient(t *testing.T) {
 	}
 
 	// Testa o saque
-	err = corporateClient.Withdraw(3000.0)
+	_, err = db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+		return c.Withdraw(models.MustParseMoney("3000.00"))
+	})
 	if err != nil {
 		t.Fatalf("Failed to withdraw: %v", err)
 	}
 
-	// Testa a atualização do cliente
-	err = db.UpdateClient(corporateClient)
-	if err != nil {
-		t.Fatalf("Failed to update client: %v", err)
-	}
-
 	// Verifica se o saldo foi atualizado
 	updatedClient, err := db.GetClient(client.ID)
 	if err != nil {
 		t.Fatalf("Failed to get updated client: %v", err)
 	}
 
-	if updatedClient.GetBalance() != 7000.0 {
+	if updatedClient.GetBalance() != models.MustParseMoney("7000.00") {
 		t.Errorf("Expected balance of 7000.0, got %v", updatedClient.GetBalance())
 	}
 }
@@ -158,24 +200,25 @@ func TestPostgresDB_ListClients(t *testing.T) {
 	defer db.Close()
 
 	// Cria um cliente pessoa física
-	personalClient := models.NewPersonalClient("John Doe", "123.456.789-00", 2000.0)
-	err := db.CreatePersonalClient(personalClient)
+	personalClient := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("2000.00"))
+	err := db.CreatePersonalClient(personalClient, nil)
 	if err != nil {
 		t.Fatalf("Failed to create personal client: %v", err)
 	}
 
 	// Cria um cliente pessoa jurídica
-	corporateClient := models.NewCorporateClient("ACME Corp", "12.345.678/0001-00", 10000.0)
-	err = db.CreateCorporateClient(corporateClient)
+	corporateClient := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("10000.00"))
+	err = db.CreateCorporateClient(corporateClient, nil)
 	if err != nil {
 		t.Fatalf("Failed to create corporate client: %v", err)
 	}
 
 	// Lista todos os clientes
-	clients, err := db.ListClients()
+	page, err := db.ListClients(ClientFilter{})
 	if err != nil {
 		t.Fatalf("Failed to list clients: %v", err)
 	}
+	clients := page.Clients
 
 	if len(clients) != 2 {
 		t.Errorf("Expected 2 clients, got %d", len(clients))
@@ -203,3 +246,295 @@ func TestPostgresDB_ListClients(t *testing.T) {
 		t.Error("Corporate client not found in list")
 	}
 }
+
+func TestPostgresDB_Transfer(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	from := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("10000.00"))
+	if err := db.CreateCorporateClient(from, nil); err != nil {
+		t.Fatalf("Failed to create corporate client: %v", err)
+	}
+	to := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(0))
+	if err := db.CreatePersonalClient(to, nil); err != nil {
+		t.Fatalf("Failed to create personal client: %v", err)
+	}
+
+	// Testa a transferência
+	transfer, err := db.Transfer(from.ID, to.ID, models.MustParseMoney("2500.00"), nil)
+	if err != nil {
+		t.Fatalf("Failed to transfer: %v", err)
+	}
+
+	updatedFrom, err := db.GetClient(from.ID)
+	if err != nil {
+		t.Fatalf("Failed to get source client: %v", err)
+	}
+	updatedTo, err := db.GetClient(to.ID)
+	if err != nil {
+		t.Fatalf("Failed to get destination client: %v", err)
+	}
+
+	if updatedFrom.GetBalance() != models.MustParseMoney("7500.00") {
+		t.Errorf("Expected balance of 7500.0, got %v", updatedFrom.GetBalance())
+	}
+	if updatedTo.GetBalance() != models.MustParseMoney("2500.00") {
+		t.Errorf("Expected balance of 2500.0, got %v", updatedTo.GetBalance())
+	}
+	statement, err := db.GetStatement(to.ID, StatementFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if statement.Transactions[0].TransferID != transfer.ID {
+		t.Errorf("Expected transfer_in linked to transfer %v", transfer.ID)
+	}
+
+	// Testa que uma transferência acima do limite não altera os saldos
+	if _, err := db.Transfer(from.ID, to.ID, models.MustParseMoney("6000.00"), nil); !errors.Is(err, models.ErrWithdrawLimit) {
+		t.Errorf("Expected ErrWithdrawLimit, got %v", err)
+	}
+	unchanged, err := db.GetClient(from.ID)
+	if err != nil {
+		t.Fatalf("Failed to get source client: %v", err)
+	}
+	if unchanged.GetBalance() != models.MustParseMoney("7500.00") {
+		t.Errorf("Expected balance of 7500.0, got %v", unchanged.GetBalance())
+	}
+}
+
+func TestPostgresDB_ConcurrentWithdrawals(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	client := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("1000.00"))
+	if err := db.CreateCorporateClient(client, nil); err != nil {
+		t.Fatalf("Failed to create corporate client: %v", err)
+	}
+
+	// Dispara saques concorrentes sobre a mesma conta
+	const withdrawals = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, withdrawals)
+	for i := 0; i < withdrawals; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+				return c.Withdraw(models.MustParseMoney("10.00"))
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Failed to withdraw: %v", err)
+		}
+	}
+
+	// Nenhum débito pode ter sido perdido
+	updated, err := db.GetClient(client.ID)
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+	if updated.GetBalance() != models.MustParseMoney("800.00") {
+		t.Errorf("Expected balance of 800.00, got %v", updated.GetBalance())
+	}
+	statement, err := db.GetStatement(client.ID, StatementFilter{Limit: MaxPageSize})
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if statement.Total != withdrawals {
+		t.Errorf("Expected %d transactions, got %d", withdrawals, statement.Total)
+	}
+}
+
+func TestPostgresDB_Statement(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("2000.00"))
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create personal client: %v", err)
+	}
+
+	// Cada atualização deve gravar apenas as transações novas
+	for i := 0; i < 3; i++ {
+		_, err := db.UpdateClientTx(client.ID, nil, func(c models.Client) error {
+			return c.Withdraw(models.MustParseMoney("100.00"))
+		})
+		if err != nil {
+			t.Fatalf("Failed to withdraw: %v", err)
+		}
+	}
+
+	statement, err := db.GetStatement(client.ID, StatementFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if len(statement.Transactions) != 3 {
+		t.Fatalf("Expected 3 transactions, got %d", len(statement.Transactions))
+	}
+	for _, transaction := range statement.Transactions {
+		if transaction.Type != models.TransactionTypeWithdrawal || transaction.Amount != models.MustParseMoney("100.00") {
+			t.Errorf("Unexpected transaction %+v", transaction)
+		}
+	}
+	if statement.OpeningBalance != models.MustParseMoney("2000.00") || statement.ClosingBalance != models.MustParseMoney("1700.00") {
+		t.Errorf("Expected balances 2000.00 -> 1700.00, got %v -> %v", statement.OpeningBalance, statement.ClosingBalance)
+	}
+
+	// Testa o período a partir da segunda transação, paginado de um em um
+	from := statement.Transactions[1].CreatedAt
+	filter := StatementFilter{From: &from, Types: []string{models.TransactionTypeWithdrawal}, Limit: 1}
+	page, err := db.GetStatement(client.ID, filter)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if page.Total != 2 || len(page.Transactions) != 1 || page.NextCursor == "" {
+		t.Fatalf("Expected first page of 2 transactions, got %+v", page)
+	}
+	if page.OpeningBalance != models.MustParseMoney("1900.00") {
+		t.Errorf("Expected opening balance of 1900.00, got %v", page.OpeningBalance)
+	}
+
+	filter.Cursor = page.NextCursor
+	page, err = db.GetStatement(client.ID, filter)
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if len(page.Transactions) != 1 || page.Transactions[0].ID != statement.Transactions[2].ID || page.NextCursor != "" {
+		t.Errorf("Expected last transaction on second page, got %+v", page)
+	}
+
+	// Testa o extrato de um cliente inexistente
+	if _, err := db.GetStatement("00000000-0000-0000-0000-000000000000", StatementFilter{}); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Expected ErrClientNotFound, got %v", err)
+	}
+}
+
+func TestPostgresDB_ListClientsPagination(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	names := []string{"Ana", "Bruno", "Carla", "Daniel", "Eduarda"}
+	cpfs := []string{"123.456.789-09", "111.444.777-35", "987.654.321-00", "246.813.579-28", "135.792.468-28"}
+	for i, name := range names {
+		client := newPersonalClient(t, name, cpfs[i], models.MustParseMoney("100.00"))
+		if err := db.CreatePersonalClient(client, nil); err != nil {
+			t.Fatalf("Failed to create personal client: %v", err)
+		}
+	}
+	corporate := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("10000.00"))
+	if err := db.CreateCorporateClient(corporate, nil); err != nil {
+		t.Fatalf("Failed to create corporate client: %v", err)
+	}
+
+	// Percorre os clientes pessoa física de dois em dois
+	filter := ClientFilter{ClientType: "personal", Limit: 2}
+	var seen []string
+	for {
+		page, err := db.ListClients(filter)
+		if err != nil {
+			t.Fatalf("Failed to list clients: %v", err)
+		}
+		if page.Total != len(names) {
+			t.Errorf("Expected total of %d, got %d", len(names), page.Total)
+		}
+		for _, client := range page.Clients {
+			seen = append(seen, client.GetName())
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	if strings.Join(seen, ",") != strings.Join(names, ",") {
+		t.Errorf("Expected %v, got %v", names, seen)
+	}
+
+	// Filtra por prefixo de nome e faixa de saldo
+	minBalance := models.MustParseMoney("1000.00")
+	page, err := db.ListClients(ClientFilter{NamePrefix: "ac", MinBalance: &minBalance})
+	if err != nil {
+		t.Fatalf("Failed to list clients: %v", err)
+	}
+	if page.Total != 1 || page.Clients[0].GetID() != corporate.ID {
+		t.Errorf("Expected only %s, got %d clients", corporate.Name, page.Total)
+	}
+}
+
+func TestPostgresDB_DuplicateDocument(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("100.00"))
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create personal client: %v", err)
+	}
+
+	// O mesmo CPF, ainda que digitado sem pontuação, não pode ser reutilizado
+	duplicate := newPersonalClient(t, "Jane Doe", "52998224725", models.MustParseMoney("100.00"))
+	if err := db.CreatePersonalClient(duplicate, nil); !errors.Is(err, ErrDuplicateDocument) {
+		t.Errorf("Expected ErrDuplicateDocument, got %v", err)
+	}
+}
+
+func TestPostgresUserStore(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	users := db.Users()
+
+	user, err := models.NewUser("Test", "User", "This is a test user biography with more than 20 characters")
+	if err != nil {
+		t.Fatalf("Failed to build user: %v", err)
+	}
+	inserted, err := users.Insert(user)
+	if err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if inserted.ID == "" {
+		t.Error("Expected Insert to assign an ID")
+	}
+
+	found, err := users.FindByID(inserted.ID)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if *found != *inserted {
+		t.Errorf("Found user does not match: got %v want %v", found, inserted)
+	}
+
+	all, err := users.FindAll()
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if len(all) != 1 {
+		t.Errorf("FindAll returned wrong number of users: got %v want %v", len(all), 1)
+	}
+
+	updated, err := users.Update(inserted.ID, &models.User{FirstName: "Updated", LastName: "User", Biography: inserted.Biography})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.ID != inserted.ID || updated.FirstName != "Updated" {
+		t.Errorf("Updated user does not match: got %v", updated)
+	}
+	if _, err := users.Update("missing", updated); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Update of missing user returned wrong error: got %v want %v", err, ErrUserNotFound)
+	}
+
+	deleted, err := users.Delete(inserted.ID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if deleted.FirstName != "Updated" {
+		t.Errorf("Deleted user does not match: got %v", deleted)
+	}
+	if _, err := users.FindByID(inserted.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("FindByID after delete returned wrong error: got %v want %v", err, ErrUserNotFound)
+	}
+}
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/lib/pq"
+)
+
+const transactionColumns = `id, client_id, type, amount, description, transfer_id, counterparty_id, created_at, overdraft_amount, posting_key, reversed_transaction_id`
+
+// insertTransactions grava novas transações de um cliente na tabela transactions
+func insertTransactions(q queryer, clientID string, transactions []models.Transaction) error {
+	query := `
+		INSERT INTO transactions (` + transactionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	for _, t := range transactions {
+		_, err := q.Exec(query,
+			t.ID,
+			clientID,
+			t.Type,
+			t.Amount,
+			t.Description,
+			nullString(t.TransferID),
+			nullString(t.CounterpartyID),
+			t.CreatedAt,
+			t.OverdraftAmount,
+			nullString(t.PostingKey),
+			nullString(t.ReversedTransactionID))
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_transactions_reversed" {
+			return models.ErrAlreadyReversed
+		}
+		if err != nil {
+			return fmt.Errorf("error inserting transaction: %w", classify(err))
+		}
+	}
+
+	return nil
+}
+
+// getLimitUsage soma os débitos que contam para os limites desde o início do
+// mês de now, como models.ComputeLimitUsage, lendo pelo índice
+// (client_id, created_at) apenas as transações do mês
+func getLimitUsage(q queryer, clientID string, now time.Time) (models.LimitUsage, error) {
+	startOfDay, startOfMonth := models.UsageWindow(now)
+	query := `
+		SELECT COALESCE(SUM(t.amount) FILTER (WHERE t.created_at >= $3), 0), COALESCE(SUM(t.amount), 0)
+		FROM transactions t
+		WHERE t.client_id = $1 AND t.created_at >= $2 AND t.type IN (` + sqlList(models.LimitedTransactionTypes) + `)
+			AND NOT EXISTS (SELECT 1 FROM transactions r WHERE r.reversed_transaction_id = t.id)`
+
+	var used models.LimitUsage
+	if err := q.QueryRow(query, clientID, startOfMonth, startOfDay).Scan(&used.Daily, &used.Monthly); err != nil {
+		return models.LimitUsage{}, fmt.Errorf("error getting limit usage: %w", classify(err))
+	}
+	return used, nil
+}
+
+func (p *PostgresDB) GetTransaction(id, txID string) (*models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE client_id = $1 AND id = $2`
+
+	t, _, err := scanTransaction(p.db.QueryRow(query, id, txID))
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := p.checkClient(id); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting transaction: %w", classify(err))
+	}
+	return &t, nil
+}
+
+func (p *PostgresDB) HasPosting(id, key string) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM clients WHERE id = $1),
+			EXISTS (SELECT 1 FROM transactions WHERE client_id = $1 AND posting_key = $2)`
+
+	var exists, posted bool
+	if err := p.db.QueryRow(query, id, key).Scan(&exists, &posted); err != nil {
+		return false, fmt.Errorf("error getting posting: %w", classify(err))
+	}
+	if !exists {
+		return false, ErrClientNotFound
+	}
+	return posted, nil
+}
+
+// BalanceAt desconta do saldo atual as transações posteriores a at, lidas
+// pelo índice (client_id, created_at)
+func (p *PostgresDB) BalanceAt(id string, at time.Time) (models.Money, error) {
+	query := `
+		SELECT c.balance, COALESCE(SUM(` + signedAmountSQL() + `), 0)
+		FROM clients c
+		LEFT JOIN transactions t ON t.client_id = c.id AND t.created_at > $2
+		WHERE c.id = $1
+		GROUP BY c.id, c.balance`
+
+	var balance, after models.Money
+	err := p.db.QueryRow(query, id, at).Scan(&balance, &after)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Money{}, ErrClientNotFound
+	}
+	if err != nil {
+		return models.Money{}, fmt.Errorf("error getting balance: %w", classify(err))
+	}
+	return balance.Sub(after), nil
+}
+
+// checkClient retorna ErrClientNotFound se o cliente não existir
+func (p *PostgresDB) checkClient(id string) error {
+	var exists bool
+	if err := p.db.QueryRow("SELECT EXISTS (SELECT 1 FROM clients WHERE id = $1)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("error getting client: %w", classify(err))
+	}
+	if !exists {
+		return ErrClientNotFound
+	}
+	return nil
+}
+
+// scanTransaction lê uma linha com as colunas de transactionColumns
+func scanTransaction(row rowScanner) (models.Transaction, string, error) {
+	var (
+		t              models.Transaction
+		clientID       string
+		transferID     sql.NullString
+		counterpartyID sql.NullString
+		overdraft      sql.NullString
+		postingKey     sql.NullString
+		reversedID     sql.NullString
+	)
+
+	err := row.Scan(
+		&t.ID,
+		&clientID,
+		&t.Type,
+		&t.Amount,
+		&t.Description,
+		&transferID,
+		&counterpartyID,
+		&t.CreatedAt,
+		&overdraft,
+		&postingKey,
+		&reversedID)
+	if err != nil {
+		return models.Transaction{}, "", err
+	}
+
+	t.TransferID = transferID.String
+	t.CounterpartyID = counterpartyID.String
+	t.PostingKey = postingKey.String
+	t.ReversedTransactionID = reversedID.String
+	if t.OverdraftAmount, err = nullMoney(overdraft); err != nil {
+		return models.Transaction{}, "", err
+	}
+	return t, clientID, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// signedAmountSQL é a expressão SQL equivalente a Transaction.SignedAmount
+func signedAmountSQL() string {
+	return "CASE WHEN t.type IN (" + sqlList(models.CreditTransactionTypes) + ") THEN t.amount ELSE -t.amount END"
+}
+
+// sqlList escreve os tipos de transação, constantes do pacote models, como
+// uma lista de literais SQL
+func sqlList(types []string) string {
+	quoted := make([]string, len(types))
+	for i, t := range types {
+		quoted[i] = "'" + t + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// GetStatement retorna uma página do extrato com os saldos de abertura e
+// fechamento do período, lidos de um mesmo snapshot do banco
+func (p *PostgresDB) GetStatement(id string, filter StatementFilter) (*Statement, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := p.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", classify(err))
+	}
+	defer tx.Rollback()
+
+	// Os saldos do período são obtidos descontando do saldo atual as
+	// transações posteriores a From e a To
+	balanceQuery := `
+		SELECT c.balance,
+			COALESCE(SUM(CASE WHEN $2::TIMESTAMPTZ IS NULL OR t.created_at >= $2 THEN ` + signedAmountSQL() + ` END), 0),
+			COALESCE(SUM(CASE WHEN t.created_at > $3::TIMESTAMPTZ THEN ` + signedAmountSQL() + ` END), 0)
+		FROM clients c
+		LEFT JOIN transactions t ON t.client_id = c.id
+		WHERE c.id = $1
+		GROUP BY c.id, c.balance`
+
+	var balance, sinceFrom, afterTo models.Money
+	err = tx.QueryRow(balanceQuery, id, nullTime(filter.From), nullTime(filter.To)).Scan(&balance, &sinceFrom, &afterTo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting statement balances: %w", classify(err))
+	}
+
+	statement := &Statement{
+		OpeningBalance: balance.Sub(sinceFrom),
+		ClosingBalance: balance.Sub(afterTo),
+		Transactions:   make([]models.Transaction, 0),
+	}
+
+	var conditions sqlConditions
+	conditions.add("client_id = $%d", id)
+	if filter.From != nil {
+		conditions.add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		conditions.add("created_at <= $%d", *filter.To)
+	}
+	if len(filter.Types) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("$%d, ", len(filter.Types)), ", ")
+		types := make([]interface{}, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = t
+		}
+		conditions.add("type IN ("+placeholders+")", types...)
+	}
+	if filter.MinAmount != nil {
+		conditions.add("amount >= $%d", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		conditions.add("amount <= $%d", *filter.MaxAmount)
+	}
+
+	countQuery := "SELECT COUNT(*) FROM transactions" + conditions.where()
+	if err := tx.QueryRow(countQuery, conditions.args...).Scan(&statement.Total); err != nil {
+		return nil, fmt.Errorf("error counting transactions: %w", classify(err))
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		createdAt, cursorID, _ := decodeStatementCursor(filter.Cursor)
+		conditions.add("(created_at, id COLLATE \"C\") "+comparison+" ($%d, $%d)", createdAt, cursorID)
+	}
+
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions` + conditions.where() +
+		fmt.Sprintf(` ORDER BY created_at %s, id COLLATE "C" %s LIMIT %d`, direction, direction, filter.Limit+1)
+
+	rows, err := tx.Query(query, conditions.args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, _, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning transaction: %w", classify(err))
+		}
+		statement.Transactions = append(statement.Transactions, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", classify(err))
+	}
+
+	if len(statement.Transactions) > filter.Limit {
+		statement.Transactions = statement.Transactions[:filter.Limit]
+		statement.NextCursor = encodeStatementCursor(statement.Transactions[filter.Limit-1])
+	}
+
+	return statement, nil
+}
+
+func nullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
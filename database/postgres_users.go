@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/google/uuid"
+)
+
+// PostgresUserStore é o repositório de usuários no PostgreSQL, com a mesma
+// semântica de DatabaseStruct
+type PostgresUserStore struct {
+	db *sql.DB
+}
+
+// Users retorna o repositório de usuários que compartilha a conexão do banco
+func (p *PostgresDB) Users() *PostgresUserStore {
+	return &PostgresUserStore{db: p.db}
+}
+
+const userColumns = `id, first_name, last_name, biography`
+
+func (s *PostgresUserStore) FindAll() ([]*models.User, error) {
+	rows, err := s.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY first_name, last_name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	users := make([]*models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user: %w", classify(err))
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", classify(err))
+	}
+
+	return users, nil
+}
+
+func (s *PostgresUserStore) FindByID(id string) (*models.User, error) {
+	user, err := scanUser(s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", classify(err))
+	}
+	return user, nil
+}
+
+// Insert gera um novo ID para o usuário e o grava
+func (s *PostgresUserStore) Insert(user *models.User) (*models.User, error) {
+	user.ID = uuid.New().String()
+
+	_, err := s.db.Exec(`INSERT INTO users (`+userColumns+`) VALUES ($1, $2, $3, $4)`,
+		user.ID, user.FirstName, user.LastName, user.Biography)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting user: %w", classify(err))
+	}
+	return user, nil
+}
+
+func (s *PostgresUserStore) Update(id string, user *models.User) (*models.User, error) {
+	query := `
+		UPDATE users
+		SET first_name = $1, last_name = $2, biography = $3
+		WHERE id = $4`
+
+	result, err := s.db.Exec(query, user.FirstName, user.LastName, user.Biography, id)
+	if err != nil {
+		return nil, fmt.Errorf("error updating user: %w", classify(err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error getting rows affected: %w", classify(err))
+	}
+	if rows == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	user.ID = id
+	return user, nil
+}
+
+// Delete remove o usuário e o retorna como estava antes da remoção
+func (s *PostgresUserStore) Delete(id string) (*models.User, error) {
+	user, err := scanUser(s.db.QueryRow(`DELETE FROM users WHERE id = $1 RETURNING `+userColumns, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error deleting user: %w", classify(err))
+	}
+	return user, nil
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Biography); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
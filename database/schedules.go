@@ -0,0 +1,176 @@
+package database
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+// ScheduleStore guarda os saques e transferências agendados e o registro de
+// cada execução
+type ScheduleStore interface {
+	// CreateSchedule grava um novo agendamento
+	CreateSchedule(op *models.ScheduledOperation) error
+	// GetSchedule retorna um agendamento pelo ID
+	GetSchedule(id string) (*models.ScheduledOperation, error)
+	// ListSchedules retorna os agendamentos do cliente, do mais antigo ao
+	// mais recente
+	ListSchedules(clientID string) ([]models.ScheduledOperation, error)
+	// ListDueSchedules retorna até limit agendamentos ativos que vencem até
+	// now, na ordem de vencimento, seguidos dos que estão em execução
+	ListDueSchedules(now time.Time, limit int) ([]models.ScheduledOperation, error)
+	// UpdateSchedule grava op se ele não foi alterado desde que foi lido,
+	// comparando Version, e incrementa Version. Caso contrário retorna
+	// ErrConflict.
+	UpdateSchedule(op *models.ScheduledOperation) error
+	// RecordScheduleRun grava op como UpdateSchedule e registra run na mesma
+	// transação. Se op foi alterado desde que foi lido, nada é gravado e o
+	// retorno é ErrConflict.
+	RecordScheduleRun(op *models.ScheduledOperation, run models.ScheduleRun) error
+	// ListScheduleRuns retorna as últimas limit execuções do agendamento, da
+	// mais recente à mais antiga
+	ListScheduleRuns(scheduleID string, limit int) ([]models.ScheduleRun, error)
+}
+
+// MemoryScheduleStore é a implementação de ScheduleStore em memória
+type MemoryScheduleStore struct {
+	mutex     sync.Mutex
+	schedules map[string]models.ScheduledOperation
+	runs      map[string][]models.ScheduleRun // agendamento -> execuções
+}
+
+// NewMemoryScheduleStore cria um repositório de agendamentos vazio
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{
+		schedules: make(map[string]models.ScheduledOperation),
+		runs:      make(map[string][]models.ScheduleRun),
+	}
+}
+
+func (s *MemoryScheduleStore) CreateSchedule(op *models.ScheduledOperation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.schedules[op.ID]; exists {
+		return ErrConflict
+	}
+	op.Version = 1
+	s.schedules[op.ID] = copySchedule(*op)
+	return nil
+}
+
+func (s *MemoryScheduleStore) GetSchedule(id string) (*models.ScheduledOperation, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	op, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	copied := copySchedule(op)
+	return &copied, nil
+}
+
+func (s *MemoryScheduleStore) ListSchedules(clientID string) ([]models.ScheduledOperation, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedules := make([]models.ScheduledOperation, 0)
+	for _, op := range s.schedules {
+		if op.ClientID == clientID {
+			schedules = append(schedules, copySchedule(op))
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].CreatedAt.Equal(schedules[j].CreatedAt) {
+			return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules, nil
+}
+
+func (s *MemoryScheduleStore) ListDueSchedules(now time.Time, limit int) ([]models.ScheduledOperation, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	due, running := make([]models.ScheduledOperation, 0), make([]models.ScheduledOperation, 0)
+	for _, op := range s.schedules {
+		switch {
+		case op.Status == models.ScheduleStatusRunning:
+			running = append(running, copySchedule(op))
+		case op.Status == models.ScheduleStatusActive && !op.DueAt().After(now):
+			due = append(due, copySchedule(op))
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].DueAt().Equal(due[j].DueAt()) {
+			return due[i].DueAt().Before(due[j].DueAt())
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return append(due, running...), nil
+}
+
+func (s *MemoryScheduleStore) UpdateSchedule(op *models.ScheduledOperation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.update(op)
+}
+
+func (s *MemoryScheduleStore) RecordScheduleRun(op *models.ScheduledOperation, run models.ScheduleRun) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if run.ScheduleID != op.ID {
+		return ErrScheduleNotFound
+	}
+	if err := s.update(op); err != nil {
+		return err
+	}
+	s.runs[run.ScheduleID] = append(s.runs[run.ScheduleID], run)
+	return nil
+}
+
+// update grava op se Version confere com a guardada; exige s.mutex
+func (s *MemoryScheduleStore) update(op *models.ScheduledOperation) error {
+	stored, ok := s.schedules[op.ID]
+	if !ok || stored.ClientID != op.ClientID {
+		return ErrScheduleNotFound
+	}
+	if stored.Version != op.Version {
+		return ErrConflict
+	}
+	op.Version++
+	s.schedules[op.ID] = copySchedule(*op)
+	return nil
+}
+
+func (s *MemoryScheduleStore) ListScheduleRuns(scheduleID string, limit int) ([]models.ScheduleRun, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := s.runs[scheduleID]
+	runs := make([]models.ScheduleRun, 0, limit)
+	for i := len(stored) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, stored[i])
+	}
+	return runs, nil
+}
+
+// copySchedule copia os ponteiros de op, para que o chamador não altere o
+// registro guardado
+func copySchedule(op models.ScheduledOperation) models.ScheduledOperation {
+	op.RunAt = copyTime(op.RunAt)
+	op.NextRunAt = copyTime(op.NextRunAt)
+	op.RetryAt = copyTime(op.RetryAt)
+	op.LastRunAt = copyTime(op.LastRunAt)
+	op.ClaimedAt = copyTime(op.ClaimedAt)
+	return op
+}
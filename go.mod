@@ -7,4 +7,4 @@ require (
 	github.com/gorilla/mux v1.8.1
 )
 
-require github.com/lib/pq v1.10.9 // indirect
+require github.com/lib/pq v1.10.9
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/accrual"
+	"github.com/Luis-Andrei/api-users/auth"
+)
+
+// RunAccrualsRequest é o corpo de POST /api/admin/accruals. Sem datas é
+// processado o último dia encerrado; date processa um dia e from e to um
+// período, para recuperar dias não processados.
+type RunAccrualsRequest struct {
+	Date string `json:"date"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RunAccrualsResponse traz o resultado de cada dia processado. Se o período
+// foi interrompido, Error é o erro que o interrompeu e Results traz os dias
+// processados até ali, cujos lançamentos continuam gravados.
+type RunAccrualsResponse struct {
+	Results []accrual.Result `json:"results"`
+	Error   *APIError        `json:"error,omitempty"`
+}
+
+// maxAccrualDays limita o período de uma chamada, que é processado dentro da
+// requisição
+const maxAccrualDays = 92
+
+// WithAccrualEngine define o motor de juros e tarifas usado por
+// POST /api/admin/accruals; por padrão as taxas e tarifas são zero
+func WithAccrualEngine(engine *accrual.Engine) Option {
+	return func(h *Handler) {
+		h.accrual = engine
+	}
+}
+
+// RunAccruals executa o motor de juros e tarifas sob demanda. Repetir um dia
+// já processado não lança nada em dobro.
+func (h *Handler) RunAccruals(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, ActionRunAccruals, auth.Resource{}) {
+		return
+	}
+
+	var req RunAccrualsRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	from, to, err := h.accrualPeriod(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Cada conta é registrada no log de auditoria junto com os lançamentos
+	results, err := h.accrual.RunRange(from, to, auditEntry(r, ActionRunAccruals))
+	if err != nil && len(results) == 0 {
+		writeError(w, r, err)
+		return
+	}
+	if err != nil {
+		problem := problemFor(err)
+		problem.RequestID = RequestIDFromContext(r.Context())
+		logError(r, problem, err)
+		writeJSON(w, problem.Status, RunAccrualsResponse{Results: results, Error: &problem})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RunAccrualsResponse{Results: results})
+}
+
+func (h *Handler) accrualPeriod(req RunAccrualsRequest) (time.Time, time.Time, error) {
+	switch {
+	case req.Date != "" && (req.From != "" || req.To != ""):
+		return time.Time{}, time.Time{}, invalidRequest("informe date ou from e to")
+	case req.Date != "":
+		day, err := h.accrual.ParseDate(req.Date)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalidRequest("date inválido")
+		}
+		return day, day, nil
+	case req.From != "" || req.To != "":
+		from, err := h.accrual.ParseDate(req.From)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalidRequest("from inválido")
+		}
+		to, err := h.accrual.ParseDate(req.To)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalidRequest("to inválido")
+		}
+		if from.After(to) {
+			return time.Time{}, time.Time{}, invalidRequest("from não pode ser posterior a to")
+		}
+		if to.After(from.AddDate(0, 0, maxAccrualDays-1)) {
+			return time.Time{}, time.Time{}, invalidRequest(fmt.Sprintf("o período pode ter no máximo %d dias", maxAccrualDays))
+		}
+		return from, to, nil
+	default:
+		day := h.accrual.Yesterday()
+		return day, day, nil
+	}
+}
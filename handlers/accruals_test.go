@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/accrual"
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func TestRunAccruals(t *testing.T) {
+	db := database.NewMemoryDB()
+	engine := accrual.New(db, accrual.Config{PersonalMonthlyFee: models.MustParseMoney("15.00")})
+	handler := NewHandler(db, WithAccrualEngine(engine))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/accruals", handler.RunAccruals).Methods("POST")
+
+	client, err := models.NewPersonalClient("John Doe", "529.982.247-25", models.MustParseMoney("100.00"))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	client.OpenedAt = client.OpenedAt.AddDate(0, -1, 0)
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	w := serve(router, "POST", "/api/admin/accruals", `{}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var response RunAccrualsResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response.Results) != 1 || response.Results[0].Fees != 1 || response.Results[0].Date != engine.Yesterday().Format(accrual.DateLayout) {
+		t.Errorf("Expected the monthly fee to be charged for yesterday, got %+v", response.Results)
+	}
+
+	// Repetir o dia não cobra a tarifa de novo
+	yesterday := engine.Yesterday().Format(accrual.DateLayout)
+	w = serve(router, "POST", "/api/admin/accruals", `{"date": "`+yesterday+`"}`)
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || response.Results[0].Fees != 0 {
+		t.Errorf("Expected nothing to be charged twice, got %d %+v", w.Code, response.Results)
+	}
+	if got, _ := db.GetClient(client.ID); got.GetBalance() != models.MustParseMoney("85.00") {
+		t.Errorf("Expected balance of 85.00, got %v", got.GetBalance())
+	}
+	page, _ := db.Audit().List(database.AuditFilter{Action: ActionRunAccruals})
+	if len(page.Entries) != 1 || page.Entries[0].ClientID != client.ID || page.Entries[0].Actor != "anonymous" {
+		t.Errorf("Expected the fee to be audited, got %+v", page.Entries)
+	}
+
+	today := time.Now().Format(accrual.DateLayout)
+	w = serve(router, "POST", "/api/admin/accruals", `{"date": "`+today+`"}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusBadRequest || problem.Code != CodeOpenBusinessDay {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeOpenBusinessDay, w.Code, problem.Code)
+	}
+
+	// Um período interrompido retorna os dias já processados junto com o erro
+	from := engine.Yesterday().AddDate(0, 0, -1).Format(accrual.DateLayout)
+	w = serve(router, "POST", "/api/admin/accruals", `{"from": "`+from+`", "to": "`+today+`"}`)
+	response = RunAccrualsResponse{}
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusBadRequest || response.Error == nil || response.Error.Code != CodeOpenBusinessDay || len(response.Results) != 2 {
+		t.Errorf("Expected %d %s with 2 results, got %d %+v", http.StatusBadRequest, CodeOpenBusinessDay, w.Code, response)
+	}
+
+	for _, body := range []string{
+		`{"from": "2026-02-10", "to": "2026-02-01"}`,
+		`{"from": "2026-01-01", "to": "2026-04-03"}`,
+	} {
+		w = serve(router, "POST", "/api/admin/accruals", body)
+		if problem := decodeProblem(t, w); w.Code != http.StatusBadRequest || problem.Code != CodeInvalidRequest {
+			t.Errorf("Expected %d %s for %s, got %d %s", http.StatusBadRequest, CodeInvalidRequest, body, w.Code, problem.Code)
+		}
+	}
+}
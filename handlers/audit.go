@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+)
+
+// Ações registradas no log de auditoria que detalham uma ação de autorização
+// mais ampla; as demais operações usam a própria ação de autorização
+const (
+	AuditGrantAccess  = "holders:grant"
+	AuditRevokeAccess = "holders:revoke"
+	AuditCreateUser   = "users:create"
+	AuditUpdateUser   = "users:update"
+	AuditDeleteUser   = "users:delete"
+
+	AuditCreateSchedule = "schedules:create"
+	AuditUpdateSchedule = "schedules:update"
+	AuditCancelSchedule = "schedules:cancel"
+)
+
+// auditEntry é o registro de uma operação feita pela requisição, com o
+// chamador, o IP de origem e o ID da requisição. As operações sobre contas o
+// passam ao banco, que o grava na mesma transação da alteração.
+func auditEntry(r *http.Request, action string) *database.AuditEntry {
+	entry := &database.AuditEntry{
+		Action:    action,
+		Actor:     "anonymous",
+		SourceIP:  sourceIP(r),
+		RequestID: RequestIDFromContext(r.Context()),
+	}
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		entry.Actor, entry.ActorType = principal.Subject, principal.Method
+	}
+	return entry
+}
+
+// recordAudit registra uma operação que não altera contas (usuários,
+// titulares e agendamentos), depois de concluída. O registro é
+// feito no melhor esforço: se falhar, a falha é apenas logada, porque a
+// operação já foi gravada e responder com erro levaria o cliente a repeti-la.
+func (h *Handler) recordAudit(r *http.Request, entry database.AuditEntry) {
+	template := auditEntry(r, entry.Action)
+	entry.Actor, entry.ActorType = template.Actor, template.ActorType
+	entry.SourceIP, entry.RequestID = template.SourceIP, template.RequestID
+
+	if _, err := h.audit.Append(entry); err != nil {
+		log.Printf("request %s: error recording audit entry %s: %v", entry.RequestID, entry.Action, err)
+	}
+}
+
+// sourceIP retorna o endereço de quem abriu a conexão, sem a porta
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ListAudit aceita os parâmetros de consulta actor, action, client_id, from e
+// to (RFC 3339 ou AAAA-MM-DD), order (asc ou desc), limit e cursor
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, ActionReadAudit, auth.Resource{}) {
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := h.audit.List(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// VerifyAudit recalcula a cadeia de hashes do log de auditoria
+func (h *Handler) VerifyAudit(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, ActionReadAudit, auth.Resource{}) {
+		return
+	}
+
+	verification, err := h.audit.Verify()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, verification)
+}
+
+func parseAuditFilter(r *http.Request) (database.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := database.AuditFilter{
+		Actor:    query.Get("actor"),
+		Action:   query.Get("action"),
+		ClientID: query.Get("client_id"),
+		Cursor:   query.Get("cursor"),
+	}
+
+	if v := query.Get("from"); v != "" {
+		from, err := parseTime(v, false)
+		if err != nil {
+			return filter, invalidRequest("from inválido")
+		}
+		filter.From = &from
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := parseTime(v, true)
+		if err != nil {
+			return filter, invalidRequest("to inválido")
+		}
+		filter.To = &to
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, invalidRequest("order deve ser asc ou desc")
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, invalidRequest("limit inválido")
+		}
+		filter.Limit = limit
+	}
+
+	if err := filter.Validate(); err != nil {
+		return filter, invalidRequest(err.Error())
+	}
+	return filter, nil
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func TestAuditLog(t *testing.T) {
+	db := database.NewMemoryDB()
+	handler := NewHandler(db)
+
+	router := mux.NewRouter()
+	router.Use(RequestID)
+	router.HandleFunc("/api/clients/{id}/withdraw", handler.Withdraw).Methods("POST")
+	router.HandleFunc("/api/transfers", handler.Transfer).Methods("POST")
+	router.HandleFunc("/api/audit", handler.ListAudit).Methods("GET")
+	router.HandleFunc("/api/audit/verify", handler.VerifyAudit).Methods("GET")
+
+	john := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(10000))
+	jane := newPersonalClient(t, "Jane Doe", "111.444.777-35", models.NewMoney(0))
+	for _, client := range []*models.PersonalClient{john, jane} {
+		if err := db.CreatePersonalClient(client, nil); err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+	}
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.RemoteAddr = "192.0.2.10:51234"
+		req.Header.Set(RequestIDHeader, "req-"+method)
+		req = req.WithContext(auth.NewContext(req.Context(), &auth.Principal{Subject: "branch", Method: auth.MethodAPIKey}))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("POST", "/api/clients/"+john.ID+"/withdraw", `{"amount": 30}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	// Operações que falham não são registradas
+	if w := send("POST", "/api/clients/"+john.ID+"/withdraw", `{"amount": 5000}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	transfer := `{"from_client_id": "` + john.ID + `", "to_client_id": "` + jane.ID + `", "amount": 20}`
+	if w := send("POST", "/api/transfers", transfer); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	w := send("GET", "/api/audit", "")
+	var page database.AuditPage
+	json.NewDecoder(w.Body).Decode(&page)
+	if len(page.Entries) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %+v", page.Entries)
+	}
+
+	withdrawal := page.Entries[0]
+	if withdrawal.Action != ActionWithdraw || withdrawal.ClientID != john.ID || withdrawal.Actor != "branch" ||
+		withdrawal.SourceIP != "192.0.2.10" || withdrawal.RequestID != "req-POST" {
+		t.Errorf("Unexpected withdrawal entry %+v", withdrawal)
+	}
+	if *withdrawal.BalanceBefore != models.NewMoney(10000) || *withdrawal.BalanceAfter != models.NewMoney(7000) {
+		t.Errorf("Expected balance 100.00 -> 70.00, got %s -> %s", withdrawal.BalanceBefore, withdrawal.BalanceAfter)
+	}
+
+	from, to := page.Entries[1], page.Entries[2]
+	if from.ClientID != john.ID || *from.BalanceBefore != models.NewMoney(7000) || *from.BalanceAfter != models.NewMoney(5000) {
+		t.Errorf("Unexpected transfer source entry %+v", from)
+	}
+	if to.ClientID != jane.ID || *to.BalanceBefore != models.NewMoney(0) || *to.BalanceAfter != models.NewMoney(2000) {
+		t.Errorf("Unexpected transfer destination entry %+v", to)
+	}
+
+	w = send("GET", "/api/audit?client_id="+jane.ID, "")
+	json.NewDecoder(w.Body).Decode(&page)
+	if len(page.Entries) != 1 || page.Entries[0].Action != ActionTransfer {
+		t.Errorf("Expected only the transfer for jane, got %+v", page.Entries)
+	}
+
+	w = send("GET", "/api/audit/verify", "")
+	var verification database.AuditVerification
+	json.NewDecoder(w.Body).Decode(&verification)
+	if !verification.Valid || verification.Entries != 3 {
+		t.Errorf("Expected a valid chain of 3 entries, got %+v", verification)
+	}
+
+	if w := send("GET", "/api/audit?order=sideways", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for invalid order, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// unavailableAuditLog recusa novos registros, como um log fora do ar
+type unavailableAuditLog struct {
+	*database.MemoryAuditLog
+}
+
+func (unavailableAuditLog) Append(entry database.AuditEntry) (*database.AuditEntry, error) {
+	return nil, database.ErrUnavailable
+}
+
+// unavailableAuditDB expõe um log de auditoria fora do ar aos handlers, mas
+// grava os registros das operações sobre contas no próprio log
+type unavailableAuditDB struct {
+	*database.MemoryDB
+}
+
+func (unavailableAuditDB) Audit() database.AuditLog {
+	return unavailableAuditLog{database.NewMemoryAuditLog()}
+}
+
+func TestAuditBestEffort(t *testing.T) {
+	db := database.NewMemoryDB()
+	handler := NewHandler(unavailableAuditDB{db})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/users", handler.CreateUser).Methods("POST")
+	router.HandleFunc("/api/clients/{id}/withdraw", handler.Withdraw).Methods("POST")
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// O registro das operações fora das contas é feito no melhor esforço: a
+	// falha não impede a resposta de uma operação já concluída
+	if w := send("/api/users", `{"first_name": "John", "last_name": "Doe", "biography": "A biography long enough to be valid"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// As operações sobre contas são registradas na mesma transação
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(10000))
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if w := send("/api/clients/"+client.ID+"/withdraw", `{"amount": 30}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	page, err := db.Audit().List(database.AuditFilter{})
+	if err != nil {
+		t.Fatalf("Failed to list audit entries: %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Action != ActionWithdraw || page.Entries[0].Actor != "anonymous" {
+		t.Errorf("Expected the withdrawal to be audited, got %+v", page.Entries)
+	}
+}
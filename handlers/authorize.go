@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+)
+
+// Ações verificadas pela política de acesso, uma por operação da API
+const (
+	ActionCreatePersonalClient  = "clients:create_personal"
+	ActionCreateCorporateClient = "clients:create_corporate"
+	ActionListClients           = "clients:list"
+	ActionReadClient            = "clients:read"
+	ActionUpdateClient          = "clients:update"
+	ActionCloseClient           = "clients:close"
+	ActionWithdraw              = "accounts:withdraw"
+	ActionDeposit               = "accounts:deposit"
+	ActionTransfer              = "accounts:transfer"
+	ActionReadStatement         = "accounts:statement"
+	ActionReverse               = "accounts:reverse"
+	ActionReadHolders           = "holders:read"
+	ActionManageHolders         = "holders:manage"
+	ActionReadUsers             = "users:read"
+	ActionManageUsers           = "users:manage"
+	ActionReadUserAccounts      = "users:accounts"
+	ActionReadAudit             = "audit:read"
+	ActionReadLimits            = "limits:read"
+	ActionManageLimits          = "limits:manage"
+	ActionManageCredit          = "credit:manage"
+	ActionRunAccruals           = "accruals:run"
+	ActionReadSchedules         = "schedules:read"
+	ActionManageSchedules       = "schedules:manage"
+)
+
+// Actions lista todas as ações, usadas para validar o arquivo de política
+var Actions = []string{
+	ActionCreatePersonalClient,
+	ActionCreateCorporateClient,
+	ActionListClients,
+	ActionReadClient,
+	ActionUpdateClient,
+	ActionCloseClient,
+	ActionWithdraw,
+	ActionDeposit,
+	ActionTransfer,
+	ActionReadStatement,
+	ActionReverse,
+	ActionReadHolders,
+	ActionManageHolders,
+	ActionReadUsers,
+	ActionManageUsers,
+	ActionReadUserAccounts,
+	ActionReadAudit,
+	ActionReadLimits,
+	ActionManageLimits,
+	ActionManageCredit,
+	ActionRunAccruals,
+	ActionReadSchedules,
+	ActionManageSchedules,
+}
+
+// WithPolicy faz cada operação consultar a política de acesso; sem ela todas
+// as operações são permitidas
+func WithPolicy(policy *auth.Policy) Option {
+	return func(h *Handler) {
+		h.policy = policy
+	}
+}
+
+// authorize consulta a política para a ação sobre o recurso e responde 403
+// quando ela é negada. Retorna false se a resposta já foi escrita.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, action string, resource auth.Resource) bool {
+	if h.policy == nil {
+		return true
+	}
+
+	err := h.policy.Authorize(auth.FromContext(r.Context()), action, resource, h.holderRole)
+	if err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// holderRole retorna o papel do usuário na conta. Uma conta inexistente é
+// tratada como uma conta sem acesso, para não revelar quais IDs existem.
+func (h *Handler) holderRole(clientID, userID string) (string, error) {
+	holders, err := h.holders.ListHolders(clientID)
+	if errors.Is(err, database.ErrClientNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, holder := range holders {
+		if holder.UserID == userID {
+			return holder.Role, nil
+		}
+	}
+	return "", nil
+}
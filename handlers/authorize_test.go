@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func TestAuthorization(t *testing.T) {
+	// Usa a política distribuída com a aplicação
+	policy, err := auth.LoadPolicy("../policy.json", Actions)
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	db, users := database.NewMemoryDB(), database.NewDatabase()
+	holders := database.NewMemoryHolderStore(db, users)
+	handler := NewHandler(db, WithUserStore(users), WithHolderStore(holders), WithPolicy(policy))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/clients/corporate", handler.CreateCorporateClient).Methods("POST")
+	router.HandleFunc("/api/clients/{id}/withdraw", handler.Withdraw).Methods("POST")
+	router.HandleFunc("/api/clients/{id}/statement", handler.GetStatement).Methods("GET")
+
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(10000))
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	user, _ := models.NewUser("John", "Doe", "A biography with more than twenty characters")
+	user, _ = users.Insert(user)
+	if _, err := holders.GrantAccess(client.ID, user.ID, models.RoleViewer); err != nil {
+		t.Fatalf("Failed to grant access: %v", err)
+	}
+
+	teller := &auth.Principal{Subject: "branch", Method: auth.MethodAPIKey, Roles: []string{"teller"}}
+	auditor := &auth.Principal{Subject: "audit", Method: auth.MethodAPIKey, Roles: []string{"auditor"}}
+	admin := &auth.Principal{Subject: "ops", Method: auth.MethodAPIKey, Roles: []string{"admin"}}
+	viewer := &auth.Principal{Subject: user.ID, Method: auth.MethodJWT}
+
+	withdraw := "/api/clients/" + client.ID + "/withdraw"
+	statement := "/api/clients/" + client.ID + "/statement"
+	corporate := `{"name": "ACME", "cnpj": "11.222.333/0001-81", "initial_balance": 0}`
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		method    string
+		path      string
+		body      string
+		status    int
+	}{
+		{"teller withdraws", teller, "POST", withdraw, `{"amount": 10}`, http.StatusOK},
+		{"auditor cannot withdraw", auditor, "POST", withdraw, `{"amount": 10}`, http.StatusForbidden},
+		{"auditor reads statement", auditor, "GET", statement, "", http.StatusOK},
+		{"teller cannot create corporate client", teller, "POST", "/api/clients/corporate", corporate, http.StatusForbidden},
+		{"admin creates corporate client", admin, "POST", "/api/clients/corporate", corporate, http.StatusCreated},
+		{"viewer reads own statement", viewer, "GET", statement, "", http.StatusOK},
+		{"viewer cannot withdraw", viewer, "POST", withdraw, `{"amount": 10}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req = req.WithContext(auth.NewContext(req.Context(), tt.principal))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status != http.StatusForbidden {
+				return
+			}
+
+			problem := decodeProblem(t, w)
+			if problem.Code != CodeForbidden || problem.Reason == nil {
+				t.Fatalf("Expected %s with a reason, got %+v", CodeForbidden, problem)
+			}
+			if problem.Reason.Subject != tt.principal.Subject || problem.Reason.Action == "" {
+				t.Errorf("Unexpected reason %+v", problem.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+// CreditLimitRequest é o corpo de PUT /api/clients/{id}/credit-limit
+type CreditLimitRequest struct {
+	CreditLimit *models.Money `json:"credit_limit"`
+}
+
+// UpdateCreditLimit altera o limite de crédito (cheque especial) do cliente,
+// até o qual o saldo pode ficar negativo
+func (h *Handler) UpdateCreditLimit(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionManageCredit, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	var req CreditLimitRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.CreditLimit == nil {
+		writeError(w, r, invalidRequest("credit_limit é obrigatório"))
+		return
+	}
+
+	client, err := h.db.UpdateClientTx(id, auditEntry(r, ActionManageCredit), func(client models.Client) error {
+		return client.SetCreditLimit(*req.CreditLimit)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, client)
+}
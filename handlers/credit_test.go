@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func TestCreditLimit(t *testing.T) {
+	db := database.NewMemoryDB()
+	handler := NewHandler(db)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/clients/{id}", handler.GetClient).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/credit-limit", handler.UpdateCreditLimit).Methods("PUT")
+	router.HandleFunc("/api/clients/{id}/withdraw", handler.Withdraw).Methods("POST")
+
+	client, err := models.NewCorporateClient("ACME Corp", "11.222.333/0001-81", models.MustParseMoney("100.00"))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	if err := db.CreateCorporateClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	clientPath := "/api/clients/" + client.ID
+
+	w := serve(router, "PUT", clientPath+"/credit-limit", `{}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusBadRequest || problem.Code != CodeInvalidRequest {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeInvalidRequest, w.Code, problem.Code)
+	}
+
+	w = serve(router, "PUT", clientPath+"/credit-limit", `{"credit_limit": -10}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusBadRequest || problem.Code != CodeValidationFailed {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeValidationFailed, w.Code, problem.Code)
+	}
+
+	if w := serve(router, "PUT", clientPath+"/credit-limit", `{"credit_limit": 500}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := serve(router, "POST", clientPath+"/withdraw", `{"amount": 300}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = serve(router, "GET", clientPath, "")
+	var body struct {
+		Balance          models.Money `json:"balance"`
+		AvailableBalance models.Money `json:"available_balance"`
+		CreditLimit      models.Money `json:"credit_limit"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if body.Balance != models.MustParseMoney("-200.00") || body.AvailableBalance != models.MustParseMoney("300.00") || body.CreditLimit != models.MustParseMoney("500.00") {
+		t.Errorf("Expected balance -200.00, 300.00 available and 500.00 of credit, got %+v", body)
+	}
+}
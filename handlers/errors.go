@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/accrual"
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/Luis-Andrei/api-users/models/validation"
+)
+
+// Códigos de erro estáveis retornados no campo "code"
+const (
+	CodeInvalidRequest      = "invalid_request"
+	CodeValidationFailed    = "validation_failed"
+	CodeInvalidAmount       = "invalid_amount"
+	CodeInsufficientFunds   = "insufficient_funds"
+	CodeWithdrawLimit       = "withdraw_limit_exceeded"
+	CodeSameClient          = "same_client"
+	CodeInvalidDocument     = "invalid_document"
+	CodeInvalidName         = "invalid_name"
+	CodeInvalidEmail        = "invalid_email"
+	CodeInvalidPhone        = "invalid_phone"
+	CodeInvalidRole         = "invalid_role"
+	CodeUnauthenticated     = "unauthenticated"
+	CodeInvalidCredentials  = "invalid_credentials"
+	CodeTokenExpired        = "token_expired"
+	CodeForbidden           = "forbidden"
+	CodeAccountClosed       = "account_closed"
+	CodeNonZeroBalance      = "non_zero_balance"
+	CodeClientNotFound      = "client_not_found"
+	CodeUserNotFound        = "user_not_found"
+	CodeHolderNotFound      = "holder_not_found"
+	CodeTransactionNotFound = "transaction_not_found"
+	CodeAlreadyReversed     = "already_reversed"
+	CodeNotReversible       = "not_reversible"
+	CodeScheduleNotFound    = "schedule_not_found"
+	CodeScheduleRunning     = "schedule_running"
+	CodeScheduleFinished    = "schedule_finished"
+	CodeDuplicateDocument   = "duplicate_document"
+	CodeLastOwner           = "last_owner"
+	CodeConflict            = "conflict"
+	CodeIdempotencyReused   = "idempotency_key_reused"
+	CodeIdempotencyPending  = "idempotency_in_progress"
+	CodeOpenBusinessDay     = "business_day_open"
+	CodeUnavailable         = "service_unavailable"
+	CodeInternal            = "internal_error"
+)
+
+// APIError é o corpo das respostas de erro, no formato
+// application/problem+json (RFC 7807) acrescido de code, details e request_id
+type APIError struct {
+	Type      string                     `json:"type"`
+	Title     string                     `json:"title"`
+	Status    int                        `json:"status"`
+	Code      string                     `json:"code"`
+	Detail    string                     `json:"detail"`
+	Details   []FieldError               `json:"details,omitempty"`
+	Reason    *auth.Denial               `json:"reason,omitempty"`
+	Limit     *models.LimitExceededError `json:"limit,omitempty"`
+	RequestID string                     `json:"request_id,omitempty"`
+}
+
+// FieldError descreve um problema em um campo específico da requisição
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// knownErrors associa os erros de domínio e de banco ao status e código da
+// API. A ordem importa: erros específicos vêm antes dos genéricos que eles
+// também satisfazem, como ErrDuplicateDocument e ErrConflict.
+var knownErrors = []struct {
+	target error
+	status int
+	code   string
+}{
+	{models.ErrInvalidAmount, http.StatusBadRequest, CodeInvalidAmount},
+	{models.ErrInvalidMoney, http.StatusBadRequest, CodeInvalidAmount},
+	{models.ErrInsufficientFunds, http.StatusBadRequest, CodeInsufficientFunds},
+	{models.ErrWithdrawLimit, http.StatusBadRequest, CodeWithdrawLimit},
+	{models.ErrSameClient, http.StatusBadRequest, CodeSameClient},
+	{validation.ErrInvalidCPF, http.StatusBadRequest, CodeInvalidDocument},
+	{validation.ErrInvalidCNPJ, http.StatusBadRequest, CodeInvalidDocument},
+	{models.ErrInvalidName, http.StatusBadRequest, CodeInvalidName},
+	{validation.ErrInvalidEmail, http.StatusBadRequest, CodeInvalidEmail},
+	{validation.ErrInvalidPhone, http.StatusBadRequest, CodeInvalidPhone},
+	{models.ErrInvalidRole, http.StatusBadRequest, CodeInvalidRole},
+	{auth.ErrMissingCredentials, http.StatusUnauthorized, CodeUnauthenticated},
+	{auth.ErrInvalidCredentials, http.StatusUnauthorized, CodeInvalidCredentials},
+	{auth.ErrTokenExpired, http.StatusUnauthorized, CodeTokenExpired},
+	{models.ErrAccountClosed, http.StatusConflict, CodeAccountClosed},
+	{models.ErrNonZeroBalance, http.StatusConflict, CodeNonZeroBalance},
+	{models.ErrAlreadyReversed, http.StatusConflict, CodeAlreadyReversed},
+	{models.ErrNotReversible, http.StatusConflict, CodeNotReversible},
+	{models.ErrTransactionNotFound, http.StatusNotFound, CodeTransactionNotFound},
+	{database.ErrClientNotFound, http.StatusNotFound, CodeClientNotFound},
+	{database.ErrUserNotFound, http.StatusNotFound, CodeUserNotFound},
+	{database.ErrHolderNotFound, http.StatusNotFound, CodeHolderNotFound},
+	{database.ErrScheduleNotFound, http.StatusNotFound, CodeScheduleNotFound},
+	{models.ErrScheduleRunning, http.StatusConflict, CodeScheduleRunning},
+	{models.ErrScheduleFinished, http.StatusConflict, CodeScheduleFinished},
+	{database.ErrDuplicateDocument, http.StatusConflict, CodeDuplicateDocument},
+	{database.ErrLastOwner, http.StatusConflict, CodeLastOwner},
+	{database.ErrConflict, http.StatusConflict, CodeConflict},
+	{errIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyReused},
+	{errIdempotencyInProgress, http.StatusConflict, CodeIdempotencyPending},
+	{accrual.ErrOpenDay, http.StatusBadRequest, CodeOpenBusinessDay},
+	{database.ErrUnavailable, http.StatusServiceUnavailable, CodeUnavailable},
+}
+
+// requestError indica uma requisição malformada (400 invalid_request)
+type requestError struct {
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func invalidRequest(message string) error {
+	return &requestError{message: message}
+}
+
+// problemFor converte um erro no corpo de erro da API. Erros desconhecidos
+// viram 500 sem expor a mensagem original.
+func problemFor(err error) APIError {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return newProblem(http.StatusBadRequest, CodeInvalidRequest, reqErr.message)
+	}
+
+	var forbidden *auth.ForbiddenError
+	if errors.As(err, &forbidden) {
+		problem := newProblem(http.StatusForbidden, CodeForbidden, forbidden.Error())
+		problem.Reason = &forbidden.Denial
+		return problem
+	}
+
+	var limitErr *models.LimitExceededError
+	if errors.As(err, &limitErr) {
+		problem := newProblem(http.StatusBadRequest, CodeWithdrawLimit, limitErr.Error())
+		problem.Limit = limitErr
+		return problem
+	}
+
+	var verr *models.ValidationError
+	if errors.As(err, &verr) {
+		problem := newProblem(http.StatusBadRequest, CodeValidationFailed, "um ou mais campos são inválidos")
+		for _, f := range verr.Fields {
+			problem.Details = append(problem.Details, FieldError{Field: f.Field, Message: f.Message})
+		}
+		return problem
+	}
+
+	for _, known := range knownErrors {
+		if errors.Is(err, known.target) {
+			return newProblem(known.status, known.code, known.target.Error())
+		}
+	}
+
+	return newProblem(http.StatusInternalServerError, CodeInternal, "erro interno do servidor")
+}
+
+func newProblem(status int, code, detail string) APIError {
+	return APIError{
+		Type:   "/errors/" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// writeError responde com o APIError correspondente a err
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := problemFor(err)
+	writeProblem(w, r, problem)
+	logError(r, problem, err)
+}
+
+// logError registra os erros 5xx, cujos detalhes não vão na resposta
+func logError(r *http.Request, problem APIError, err error) {
+	if problem.Status >= http.StatusInternalServerError {
+		log.Printf("request %s: %s %s: %v", RequestIDFromContext(r.Context()), r.Method, r.URL.Path, err)
+	}
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, problem APIError) {
+	problem.RequestID = RequestIDFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// writeJSON responde com v serializado em JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
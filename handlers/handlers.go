@@ -2,147 +2,497 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Luis-Andrei/api-users/accrual"
+	"github.com/Luis-Andrei/api-users/auth"
 	"github.com/Luis-Andrei/api-users/database"
 	"github.com/Luis-Andrei/api-users/models"
+	"github.com/Luis-Andrei/api-users/models/validation"
+	"github.com/Luis-Andrei/api-users/scheduler"
 	"github.com/gorilla/mux"
 )
 
 type Handler struct {
-	db database.Database
+	db          database.Database
+	users       database.UserStore
+	holders     database.HolderStore
+	policy      *auth.Policy
+	idempotency database.IdempotencyStore
+	audit       database.AuditLog
+	accrual     *accrual.Engine
+	scheduler   *scheduler.Scheduler
 }
 
-func NewHandler(db database.Database) *Handler {
-	return &Handler{db: db}
+// Option configura dependências opcionais do Handler
+type Option func(*Handler)
+
+// WithUserStore define o repositório usado pelas rotas /api/users; por padrão
+// os usuários ficam em memória
+func WithUserStore(users database.UserStore) Option {
+	return func(h *Handler) {
+		h.users = users
+	}
+}
+
+// WithHolderStore define o repositório de titulares das contas; por padrão
+// os acessos ficam em memória, ligados a db e ao repositório de usuários
+func WithHolderStore(holders database.HolderStore) Option {
+	return func(h *Handler) {
+		h.holders = holders
+	}
+}
+
+func NewHandler(db database.Database, opts ...Option) *Handler {
+	h := &Handler{
+		db:          db,
+		users:       database.NewDatabase(),
+		idempotency: database.NewMemoryIdempotencyStore(),
+		audit:       db.Audit(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.holders == nil {
+		h.holders = database.NewMemoryHolderStore(h.db, h.users)
+	}
+	if h.accrual == nil {
+		h.accrual = accrual.New(h.db, accrual.Config{})
+	}
+	if h.scheduler == nil {
+		h.scheduler = scheduler.New(h.db, database.NewMemoryScheduleStore(), scheduler.Config{})
+	}
+	return h
 }
 
 type CreatePersonalClientRequest struct {
-	Name           string  `json:"name"`
-	CPF            string  `json:"cpf"`
-	InitialBalance float64 `json:"initial_balance"`
+	Name           string       `json:"name"`
+	CPF            string       `json:"cpf"`
+	Email          string       `json:"email"`
+	Phone          string       `json:"phone"`
+	InitialBalance models.Money `json:"initial_balance"`
 }
 
 type CreateCorporateClientRequest struct {
-	Name           string  `json:"name"`
-	CNPJ           string  `json:"cnpj"`
-	InitialBalance float64 `json:"initial_balance"`
+	Name           string       `json:"name"`
+	CNPJ           string       `json:"cnpj"`
+	Email          string       `json:"email"`
+	Phone          string       `json:"phone"`
+	InitialBalance models.Money `json:"initial_balance"`
+}
+
+// UpdateClientRequest é o corpo de PUT e PATCH /api/clients/{id}. No PUT o
+// nome é obrigatório e e-mail ou telefone omitidos são removidos; no PATCH
+// apenas os campos presentes são alterados.
+type UpdateClientRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+	Phone *string `json:"phone"`
 }
 
 type WithdrawRequest struct {
-	Amount float64 `json:"amount"`
+	Amount models.Money `json:"amount"`
+}
+
+type TransferRequest struct {
+	FromClientID string       `json:"from_client_id"`
+	ToClientID   string       `json:"to_client_id"`
+	Amount       models.Money `json:"amount"`
+}
+
+type DepositRequest struct {
+	Amount      models.Money `json:"amount"`
+	Description string       `json:"description"`
+}
+
+// decodeRequest decodifica o corpo JSON da requisição, respondendo 400 em caso de erro
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if !errors.Is(err, models.ErrInvalidMoney) {
+			err = invalidRequest("corpo da requisição inválido")
+		}
+		writeError(w, r, err)
+		return false
+	}
+	return true
 }
 
 func (h *Handler) CreatePersonalClient(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, ActionCreatePersonalClient, auth.Resource{}) {
+		return
+	}
+
 	var req CreatePersonalClientRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	client := models.NewPersonalClient(req.Name, req.CPF, req.InitialBalance)
-	if err := h.db.CreatePersonalClient(client); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	client, err := models.NewPersonalClient(req.Name, req.CPF, req.InitialBalance)
+	if err == nil {
+		err = client.UpdateProfile(models.ProfileUpdate{Email: &req.Email, Phone: &req.Phone})
+	}
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(client)
+	if err := h.db.CreatePersonalClient(client, auditEntry(r, ActionCreatePersonalClient)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, client)
 }
 
 func (h *Handler) CreateCorporateClient(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, ActionCreateCorporateClient, auth.Resource{}) {
+		return
+	}
+
 	var req CreateCorporateClientRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	client, err := models.NewCorporateClient(req.Name, req.CNPJ, req.InitialBalance)
+	if err == nil {
+		err = client.UpdateProfile(models.ProfileUpdate{Email: &req.Email, Phone: &req.Phone})
+	}
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	client := models.NewCorporateClient(req.Name, req.CNPJ, req.InitialBalance)
-	if err := h.db.CreateCorporateClient(client); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.db.CreateCorporateClient(client, auditEntry(r, ActionCreateCorporateClient)); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(client)
+	writeJSON(w, http.StatusCreated, client)
 }
 
 func (h *Handler) GetClient(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	if !h.authorize(w, r, ActionReadClient, auth.Resource{ClientID: id}) {
+		return
+	}
+
 	client, err := h.db.GetClient(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(client)
+	writeJSON(w, http.StatusOK, client)
 }
 
+// UpdateClient altera os dados cadastrais do cliente (PUT substitui, PATCH
+// altera apenas os campos enviados)
+func (h *Handler) UpdateClient(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionUpdateClient, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	var req UpdateClientRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	update := models.ProfileUpdate{Name: req.Name, Email: req.Email, Phone: req.Phone}
+	if r.Method == http.MethodPut {
+		if req.Name == nil {
+			writeError(w, r, invalidRequest("name é obrigatório"))
+			return
+		}
+		empty := ""
+		if update.Email == nil {
+			update.Email = &empty
+		}
+		if update.Phone == nil {
+			update.Phone = &empty
+		}
+	}
+
+	client, err := h.db.UpdateClientTx(id, auditEntry(r, ActionUpdateClient), func(client models.Client) error {
+		return client.UpdateProfile(update)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, client)
+}
+
+// DeleteClient encerra a conta do cliente, que precisa estar com saldo zero
+func (h *Handler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionCloseClient, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	if err := h.db.DeleteClient(id, auditEntry(r, ActionCloseClient)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListClients aceita os parâmetros de consulta type, name, document,
+// min_balance, max_balance, include_closed, sort (name, balance ou id; prefixo "-" para
+// ordem decrescente), limit e cursor
 func (h *Handler) ListClients(w http.ResponseWriter, r *http.Request) {
-	clients, err := h.db.ListClients()
+	if !h.authorize(w, r, ActionListClients, auth.Resource{}) {
+		return
+	}
+
+	filter, err := parseClientFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(clients)
+	page, err := h.db.ListClients(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func parseClientFilter(r *http.Request) (database.ClientFilter, error) {
+	query := r.URL.Query()
+	filter := database.ClientFilter{
+		ClientType: query.Get("type"),
+		NamePrefix: query.Get("name"),
+		SortBy:     strings.TrimPrefix(query.Get("sort"), "-"),
+		Descending: strings.HasPrefix(query.Get("sort"), "-"),
+		Cursor:     query.Get("cursor"),
+	}
+
+	if v := query.Get("document"); v != "" {
+		document, err := validation.NormalizeCPF(v)
+		if err != nil {
+			document, err = validation.NormalizeCNPJ(v)
+		}
+		if err != nil {
+			return filter, invalidRequest("document deve ser um CPF ou CNPJ válido")
+		}
+		filter.Document = document
+	}
+	if v := query.Get("min_balance"); v != "" {
+		minBalance, err := models.ParseMoney(v)
+		if err != nil {
+			return filter, invalidRequest("min_balance inválido")
+		}
+		filter.MinBalance = &minBalance
+	}
+	if v := query.Get("max_balance"); v != "" {
+		maxBalance, err := models.ParseMoney(v)
+		if err != nil {
+			return filter, invalidRequest("max_balance inválido")
+		}
+		filter.MaxBalance = &maxBalance
+	}
+	if v := query.Get("include_closed"); v != "" {
+		includeClosed, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, invalidRequest("include_closed inválido")
+		}
+		filter.IncludeClosed = includeClosed
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, invalidRequest("limit inválido")
+		}
+		filter.Limit = limit
+	}
+
+	if err := filter.Validate(); err != nil {
+		return filter, invalidRequest(err.Error())
+	}
+	return filter, nil
 }
 
 func (h *Handler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	if !h.authorize(w, r, ActionWithdraw, auth.Resource{ClientID: id}) {
+		return
+	}
+
 	var req WithdrawRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	client, err := h.db.GetClient(id)
+	client, err := h.db.UpdateClientTx(id, auditEntry(r, ActionWithdraw), func(client models.Client) error {
+		return client.Withdraw(req.Amount)
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	if err := client.Withdraw(req.Amount); err != nil {
-		switch err {
-		case models.ErrInvalidAmount:
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case models.ErrInsufficientFunds:
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		case models.ErrWithdrawLimit:
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	writeJSON(w, http.StatusOK, client)
+}
+
+func (h *Handler) Deposit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if !h.authorize(w, r, ActionDeposit, auth.Resource{ClientID: id}) {
 		return
 	}
 
-	if err := h.db.UpdateClient(client); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	var req DepositRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	client, err := h.db.UpdateClientTx(id, auditEntry(r, ActionDeposit), func(client models.Client) error {
+		return client.Deposit(req.Amount, req.Description)
+	})
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(client)
+	writeJSON(w, http.StatusOK, client)
 }
 
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	// A transferência é autorizada pela conta de origem, que é debitada
+	if !h.authorize(w, r, ActionTransfer, auth.Resource{ClientID: req.FromClientID}) {
+		return
+	}
+
+	transfer, err := h.db.Transfer(req.FromClientID, req.ToClientID, req.Amount, auditEntry(r, ActionTransfer))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, transfer)
+}
+
+// GetStatement aceita os parâmetros de consulta from e to (RFC 3339 ou
+// AAAA-MM-DD), type (repetido ou separado por vírgulas), min_amount,
+// max_amount, order (asc ou desc), limit e cursor
 func (h *Handler) GetStatement(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	client, err := h.db.GetClient(id)
+	if !h.authorize(w, r, ActionReadStatement, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	filter, err := parseStatementFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(client.GetStatement())
+	statement, err := h.db.GetStatement(id, filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statement)
+}
+
+func parseStatementFilter(r *http.Request) (database.StatementFilter, error) {
+	query := r.URL.Query()
+	filter := database.StatementFilter{
+		Cursor: query.Get("cursor"),
+	}
+
+	if v := query.Get("from"); v != "" {
+		from, err := parseTime(v, false)
+		if err != nil {
+			return filter, invalidRequest("from inválido")
+		}
+		filter.From = &from
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := parseTime(v, true)
+		if err != nil {
+			return filter, invalidRequest("to inválido")
+		}
+		filter.To = &to
+	}
+	for _, v := range query["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+	if v := query.Get("min_amount"); v != "" {
+		minAmount, err := models.ParseMoney(v)
+		if err != nil {
+			return filter, invalidRequest("min_amount inválido")
+		}
+		filter.MinAmount = &minAmount
+	}
+	if v := query.Get("max_amount"); v != "" {
+		maxAmount, err := models.ParseMoney(v)
+		if err != nil {
+			return filter, invalidRequest("max_amount inválido")
+		}
+		filter.MaxAmount = &maxAmount
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, invalidRequest("order deve ser asc ou desc")
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, invalidRequest("limit inválido")
+		}
+		filter.Limit = limit
+	}
+
+	if err := filter.Validate(); err != nil {
+		return filter, invalidRequest(err.Error())
+	}
+	return filter, nil
+}
+
+// parseTime aceita RFC 3339 ou uma data AAAA-MM-DD; com endOfDay a data
+// representa o último instante do dia
+func parseTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	day, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return day, nil
 }
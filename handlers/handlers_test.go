@@ -3,15 +3,39 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/Luis-Andrei/api-users/auth"
 	"github.com/Luis-Andrei/api-users/database"
 	"github.com/Luis-Andrei/api-users/models"
 	"github.com/gorilla/mux"
 )
 
+func newPersonalClient(t *testing.T, name, cpf string, balance models.Money) *models.PersonalClient {
+	t.Helper()
+	client, err := models.NewPersonalClient(name, cpf, balance)
+	if err != nil {
+		t.Fatalf("Failed to build personal client: %v", err)
+	}
+	return client
+}
+
+func newCorporateClient(t *testing.T, name, cnpj string, balance models.Money) *models.CorporateClient {
+	t.Helper()
+	client, err := models.NewCorporateClient(name, cnpj, balance)
+	if err != nil {
+		t.Fatalf("Failed to build corporate client: %v", err)
+	}
+	return client
+}
+
 func setupTestHandler(t *testing.T) *Handler {
 	db := &database.MockDB{
 		OnCreatePersonalClient: func(client *models.PersonalClient) error {
@@ -22,15 +46,17 @@ func setupTestHandler(t *testing.T) *Handler {
 		},
 		OnGetClient: func(id string) (models.Client, error) {
 			if id == "123" {
-				return models.NewPersonalClient("John Doe", "123.456.789-00", 2000.0), nil
+				return newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("2000.00")), nil
 			}
-			return nil, nil
+			return nil, database.ErrClientNotFound
 		},
-		OnUpdateClient: func(client models.Client) error {
-			return nil
+		OnTransfer: func(fromID, toID string, amount models.Money) (*models.Transfer, error) {
+			from := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", models.MustParseMoney("10000.00"))
+			to := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(0))
+			return models.ExecuteTransfer(from, to, amount)
 		},
-		OnListClients: func() ([]models.Client, error) {
-			return nil, nil
+		OnListClients: func(filter database.ClientFilter) (*database.ClientPage, error) {
+			return &database.ClientPage{Clients: []models.Client{}}, nil
 		},
 	}
 
@@ -42,8 +68,8 @@ func TestCreatePersonalClient(t *testing.T) {
 
 	reqBody := CreatePersonalClientRequest{
 		Name:           "John Doe",
-		CPF:            "123.456.789-00",
-		InitialBalance: 2000.0,
+		CPF:            "529.982.247-25",
+		InitialBalance: models.MustParseMoney("2000.00"),
 	}
 	body, _ := json.Marshal(reqBody)
 
@@ -62,8 +88,8 @@ func TestCreateCorporateClient(t *testing.T) {
 
 	reqBody := CreateCorporateClientRequest{
 		Name:           "ACME Corp",
-		CNPJ:           "12.345.678/0001-00",
-		InitialBalance: 10000.0,
+		CNPJ:           "11.222.333/0001-81",
+		InitialBalance: models.MustParseMoney("10000.00"),
 	}
 	body, _ := json.Marshal(reqBody)
 
@@ -108,7 +134,7 @@ func TestWithdraw(t *testing.T) {
 	handler := setupTestHandler(t)
 
 	reqBody := WithdrawRequest{
-		Amount: 500.0,
+		Amount: models.MustParseMoney("500.00"),
 	}
 	body, _ := json.Marshal(reqBody)
 
@@ -123,6 +149,45 @@ func TestWithdraw(t *testing.T) {
 	}
 }
 
+func TestDeposit(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	reqBody := DepositRequest{
+		Amount:      models.MustParseMoney("250.00"),
+		Description: "Depósito no caixa",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/clients/123/deposit", bytes.NewBuffer(body))
+	req = mux.SetURLVars(req, map[string]string{"id": "123"})
+	w := httptest.NewRecorder()
+
+	handler.Deposit(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestDepositInvalidAmount(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	reqBody := DepositRequest{
+		Amount: models.MustParseMoney("-10.00"),
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/clients/123/deposit", bytes.NewBuffer(body))
+	req = mux.SetURLVars(req, map[string]string{"id": "123"})
+	w := httptest.NewRecorder()
+
+	handler.Deposit(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
 func TestGetStatement(t *testing.T) {
 	handler := setupTestHandler(t)
 
@@ -136,3 +201,589 @@ func TestGetStatement(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
 	}
 }
+
+func TestTransfer(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	reqBody := TransferRequest{
+		FromClientID: "456",
+		ToClientID:   "123",
+		Amount:       models.MustParseMoney("1500.00"),
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/transfers", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.Transfer(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+}
+
+func TestTransferAboveLimit(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	reqBody := TransferRequest{
+		FromClientID: "456",
+		ToClientID:   "123",
+		Amount:       models.MustParseMoney("6000.00"),
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/transfers", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.Transfer(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestWithdrawRejectsFractionalCents(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	req := httptest.NewRequest("POST", "/api/clients/123/withdraw", bytes.NewBufferString(`{"amount": 10.005}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "123"})
+	w := httptest.NewRecorder()
+
+	handler.Withdraw(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestWithdrawClientNotFound(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	reqBody := WithdrawRequest{
+		Amount: models.MustParseMoney("10.00"),
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/clients/999/withdraw", bytes.NewBuffer(body))
+	req = mux.SetURLVars(req, map[string]string{"id": "999"})
+	w := httptest.NewRecorder()
+
+	handler.Withdraw(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestListClientsFilters(t *testing.T) {
+	var received database.ClientFilter
+	handler := NewHandler(&database.MockDB{
+		OnListClients: func(filter database.ClientFilter) (*database.ClientPage, error) {
+			received = filter
+			return &database.ClientPage{Clients: []models.Client{}, Total: 0}, nil
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/api/clients?type=corporate&name=AC&min_balance=100.50&sort=-balance&limit=10", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListClients(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if received.ClientType != "corporate" || received.NamePrefix != "AC" {
+		t.Errorf("Unexpected filter %+v", received)
+	}
+	if received.MinBalance == nil || *received.MinBalance != models.MustParseMoney("100.50") {
+		t.Errorf("Expected min_balance 100.50, got %v", received.MinBalance)
+	}
+	if received.SortBy != database.SortByBalance || !received.Descending || received.Limit != 10 {
+		t.Errorf("Unexpected sorting %+v", received)
+	}
+
+	var envelope map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&envelope)
+	if _, ok := envelope["clients"]; !ok {
+		t.Errorf("Expected clients in response envelope, got %v", envelope)
+	}
+	if _, ok := envelope["total"]; !ok {
+		t.Errorf("Expected total in response envelope, got %v", envelope)
+	}
+}
+
+func TestListClientsInvalidFilter(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	for _, query := range []string{"type=other", "sort=cpf", "limit=1000", "min_balance=1.001", "cursor=@@", "min_balance=10&max_balance=5"} {
+		req := httptest.NewRequest("GET", "/api/clients?"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.ListClients(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestGetStatementFilters(t *testing.T) {
+	var received database.StatementFilter
+	handler := NewHandler(&database.MockDB{
+		OnGetStatement: func(id string, filter database.StatementFilter) (*database.Statement, error) {
+			received = filter
+			return &database.Statement{Transactions: []models.Transaction{}}, nil
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/api/clients/123/statement?from=2024-05-01&to=2024-05-31&type=withdrawal,deposit&order=desc&limit=20", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "123"})
+	w := httptest.NewRecorder()
+
+	handler.GetStatement(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if received.From == nil || !received.From.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected from %v", received.From)
+	}
+	if received.To == nil || received.To.Day() != 31 || received.To.Hour() != 23 {
+		t.Errorf("Expected to at the end of May 31, got %v", received.To)
+	}
+	if len(received.Types) != 2 || !received.Descending || received.Limit != 20 {
+		t.Errorf("Unexpected filter %+v", received)
+	}
+}
+
+func TestGetStatementInvalidFilter(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	for _, query := range []string{"from=yesterday", "type=unknown", "order=up", "from=2024-06-01&to=2024-05-01"} {
+		req := httptest.NewRequest("GET", "/api/clients/123/statement?"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "123"})
+		w := httptest.NewRecorder()
+
+		handler.GetStatement(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestCreatePersonalClientInvalidCPF(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	reqBody := CreatePersonalClientRequest{
+		Name: "John Doe",
+		CPF:  "123.456.789-00",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/clients/personal", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.CreatePersonalClient(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestCreatePersonalClientNegativeBalance(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	req := httptest.NewRequest("POST", "/api/clients/personal",
+		bytes.NewBufferString(`{"name": "John Doe", "cpf": "529.982.247-25", "initial_balance": "-500.00"}`))
+	w := httptest.NewRecorder()
+
+	handler.CreatePersonalClient(w, req)
+
+	problem := decodeProblem(t, w)
+	if w.Code != http.StatusBadRequest || problem.Code != CodeValidationFailed {
+		t.Fatalf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeValidationFailed, w.Code, problem.Code)
+	}
+	if len(problem.Details) != 1 || problem.Details[0].Field != "initial_balance" {
+		t.Errorf("Expected an error on initial_balance, got %+v", problem.Details)
+	}
+}
+
+func TestCreateCorporateClientDuplicateCNPJ(t *testing.T) {
+	handler := NewHandler(&database.MockDB{
+		OnCreateCorporateClient: func(client *models.CorporateClient) error {
+			return database.ErrDuplicateDocument
+		},
+	})
+
+	reqBody := CreateCorporateClientRequest{
+		Name: "ACME Corp",
+		CNPJ: "11222333000181",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/clients/corporate", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateCorporateClient(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) APIError {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected Content-Type application/problem+json, got %q", ct)
+	}
+	var problem APIError
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	return problem
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"insufficient funds", "/api/clients/123/withdraw", `{"amount": 900.00}`, http.StatusBadRequest, CodeInsufficientFunds},
+		{"withdraw limit", "/api/clients/123/withdraw", `{"amount": 1500.00}`, http.StatusBadRequest, CodeWithdrawLimit},
+		{"invalid amount", "/api/clients/123/withdraw", `{"amount": -10}`, http.StatusBadRequest, CodeInvalidAmount},
+		{"fractional cents", "/api/clients/123/withdraw", `{"amount": 10.005}`, http.StatusBadRequest, CodeInvalidAmount},
+		{"malformed body", "/api/clients/123/withdraw", `{`, http.StatusBadRequest, CodeInvalidRequest},
+		{"client not found", "/api/clients/999/withdraw", `{"amount": 10}`, http.StatusNotFound, CodeClientNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestHandler(t)
+			handler.db.(*database.MockDB).OnGetClient = func(id string) (models.Client, error) {
+				if id == "123" {
+					return newPersonalClient(t, "John Doe", "529.982.247-25", models.MustParseMoney("500.00")), nil
+				}
+				return nil, database.ErrClientNotFound
+			}
+
+			router := mux.NewRouter()
+			router.Use(RequestID)
+			router.HandleFunc("/api/clients/{id}/withdraw", handler.Withdraw)
+
+			req := httptest.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, w.Code)
+			}
+			problem := decodeProblem(t, w)
+			if problem.Code != tt.code {
+				t.Errorf("Expected code %q, got %q", tt.code, problem.Code)
+			}
+			if problem.Status != tt.status {
+				t.Errorf("Expected status %d in body, got %d", tt.status, problem.Status)
+			}
+			if problem.RequestID == "" || problem.RequestID != w.Header().Get(RequestIDHeader) {
+				t.Errorf("Expected request_id to match %s header, got %q", RequestIDHeader, problem.RequestID)
+			}
+		})
+	}
+}
+
+func TestInternalErrorIsNotLeaked(t *testing.T) {
+	handler := NewHandler(&database.MockDB{
+		OnListClients: func(filter database.ClientFilter) (*database.ClientPage, error) {
+			return nil, errors.New("pq: relation \"clients\" does not exist")
+		},
+	})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	req := httptest.NewRequest("GET", "/api/clients", nil)
+	req.Header.Set(RequestIDHeader, "req-500")
+	w := httptest.NewRecorder()
+
+	RequestID(http.HandlerFunc(handler.ListClients)).ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	problem := decodeProblem(t, w)
+	if problem.Code != CodeInternal {
+		t.Errorf("Expected code %q, got %q", CodeInternal, problem.Code)
+	}
+	if bytes.Contains([]byte(problem.Detail), []byte("pq:")) {
+		t.Errorf("Expected database error to be hidden, got %q", problem.Detail)
+	}
+	// O erro registrado no log traz o mesmo request_id devolvido ao cliente
+	if !bytes.Contains(logs.Bytes(), []byte("request req-500: GET /api/clients: pq:")) {
+		t.Errorf("Expected the logged error to carry the request ID, got %q", logs.String())
+	}
+}
+
+func TestRequestIDIsPropagated(t *testing.T) {
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RequestIDFromContext(r.Context())))
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("Expected %s header abc-123, got %q", RequestIDHeader, got)
+	}
+	if w.Body.String() != "abc-123" {
+		t.Errorf("Expected request ID in context, got %q", w.Body.String())
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	authenticator, err := auth.New(auth.Config{APIKeys: []auth.APIKey{
+		{Name: "billing", Hash: auth.HashAPIKey("s3cret"), Roles: []string{"teller"}},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	handler := Authenticate(authenticator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(auth.FromContext(r.Context()).Subject))
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+		code   string
+	}{
+		{"valid key", auth.APIKeyHeader, "s3cret", http.StatusOK, ""},
+		{"missing credentials", "", "", http.StatusUnauthorized, CodeUnauthenticated},
+		{"invalid key", auth.APIKeyHeader, "wrong", http.StatusUnauthorized, CodeInvalidCredentials},
+		{"invalid token", "Authorization", "Bearer a.b.c", http.StatusUnauthorized, CodeInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/clients", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status code %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusOK {
+				if w.Body.String() != "billing" {
+					t.Errorf("Expected principal billing in context, got %q", w.Body.String())
+				}
+				return
+			}
+			if w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate header")
+			}
+			if problem := decodeProblem(t, w); problem.Code != tt.code {
+				t.Errorf("Expected code %s, got %s", tt.code, problem.Code)
+			}
+		})
+	}
+}
+
+func TestDatabaseErrorMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"not found", database.ErrClientNotFound, http.StatusNotFound, CodeClientNotFound, "cliente não encontrado"},
+		{"wrapped not found", fmt.Errorf("error getting client: %w", database.ErrClientNotFound), http.StatusNotFound, CodeClientNotFound, "cliente não encontrado"},
+		{"duplicate document", database.ErrDuplicateDocument, http.StatusConflict, CodeDuplicateDocument, "já existe um cliente com este documento"},
+		{"conflict", fmt.Errorf("error committing transaction: %w", database.ErrConflict), http.StatusConflict, CodeConflict, "conflito com os dados atuais"},
+		{"unavailable", fmt.Errorf("error getting client: %w", database.ErrUnavailable), http.StatusServiceUnavailable, CodeUnavailable, "banco de dados indisponível"},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, CodeInternal, "erro interno do servidor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &database.MockDB{
+				OnGetClient: func(id string) (models.Client, error) {
+					return nil, tt.err
+				},
+				OnUpdateClientTx: func(id string, fn func(models.Client) error) (models.Client, error) {
+					return nil, tt.err
+				},
+				OnTransfer: func(fromID, toID string, amount models.Money) (*models.Transfer, error) {
+					return nil, tt.err
+				},
+				OnGetStatement: func(id string, filter database.StatementFilter) (*database.Statement, error) {
+					return nil, tt.err
+				},
+			}
+			handler := NewHandler(db)
+
+			requests := map[string]func() *httptest.ResponseRecorder{
+				"GetClient": func() *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := mux.SetURLVars(httptest.NewRequest("GET", "/api/clients/1", nil), map[string]string{"id": "1"})
+					handler.GetClient(w, req)
+					return w
+				},
+				"Withdraw": func() *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest("POST", "/api/clients/1/withdraw", bytes.NewBufferString(`{"amount": 10}`))
+					handler.Withdraw(w, mux.SetURLVars(req, map[string]string{"id": "1"}))
+					return w
+				},
+				"Transfer": func() *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest("POST", "/api/transfers", bytes.NewBufferString(`{"from_client_id": "1", "to_client_id": "2", "amount": 10}`))
+					handler.Transfer(w, req)
+					return w
+				},
+				"GetStatement": func() *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := mux.SetURLVars(httptest.NewRequest("GET", "/api/clients/1/statement", nil), map[string]string{"id": "1"})
+					handler.GetStatement(w, req)
+					return w
+				},
+			}
+
+			for name, do := range requests {
+				w := do()
+				if w.Code != tt.status {
+					t.Errorf("%s: expected status code %d, got %d", name, tt.status, w.Code)
+				}
+				if problem := decodeProblem(t, w); problem.Code != tt.code || problem.Detail != tt.detail {
+					t.Errorf("%s: expected %q %q, got %q %q", name, tt.code, tt.detail, problem.Code, problem.Detail)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateClient(t *testing.T) {
+	stored := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(0))
+	if err := stored.UpdateProfile(models.ProfileUpdate{Email: stringPtr("john@example.com")}); err != nil {
+		t.Fatalf("Failed to set email: %v", err)
+	}
+	handler := NewHandler(&database.MockDB{
+		OnGetClient: func(id string) (models.Client, error) {
+			if id == stored.ID {
+				return stored, nil
+			}
+			return nil, database.ErrClientNotFound
+		},
+	})
+
+	send := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/clients/"+stored.ID, bytes.NewBufferString(body))
+		req = mux.SetURLVars(req, map[string]string{"id": stored.ID})
+		w := httptest.NewRecorder()
+		handler.UpdateClient(w, req)
+		return w
+	}
+
+	// PATCH altera só o telefone e preserva o e-mail
+	if w := send("PATCH", `{"phone": "(11) 98765-4321"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if stored.Phone != "+5511987654321" || stored.Email != "john@example.com" {
+		t.Errorf("Expected phone to be set and email kept, got %+v", stored.GetProfile())
+	}
+
+	// PUT exige o nome e remove os contatos omitidos
+	if w := send("PUT", `{"phone": "(11) 98765-4321"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := send("PUT", `{"name": "John Smith"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if want := (models.Profile{Name: "John Smith"}); stored.GetProfile() != want {
+		t.Errorf("Expected %+v, got %+v", want, stored.GetProfile())
+	}
+
+	w := send("PATCH", `{"email": "not-an-email"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if problem := decodeProblem(t, w); problem.Code != CodeInvalidEmail {
+		t.Errorf("Expected code %q, got %q", CodeInvalidEmail, problem.Code)
+	}
+}
+
+func TestDeleteClient(t *testing.T) {
+	handler := setupTestHandler(t)
+
+	req := mux.SetURLVars(httptest.NewRequest("DELETE", "/api/clients/123", nil), map[string]string{"id": "123"})
+	w := httptest.NewRecorder()
+	handler.DeleteClient(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+	if problem := decodeProblem(t, w); problem.Code != CodeNonZeroBalance {
+		t.Errorf("Expected code %q, got %q", CodeNonZeroBalance, problem.Code)
+	}
+
+	empty := newPersonalClient(t, "Jane Doe", "123.456.789-09", models.NewMoney(0))
+	handler = NewHandler(&database.MockDB{
+		OnGetClient: func(id string) (models.Client, error) {
+			return empty, nil
+		},
+	})
+
+	w = httptest.NewRecorder()
+	handler.DeleteClient(w, mux.SetURLVars(httptest.NewRequest("DELETE", "/api/clients/1", nil), map[string]string{"id": "1"}))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if empty.GetClosedAt() == nil {
+		t.Error("Expected account to be closed")
+	}
+
+	// Operações sobre a conta encerrada retornam 409
+	body, _ := json.Marshal(DepositRequest{Amount: models.MustParseMoney("10.00")})
+	w = httptest.NewRecorder()
+	handler.Deposit(w, mux.SetURLVars(httptest.NewRequest("POST", "/api/clients/1/deposit", bytes.NewBuffer(body)), map[string]string{"id": "1"}))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestListClientsIncludeClosed(t *testing.T) {
+	var got database.ClientFilter
+	handler := NewHandler(&database.MockDB{
+		OnListClients: func(filter database.ClientFilter) (*database.ClientPage, error) {
+			got = filter
+			return &database.ClientPage{Clients: []models.Client{}}, nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.ListClients(w, httptest.NewRequest("GET", "/api/clients?include_closed=true", nil))
+	if w.Code != http.StatusOK || !got.IncludeClosed {
+		t.Errorf("Expected include_closed to be forwarded, got status %d and %+v", w.Code, got)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ListClients(w, httptest.NewRequest("GET", "/api/clients?include_closed=talvez", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
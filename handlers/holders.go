@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/gorilla/mux"
+)
+
+// GrantAccessRequest é o corpo de PUT /api/clients/{id}/holders/{user_id}
+type GrantAccessRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *Handler) ListHolders(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionReadHolders, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	holders, err := h.holders.ListHolders(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, holders)
+}
+
+// GrantAccess concede ao usuário acesso à conta ou altera o seu papel
+func (h *Handler) GrantAccess(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.authorize(w, r, ActionManageHolders, auth.Resource{ClientID: vars["id"]}) {
+		return
+	}
+
+	var req GrantAccessRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	holder, err := h.holders.GrantAccess(vars["id"], vars["user_id"], req.Role)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.recordAudit(r, database.AuditEntry{Action: AuditGrantAccess, ClientID: holder.ClientID, UserID: holder.UserID})
+
+	writeJSON(w, http.StatusOK, holder)
+}
+
+func (h *Handler) RevokeAccess(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.authorize(w, r, ActionManageHolders, auth.Resource{ClientID: vars["id"]}) {
+		return
+	}
+
+	if err := h.holders.RevokeAccess(vars["id"], vars["user_id"]); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.recordAudit(r, database.AuditEntry{Action: AuditRevokeAccess, ClientID: vars["id"], UserID: vars["user_id"]})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListUserAccounts(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionReadUserAccounts, auth.Resource{UserID: id}) {
+		return
+	}
+
+	accounts, err := h.holders.ListUserAccounts(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, accounts)
+}
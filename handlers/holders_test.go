@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func TestAccountHolders(t *testing.T) {
+	db, users := database.NewMemoryDB(), database.NewDatabase()
+	handler := NewHandler(db, WithUserStore(users))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/clients/{id}/holders", handler.ListHolders).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/holders/{user_id}", handler.GrantAccess).Methods("PUT")
+	router.HandleFunc("/api/clients/{id}/holders/{user_id}", handler.RevokeAccess).Methods("DELETE")
+	router.HandleFunc("/api/users/{id}/accounts", handler.ListUserAccounts).Methods("GET")
+	router.HandleFunc("/api/users/{id}", handler.DeleteUser).Methods("DELETE")
+
+	client, err := models.NewPersonalClient("John Doe", "529.982.247-25", models.NewMoney(0))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	user, _ := models.NewUser("John", "Doe", "A biography with more than twenty characters")
+	user, _ = users.Insert(user)
+
+	holderPath := "/api/clients/" + client.ID + "/holders/" + user.ID
+
+	w := serve(router, "PUT", holderPath, `{"role": "admin"}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusBadRequest || problem.Code != CodeInvalidRole {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeInvalidRole, w.Code, problem.Code)
+	}
+
+	w = serve(router, "PUT", holderPath, `{"role": "owner"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var holder models.AccountHolder
+	json.NewDecoder(w.Body).Decode(&holder)
+	if holder.Role != models.RoleOwner || holder.UserID != user.ID {
+		t.Errorf("Unexpected holder %+v", holder)
+	}
+
+	w = serve(router, "GET", "/api/users/"+user.ID+"/accounts", "")
+	var accounts []struct {
+		Role   string `json:"role"`
+		Client struct {
+			ID string `json:"id"`
+		} `json:"client"`
+	}
+	json.NewDecoder(w.Body).Decode(&accounts)
+	if len(accounts) != 1 || accounts[0].Client.ID != client.ID || accounts[0].Role != models.RoleOwner {
+		t.Errorf("Expected the user to own %s, got %+v", client.ID, accounts)
+	}
+
+	w = serve(router, "DELETE", holderPath, "")
+	if problem := decodeProblem(t, w); w.Code != http.StatusConflict || problem.Code != CodeLastOwner {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusConflict, CodeLastOwner, w.Code, problem.Code)
+	}
+	w = serve(router, "DELETE", "/api/users/"+user.ID, "")
+	if problem := decodeProblem(t, w); w.Code != http.StatusConflict || problem.Code != CodeLastOwner {
+		t.Errorf("Expected %d %s when deleting the last owner, got %d %s", http.StatusConflict, CodeLastOwner, w.Code, problem.Code)
+	}
+
+	w = serve(router, "GET", "/api/clients/missing/holders", "")
+	if problem := decodeProblem(t, w); w.Code != http.StatusNotFound || problem.Code != CodeClientNotFound {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusNotFound, CodeClientNotFound, w.Code, problem.Code)
+	}
+
+	w = serve(router, "DELETE", "/api/clients/"+client.ID+"/holders/missing", "")
+	if problem := decodeProblem(t, w); w.Code != http.StatusNotFound || problem.Code != CodeHolderNotFound {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusNotFound, CodeHolderNotFound, w.Code, problem.Code)
+	}
+}
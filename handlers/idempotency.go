@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+)
+
+const (
+	// IdempotencyKeyHeader identifica uma requisição que pode ser repetida
+	// com segurança
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marca as respostas devolvidas de uma
+	// requisição anterior
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20
+)
+
+var (
+	errIdempotencyKeyReused  = errors.New("Idempotency-Key já usada em uma requisição diferente")
+	errIdempotencyInProgress = errors.New("a requisição com esta Idempotency-Key ainda está em andamento")
+)
+
+// WithIdempotencyStore define onde as chaves de idempotência são guardadas;
+// por padrão elas ficam em memória
+func WithIdempotencyStore(store database.IdempotencyStore) Option {
+	return func(h *Handler) {
+		h.idempotency = store
+	}
+}
+
+// Idempotent faz com que uma requisição repetida com o mesmo cabeçalho
+// Idempotency-Key devolva a resposta original em vez de ser executada de
+// novo. Reutilizar a chave com outro método, caminho ou corpo retorna 422, e
+// repeti-la enquanto a original está em andamento retorna 409. Requisições
+// sem o cabeçalho são executadas normalmente.
+func (h *Handler) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeError(w, r, invalidRequest("Idempotency-Key deve ter até 255 caracteres"))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+		if err != nil || len(body) > maxIdempotentBodySize {
+			writeError(w, r, invalidRequest("corpo da requisição inválido"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope, hash := idempotencyScope(r), requestHash(r, body)
+		record, started, err := h.idempotency.Begin(scope, key, hash)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if !started {
+			switch {
+			case record.RequestHash != hash:
+				writeError(w, r, errIdempotencyKeyReused)
+			case record.Response == nil:
+				writeError(w, r, errIdempotencyInProgress)
+			default:
+				replay(w, record.Response)
+			}
+			return
+		}
+
+		// Se a requisição falhar sem resposta definitiva (inclusive em um
+		// panic), a chave é liberada para que o cliente possa repeti-la
+		capture := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+		finished := false
+		defer func() {
+			if !finished {
+				if err := h.idempotency.Release(scope, key); err != nil {
+					log.Printf("request %s: error releasing idempotency key: %v", RequestIDFromContext(r.Context()), err)
+				}
+			}
+		}()
+
+		next(capture, r)
+
+		if !isFinalStatus(capture.status) {
+			return
+		}
+		finished = true
+		response := database.IdempotentResponse{
+			StatusCode:  capture.status,
+			ContentType: capture.Header().Get("Content-Type"),
+			Body:        capture.body.Bytes(),
+		}
+		// Em caso de erro a chave fica pendente até expirar: liberá-la
+		// permitiria executar de novo uma operação que já foi concluída
+		if err := h.idempotency.Complete(scope, key, response); err != nil {
+			log.Printf("request %s: error storing idempotent response: %v", RequestIDFromContext(r.Context()), err)
+		}
+	}
+}
+
+// isFinalStatus indica se a resposta deve ser devolvida nas repetições.
+// Erros 5xx e todo 409, inclusive os de regra de negócio, são tratados como
+// transitórios: a chave é liberada e a repetição executa a operação de novo.
+func isFinalStatus(status int) bool {
+	return status < http.StatusInternalServerError && status != http.StatusConflict
+}
+
+// idempotencyScope separa as chaves de cada chamador autenticado
+func idempotencyScope(r *http.Request) string {
+	principal := auth.FromContext(r.Context())
+	if principal == nil {
+		return "anonymous"
+	}
+	return principal.Method + ":" + principal.Subject
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, response *database.IdempotentResponse) {
+	if response.ContentType != "" {
+		w.Header().Set("Content-Type", response.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(response.StatusCode)
+	w.Write(response.Body)
+}
+
+// responseCapture repassa a resposta ao cliente guardando o status e o corpo
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(data []byte) (int, error) {
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
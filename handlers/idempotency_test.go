@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func TestIdempotentWithdraw(t *testing.T) {
+	db, store := database.NewMemoryDB(), database.NewMemoryIdempotencyStore()
+	handler := NewHandler(db, WithIdempotencyStore(store))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/clients/{id}/withdraw", handler.Idempotent(handler.Withdraw)).Methods("POST")
+
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", models.NewMoney(10000))
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	path := "/api/clients/" + client.ID + "/withdraw"
+
+	withdraw := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := withdraw("key-1", `{"amount": 30}`)
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, first.Code)
+	}
+
+	second := withdraw("key-1", `{"amount": 30}`)
+	if second.Code != http.StatusOK || second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("Expected replayed %d, got %d (replayed %q)", http.StatusOK, second.Code, second.Header().Get(IdempotentReplayedHeader))
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("Expected the original response, got %s", second.Body.String())
+	}
+
+	stored, _ := db.GetClient(client.ID)
+	if stored.GetBalance() != models.NewMoney(7000) {
+		t.Errorf("Expected a single withdrawal leaving 70.00, got %s", stored.GetBalance())
+	}
+
+	// A mesma chave com outro corpo é rejeitada
+	w := withdraw("key-1", `{"amount": 40}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusUnprocessableEntity || problem.Code != CodeIdempotencyReused {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusUnprocessableEntity, CodeIdempotencyReused, w.Code, problem.Code)
+	}
+
+	// Erros de negócio também são devolvidos nas repetições
+	w = withdraw("key-2", `{"amount": 500}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := withdraw("key-2", `{"amount": 500}`); w.Code != http.StatusBadRequest || w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("Expected replayed %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// Uma requisição ainda em andamento com a mesma chave retorna 409
+	if _, _, err := store.Begin("anonymous", "key-3", requestHash(httptest.NewRequest("POST", path, nil), []byte(`{"amount": 10}`))); err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	w = withdraw("key-3", `{"amount": 10}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusConflict || problem.Code != CodeIdempotencyPending {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusConflict, CodeIdempotencyPending, w.Code, problem.Code)
+	}
+}
+
+func TestIdempotentReleasesOnServerError(t *testing.T) {
+	store := database.NewMemoryIdempotencyStore()
+	handler := NewHandler(&database.MockDB{}, WithIdempotencyStore(store))
+
+	calls := 0
+	wrapped := handler.Idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			writeError(w, r, database.ErrUnavailable)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"status": "ok"})
+	})
+
+	for _, expected := range []int{http.StatusServiceUnavailable, http.StatusCreated, http.StatusCreated} {
+		req := httptest.NewRequest("POST", "/api/clients/personal", bytes.NewBufferString(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		w := httptest.NewRecorder()
+		wrapped(w, req)
+		if w.Code != expected {
+			t.Errorf("Expected status code %d, got %d", expected, w.Code)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected the request to run again only after the server error, got %d calls", calls)
+	}
+}
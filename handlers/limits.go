@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+// LimitsResponse é o corpo de GET e PUT /api/clients/{id}/limits
+type LimitsResponse struct {
+	ClientID string                `json:"client_id"`
+	Limits   models.WithdrawLimits `json:"limits"`
+	Override models.LimitOverride  `json:"override"`
+	Used     models.LimitUsage     `json:"used"`
+}
+
+func limitsResponse(client models.Client) LimitsResponse {
+	return LimitsResponse{
+		ClientID: client.GetID(),
+		Limits:   client.GetLimits(),
+		Override: client.GetLimitOverride(),
+		Used:     client.GetLimitUsage(),
+	}
+}
+
+// GetLimits retorna os limites de saque efetivos do cliente, o override
+// configurado e quanto já foi sacado no dia e no mês
+func (h *Handler) GetLimits(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionReadLimits, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	client, err := h.db.GetClient(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, limitsResponse(client))
+}
+
+// UpdateLimits substitui o override de limites do cliente; campos omitidos
+// voltam ao limite padrão do tipo de cliente
+func (h *Handler) UpdateLimits(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionManageLimits, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	var req models.LimitOverride
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	client, err := h.db.UpdateClientTx(id, auditEntry(r, ActionManageLimits), func(client models.Client) error {
+		return client.SetLimitOverride(req)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, limitsResponse(client))
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func TestWithdrawLimits(t *testing.T) {
+	db := database.NewMemoryDB()
+	handler := NewHandler(db)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/clients/{id}/limits", handler.GetLimits).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/limits", handler.UpdateLimits).Methods("PUT")
+	router.HandleFunc("/api/clients/{id}/withdraw", handler.Withdraw).Methods("POST")
+
+	client, err := models.NewCorporateClient("ACME Corp", "11.222.333/0001-81", models.MustParseMoney("100000.00"))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	if err := db.CreateCorporateClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	limitsPath := "/api/clients/" + client.ID + "/limits"
+	withdrawPath := "/api/clients/" + client.ID + "/withdraw"
+
+	w := serve(router, "PUT", limitsPath, `{"per_transaction": 0}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusBadRequest || problem.Code != CodeValidationFailed {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeValidationFailed, w.Code, problem.Code)
+	}
+
+	// O mensal informado é conferido contra o diário padrão do cliente
+	w = serve(router, "PUT", limitsPath, `{"monthly": 40000}`)
+	problem := decodeProblem(t, w)
+	if w.Code != http.StatusBadRequest || problem.Code != CodeValidationFailed {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeValidationFailed, w.Code, problem.Code)
+	}
+	if len(problem.Details) != 1 || problem.Details[0].Field != models.LimitMonthly {
+		t.Errorf("Expected an error on monthly, got %+v", problem.Details)
+	}
+
+	w = serve(router, "PUT", limitsPath, `{"per_transaction": 8000, "daily": 10000}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var limits LimitsResponse
+	json.NewDecoder(w.Body).Decode(&limits)
+	if limits.Limits.PerTransaction != models.MustParseMoney("8000.00") || limits.Limits.Monthly != models.CorporateClientLimits.Monthly {
+		t.Errorf("Unexpected limits %+v", limits.Limits)
+	}
+
+	if w := serve(router, "POST", withdrawPath, `{"amount": 8000}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = serve(router, "POST", withdrawPath, `{"amount": 2500}`)
+	problem = decodeProblem(t, w)
+	if w.Code != http.StatusBadRequest || problem.Code != CodeWithdrawLimit {
+		t.Fatalf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeWithdrawLimit, w.Code, problem.Code)
+	}
+	if problem.Limit == nil || problem.Limit.Limit != models.LimitDaily || problem.Limit.Used != models.MustParseMoney("8000.00") {
+		t.Errorf("Expected the daily limit with 8000.00 used, got %+v", problem.Limit)
+	}
+
+	w = serve(router, "GET", limitsPath, "")
+	json.NewDecoder(w.Body).Decode(&limits)
+	if limits.Used.Daily != models.MustParseMoney("8000.00") || limits.Override.Daily == nil {
+		t.Errorf("Expected 8000.00 used today with a daily override, got %+v", limits)
+	}
+
+	w = serve(router, "GET", "/api/clients/missing/limits", "")
+	if problem := decodeProblem(t, w); w.Code != http.StatusNotFound || problem.Code != CodeClientNotFound {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusNotFound, CodeClientNotFound, w.Code, problem.Code)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader é o cabeçalho usado para propagar o ID da requisição
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// RequestID reaproveita o X-Request-ID recebido ou gera um novo, devolvendo-o
+// na resposta e disponibilizando-o no contexto da requisição
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// RequestIDFromContext retorna o ID da requisição definido pelo middleware RequestID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Authenticate exige credenciais válidas em todas as rotas e disponibiliza o
+// chamador no contexto, acessível com auth.FromContext. Requisições sem
+// credenciais ou com credenciais inválidas recebem 401.
+func Authenticate(authenticator *auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				writeError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
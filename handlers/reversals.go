@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+// ReverseRequest é o corpo de POST /api/clients/{id}/transactions/{tx_id}/reverse
+type ReverseRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReverseResponse traz o estorno criado e o cliente com o saldo atualizado
+type ReverseResponse struct {
+	Reversal models.Transaction `json:"reversal"`
+	Client   models.Client      `json:"client"`
+}
+
+// ReverseTransaction estorna uma transação do cliente com uma transação
+// compensatória ligada à original por reversed_transaction_id. O motivo é
+// obrigatório e aparece na descrição do estorno no extrato.
+func (h *Handler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if !h.authorize(w, r, ActionReverse, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	var req ReverseRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	// A transação original não muda depois de gravada e pode ser lida antes
+	// de bloquear a conta; um estorno concorrente é recusado pelo banco
+	original, err := h.db.GetTransaction(id, vars["tx_id"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var reversal *models.Transaction
+	client, err := h.db.UpdateClientTx(id, auditEntry(r, ActionReverse), func(client models.Client) error {
+		var err error
+		reversal, err = client.Reverse(*original, req.Reason)
+		return err
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ReverseResponse{Reversal: *reversal, Client: client})
+}
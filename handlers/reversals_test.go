@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func TestReverseTransaction(t *testing.T) {
+	db := database.NewMemoryDB()
+	handler := NewHandler(db)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/clients/{id}/transactions/{tx_id}/reverse", handler.ReverseTransaction).Methods("POST")
+
+	client, err := models.NewPersonalClient("John Doe", "529.982.247-25", models.MustParseMoney("1000.00"))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	if err := client.Withdraw(models.MustParseMoney("250.00")); err != nil {
+		t.Fatalf("Failed to withdraw: %v", err)
+	}
+	if err := db.CreatePersonalClient(client, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	txID := client.GetStatement()[0].ID
+	reversePath := "/api/clients/" + client.ID + "/transactions/" + txID + "/reverse"
+
+	w := serve(router, "POST", reversePath, `{"reason": ""}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusBadRequest || problem.Code != CodeValidationFailed {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeValidationFailed, w.Code, problem.Code)
+	}
+
+	w = serve(router, "POST", "/api/clients/"+client.ID+"/transactions/missing/reverse", `{"reason": "Erro do caixa"}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusNotFound || problem.Code != CodeTransactionNotFound {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusNotFound, CodeTransactionNotFound, w.Code, problem.Code)
+	}
+
+	w = serve(router, "POST", reversePath, `{"reason": "Saque lançado por engano"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+	var body struct {
+		Reversal models.Transaction `json:"reversal"`
+		Client   struct {
+			Balance models.Money `json:"balance"`
+		} `json:"client"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if body.Reversal.ReversedTransactionID != txID || body.Reversal.Description != "Estorno: Saque lançado por engano" {
+		t.Errorf("Unexpected reversal %+v", body.Reversal)
+	}
+	if body.Client.Balance != models.MustParseMoney("1000.00") {
+		t.Errorf("Expected balance 1000.00, got %v", body.Client.Balance)
+	}
+
+	w = serve(router, "POST", reversePath, `{"reason": "De novo"}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusConflict || problem.Code != CodeAlreadyReversed {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusConflict, CodeAlreadyReversed, w.Code, problem.Code)
+	}
+
+	w = serve(router, "POST", "/api/clients/"+client.ID+"/transactions/"+body.Reversal.ID+"/reverse", `{"reason": "Estorno do estorno"}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusConflict || problem.Code != CodeNotReversible {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusConflict, CodeNotReversible, w.Code, problem.Code)
+	}
+}
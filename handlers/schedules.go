@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/Luis-Andrei/api-users/scheduler"
+	"github.com/gorilla/mux"
+)
+
+// WithScheduler define o worker de agendamentos, cujo repositório guarda os
+// agendamentos criados pelas rotas /api/clients/{id}/schedules; por padrão
+// os agendamentos ficam em memória
+func WithScheduler(s *scheduler.Scheduler) Option {
+	return func(h *Handler) {
+		h.scheduler = s
+	}
+}
+
+// CreateSchedule agenda um saque ou uma transferência da conta. A conta de
+// destino de uma transferência precisa existir.
+func (h *Handler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionManageSchedules, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	var spec models.ScheduleSpec
+	if !decodeRequest(w, r, &spec) {
+		return
+	}
+	if err := h.checkScheduleAccounts(id, spec); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	op, err := models.NewScheduledOperation(id, spec, h.scheduler.Now())
+	if err == nil {
+		err = h.scheduler.Store().CreateSchedule(op)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.recordAudit(r, database.AuditEntry{Action: AuditCreateSchedule, ClientID: id})
+
+	writeJSON(w, http.StatusCreated, op)
+}
+
+// ListSchedules lista os agendamentos da conta, inclusive os encerrados
+func (h *Handler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionReadSchedules, auth.Resource{ClientID: id}) {
+		return
+	}
+
+	if _, err := h.db.GetClient(id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	schedules, err := h.scheduler.Store().ListSchedules(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.authorize(w, r, ActionReadSchedules, auth.Resource{ClientID: vars["id"]}) {
+		return
+	}
+
+	op, err := h.getSchedule(vars["id"], vars["schedule_id"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, op)
+}
+
+// UpdateSchedule substitui a operação de um agendamento ativo e recalcula a
+// próxima ocorrência; tentativas pendentes são descartadas
+func (h *Handler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.authorize(w, r, ActionManageSchedules, auth.Resource{ClientID: vars["id"]}) {
+		return
+	}
+
+	var spec models.ScheduleSpec
+	if !decodeRequest(w, r, &spec) {
+		return
+	}
+	if err := h.checkScheduleAccounts(vars["id"], spec); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	op, err := h.getSchedule(vars["id"], vars["schedule_id"])
+	if err == nil {
+		err = op.Update(spec, h.scheduler.Now())
+	}
+	if err == nil {
+		err = h.scheduler.Store().UpdateSchedule(op)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.recordAudit(r, database.AuditEntry{Action: AuditUpdateSchedule, ClientID: vars["id"]})
+
+	writeJSON(w, http.StatusOK, op)
+}
+
+// CancelSchedule encerra um agendamento ativo. O agendamento e as suas
+// execuções continuam disponíveis para consulta.
+func (h *Handler) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.authorize(w, r, ActionManageSchedules, auth.Resource{ClientID: vars["id"]}) {
+		return
+	}
+
+	op, err := h.getSchedule(vars["id"], vars["schedule_id"])
+	if err == nil {
+		err = op.Cancel(h.scheduler.Now())
+	}
+	if err == nil {
+		err = h.scheduler.Store().UpdateSchedule(op)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.recordAudit(r, database.AuditEntry{Action: AuditCancelSchedule, ClientID: vars["id"]})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListScheduleRuns lista as últimas execuções do agendamento, inclusive as
+// que falharam, da mais recente à mais antiga. Aceita limit (padrão 50,
+// máximo 200).
+func (h *Handler) ListScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !h.authorize(w, r, ActionReadSchedules, auth.Resource{ClientID: vars["id"]}) {
+		return
+	}
+
+	limit := database.DefaultPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > database.MaxPageSize {
+			writeError(w, r, invalidRequest("limit deve estar entre 1 e 200"))
+			return
+		}
+	}
+
+	op, err := h.getSchedule(vars["id"], vars["schedule_id"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	runs, err := h.scheduler.Store().ListScheduleRuns(op.ID, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// getSchedule retorna o agendamento se ele pertence ao cliente
+func (h *Handler) getSchedule(clientID, scheduleID string) (*models.ScheduledOperation, error) {
+	op, err := h.scheduler.Store().GetSchedule(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if op.ClientID != clientID {
+		return nil, database.ErrScheduleNotFound
+	}
+	return op, nil
+}
+
+// checkScheduleAccounts exige que a conta esteja aberta e que a conta de
+// destino de uma transferência exista
+func (h *Handler) checkScheduleAccounts(clientID string, spec models.ScheduleSpec) error {
+	client, err := h.db.GetClient(clientID)
+	if err != nil {
+		return err
+	}
+	if client.GetClosedAt() != nil {
+		return models.ErrAccountClosed
+	}
+	if spec.Type == models.ScheduleTypeTransfer && spec.ToClientID != "" && spec.ToClientID != clientID {
+		if _, err := h.db.GetClient(spec.ToClientID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
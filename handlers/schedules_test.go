@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/Luis-Andrei/api-users/scheduler"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestSchedules(t *testing.T) {
+	db := database.NewMemoryDB()
+	sched := scheduler.New(db, database.NewMemoryScheduleStore(), scheduler.Config{Location: time.UTC})
+	handler := NewHandler(db, WithScheduler(sched))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/clients/{id}/schedules", handler.CreateSchedule).Methods("POST")
+	router.HandleFunc("/api/clients/{id}/schedules", handler.ListSchedules).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/schedules/{schedule_id}", handler.GetSchedule).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/schedules/{schedule_id}", handler.UpdateSchedule).Methods("PUT")
+	router.HandleFunc("/api/clients/{id}/schedules/{schedule_id}", handler.CancelSchedule).Methods("DELETE")
+	router.HandleFunc("/api/clients/{id}/schedules/{schedule_id}/runs", handler.ListScheduleRuns).Methods("GET")
+
+	payer, err := models.NewCorporateClient("ACME Corp", "11.222.333/0001-81", models.MustParseMoney("3000.00"))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	landlord, err := models.NewPersonalClient("John Doe", "529.982.247-25", models.NewMoney(0))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	if err := db.CreateCorporateClient(payer, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := db.CreatePersonalClient(landlord, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	schedulesPath := "/api/clients/" + payer.ID + "/schedules"
+
+	w := serve(router, "POST", schedulesPath, `{"type": "transfer", "amount": 2000, "recurrence": "0 9 31 2 *"}`)
+	problem := decodeProblem(t, w)
+	if w.Code != http.StatusBadRequest || problem.Code != CodeValidationFailed {
+		t.Fatalf("Expected %d %s, got %d %s", http.StatusBadRequest, CodeValidationFailed, w.Code, problem.Code)
+	}
+	if len(problem.Details) != 2 || problem.Details[0].Field != "to_client_id" || problem.Details[1].Field != "recurrence" {
+		t.Errorf("Expected errors for to_client_id and recurrence, got %+v", problem.Details)
+	}
+
+	w = serve(router, "POST", schedulesPath, `{"type": "transfer", "amount": 2000, "to_client_id": "`+uuid.New().String()+`", "recurrence": "@monthly"}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusNotFound || problem.Code != CodeClientNotFound {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusNotFound, CodeClientNotFound, w.Code, problem.Code)
+	}
+
+	w = serve(router, "POST", schedulesPath, `{"type": "transfer", "amount": 2000, "to_client_id": "`+landlord.ID+`", "description": "Aluguel", "recurrence": "0 9 5 * *"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+	var op models.ScheduledOperation
+	json.NewDecoder(w.Body).Decode(&op)
+	if op.Status != models.ScheduleStatusActive || op.NextRunAt == nil || op.NextRunAt.Day() != 5 || op.NextRunAt.Hour() != 9 {
+		t.Errorf("Expected an active schedule on day 5 at 09:00, got %+v", op)
+	}
+	schedulePath := schedulesPath + "/" + op.ID
+
+	w = serve(router, "GET", schedulesPath, "")
+	var list []models.ScheduledOperation
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list) != 1 || list[0].ID != op.ID {
+		t.Errorf("Expected the schedule to be listed, got %d %+v", w.Code, list)
+	}
+
+	// O agendamento não é encontrado pela rota de outro cliente
+	w = serve(router, "GET", "/api/clients/"+landlord.ID+"/schedules/"+op.ID, "")
+	if problem := decodeProblem(t, w); w.Code != http.StatusNotFound || problem.Code != CodeScheduleNotFound {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusNotFound, CodeScheduleNotFound, w.Code, problem.Code)
+	}
+
+	w = serve(router, "PUT", schedulePath, `{"type": "withdrawal", "amount": 150.50, "recurrence": "@daily"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	w = serve(router, "GET", schedulePath, "")
+	var updated models.ScheduledOperation
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Type != models.ScheduleTypeWithdrawal || updated.Amount != models.MustParseMoney("150.50") || updated.ToClientID != "" ||
+		updated.Description != "" {
+		t.Errorf("Expected the schedule to become a daily withdrawal, got %+v", updated)
+	}
+
+	stored, err := sched.Store().GetSchedule(op.ID)
+	if err != nil {
+		t.Fatalf("Failed to get schedule: %v", err)
+	}
+	run := models.ScheduleRun{
+		ID:           uuid.New().String(),
+		ScheduleID:   op.ID,
+		ScheduledFor: *updated.NextRunAt,
+		ExecutedAt:   *updated.NextRunAt,
+		Attempt:      1,
+		Status:       models.ScheduleRunFailed,
+		Error:        "saldo insuficiente",
+	}
+	if err := sched.Store().RecordScheduleRun(stored, run); err != nil {
+		t.Fatalf("Failed to record schedule run: %v", err)
+	}
+	w = serve(router, "GET", schedulePath+"/runs", "")
+	var runs []models.ScheduleRun
+	json.NewDecoder(w.Body).Decode(&runs)
+	if w.Code != http.StatusOK || len(runs) != 1 || runs[0].Error != run.Error {
+		t.Errorf("Expected the failed run, got %d %+v", w.Code, runs)
+	}
+	if w := serve(router, "GET", schedulePath+"/runs?limit=0", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid limit, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if w := serve(router, "DELETE", schedulePath, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+	w = serve(router, "PUT", schedulePath, `{"type": "withdrawal", "amount": 10, "recurrence": "@daily"}`)
+	if problem := decodeProblem(t, w); w.Code != http.StatusConflict || problem.Code != CodeScheduleFinished {
+		t.Errorf("Expected %d %s, got %d %s", http.StatusConflict, CodeScheduleFinished, w.Code, problem.Code)
+	}
+	w = serve(router, "GET", schedulePath, "")
+	var canceled models.ScheduledOperation
+	json.NewDecoder(w.Body).Decode(&canceled)
+	if canceled.Status != models.ScheduleStatusCanceled || canceled.NextRunAt != nil {
+		t.Errorf("Expected a canceled schedule, got %+v", canceled)
+	}
+}
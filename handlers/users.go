@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Luis-Andrei/api-users/auth"
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+// UserRequest é o corpo de POST /api/users e PUT /api/users/{id}
+type UserRequest struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Biography string `json:"biography"`
+}
+
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, ActionReadUsers, auth.Resource{}) {
+		return
+	}
+
+	users, err := h.users.FindAll()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionReadUsers, auth.Resource{UserID: id}) {
+		return
+	}
+
+	user, err := h.users.FindByID(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, ActionManageUsers, auth.Resource{}) {
+		return
+	}
+
+	var req UserRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	user, err := models.NewUser(req.FirstName, req.LastName, req.Biography)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err = h.users.Insert(user)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.recordAudit(r, database.AuditEntry{Action: AuditCreateUser, UserID: user.ID})
+
+	writeJSON(w, http.StatusCreated, user)
+}
+
+// UpdateUser substitui todos os campos do usuário, validados como na criação
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionManageUsers, auth.Resource{UserID: id}) {
+		return
+	}
+
+	var req UserRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	user, err := models.NewUser(req.FirstName, req.LastName, req.Biography)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err = h.users.Update(id, user)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.recordAudit(r, database.AuditEntry{Action: AuditUpdateUser, UserID: id})
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// DeleteUser remove o usuário e os seus acessos. O último titular de uma
+// conta não pode ser removido.
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !h.authorize(w, r, ActionManageUsers, auth.Resource{UserID: id}) {
+		return
+	}
+
+	if _, err := h.holders.DeleteUser(id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.recordAudit(r, database.AuditEntry{Action: AuditDeleteUser, UserID: id})
+
+	w.WriteHeader(http.StatusNoContent)
+}
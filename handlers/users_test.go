@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/gorilla/mux"
+)
+
+func setupUserRouter() *mux.Router {
+	handler := NewHandler(&database.MockDB{}, WithUserStore(database.NewDatabase()))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/users", handler.ListUsers).Methods("GET")
+	router.HandleFunc("/api/users", handler.CreateUser).Methods("POST")
+	router.HandleFunc("/api/users/{id}", handler.GetUser).Methods("GET")
+	router.HandleFunc("/api/users/{id}", handler.UpdateUser).Methods("PUT")
+	router.HandleFunc("/api/users/{id}", handler.DeleteUser).Methods("DELETE")
+	return router
+}
+
+func serve(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	return w
+}
+
+func TestUserCRUD(t *testing.T) {
+	router := setupUserRouter()
+
+	w := serve(router, "POST", "/api/users", `{"first_name": "John", "last_name": "Doe", "biography": "A biography with more than twenty characters"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+	var user models.User
+	json.NewDecoder(w.Body).Decode(&user)
+	if user.ID == "" {
+		t.Fatal("Expected created user to have an ID")
+	}
+
+	if w := serve(router, "GET", "/api/users/"+user.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = serve(router, "PUT", "/api/users/"+user.ID, `{"first_name": "Jane", "last_name": "Doe", "biography": "A biography with more than twenty characters"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = serve(router, "GET", "/api/users", "")
+	var users []models.User
+	json.NewDecoder(w.Body).Decode(&users)
+	if len(users) != 1 || users[0].FirstName != "Jane" {
+		t.Errorf("Expected updated user in list, got %+v", users)
+	}
+
+	if w := serve(router, "DELETE", "/api/users/"+user.ID, ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	w = serve(router, "GET", "/api/users/"+user.ID, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if problem := decodeProblem(t, w); problem.Code != CodeUserNotFound {
+		t.Errorf("Expected code %q, got %q", CodeUserNotFound, problem.Code)
+	}
+}
+
+func TestCreateUserValidation(t *testing.T) {
+	router := setupUserRouter()
+
+	w := serve(router, "POST", "/api/users", `{"first_name": "J", "last_name": "Doe", "biography": "short"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	problem := decodeProblem(t, w)
+	if problem.Code != CodeValidationFailed {
+		t.Errorf("Expected code %q, got %q", CodeValidationFailed, problem.Code)
+	}
+	fields := map[string]bool{}
+	for _, d := range problem.Details {
+		fields[d.Field] = true
+	}
+	if len(problem.Details) != 2 || !fields["first_name"] || !fields["biography"] {
+		t.Errorf("Expected details for first_name and biography, got %+v", problem.Details)
+	}
+
+	w = serve(router, "PUT", "/api/users/missing", `{"first_name": "John", "last_name": "Doe", "biography": "A biography with more than twenty characters"}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
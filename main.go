@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/Luis-Andrei/api-users/accrual"
+	"github.com/Luis-Andrei/api-users/auth"
 	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/database/migrations"
 	"github.com/Luis-Andrei/api-users/handlers"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/Luis-Andrei/api-users/scheduler"
 	"github.com/gorilla/mux"
 )
 
@@ -33,33 +42,326 @@ func main() {
 		dbname = "bank"
 	}
 
-	// Conecta ao banco de dados
-	db, err := database.NewPostgresDB(host, port, user, password, dbname)
+	// Subcomando: hash-key <chave>, que gera o hash para API_KEYS_FILE
+	if len(os.Args) > 1 && os.Args[1] == "hash-key" {
+		if len(os.Args) != 3 {
+			log.Fatal("Uso: hash-key <chave>")
+		}
+		fmt.Println(auth.HashAPIKey(os.Args[2]))
+		return
+	}
+
+	// Subcomando: migrate up|down [n]|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(host, port, user, password, dbname, os.Args[2:])
+		return
+	}
+
+	accrualConfig, err := newAccrualConfig()
 	if err != nil {
-		log.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+		log.Fatalf("Erro na configuração de juros e tarifas: %v", err)
+	}
+
+	// Subcomando: accrue [AAAA-MM-DD [AAAA-MM-DD]], que lança juros e tarifas
+	if len(os.Args) > 1 && os.Args[1] == "accrue" {
+		runAccrue(host, port, user, password, dbname, accrualConfig, os.Args[2:])
+		return
+	}
+
+	// Seleciona o armazenamento: PostgreSQL (padrão) ou memória
+	var (
+		db          database.Database
+		users       database.UserStore
+		holders     database.HolderStore
+		idempotency database.IdempotencyStore
+		schedules   database.ScheduleStore
+	)
+	switch storage := os.Getenv("STORAGE"); storage {
+	case "", "postgres":
+		postgres, err := database.NewPostgresDB(host, port, user, password, dbname)
+		if err != nil {
+			log.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+		}
+		pg := postgres.(*database.PostgresDB)
+		db, users, holders, idempotency = pg, pg.Users(), pg.Holders(), pg.Idempotency()
+		schedules = pg.Schedules()
+	case "memory":
+		log.Println("Usando armazenamento em memória; os dados serão perdidos ao encerrar")
+		memory, memoryUsers := database.NewMemoryDB(), database.NewDatabase()
+		db, users, holders = memory, memoryUsers, database.NewMemoryHolderStore(memory, memoryUsers)
+		idempotency = database.NewMemoryIdempotencyStore()
+		schedules = database.NewMemoryScheduleStore()
+	default:
+		log.Fatalf("STORAGE inválido: %s (use postgres ou memory)", storage)
 	}
 	defer db.Close()
 
-	// Inicializa as tabelas
+	// Aplica as migrações pendentes
 	if err := db.InitTables(); err != nil {
 		log.Fatalf("Erro ao inicializar tabelas: %v", err)
 	}
 
+	// Lança juros e tarifas a cada virada de dia, salvo com ACCRUAL_DISABLED=true
+	engine := accrual.New(db, accrualConfig)
+	if os.Getenv("ACCRUAL_DISABLED") == "true" {
+		log.Println("Lançamento automático de juros e tarifas desabilitado (ACCRUAL_DISABLED=true)")
+	} else {
+		go engine.Schedule(context.Background())
+	}
+
+	// Executa os saques e transferências agendados, salvo com
+	// SCHEDULER_DISABLED=true
+	schedulerConfig := scheduler.Config{}
+	if v := os.Getenv("SCHEDULER_TIMEZONE"); v != "" {
+		if schedulerConfig.Location, err = time.LoadLocation(v); err != nil {
+			log.Fatalf("SCHEDULER_TIMEZONE inválido: %v", err)
+		}
+	}
+	sched := scheduler.New(db, schedules, schedulerConfig)
+	if os.Getenv("SCHEDULER_DISABLED") == "true" {
+		log.Println("Execução de agendamentos desabilitada (SCHEDULER_DISABLED=true)")
+	} else {
+		go sched.Run(context.Background())
+	}
+
 	// Cria uma nova instância do handler
-	handler := handlers.NewHandler(db)
+	opts := []handlers.Option{
+		handlers.WithUserStore(users),
+		handlers.WithHolderStore(holders),
+		handlers.WithIdempotencyStore(idempotency),
+		handlers.WithAccrualEngine(engine),
+		handlers.WithScheduler(sched),
+	}
 
 	// Cria um novo router
 	router := mux.NewRouter()
+	router.Use(handlers.RequestID)
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		log.Println("Autenticação desabilitada (AUTH_DISABLED=true); todas as rotas estão abertas")
+	} else {
+		authenticator, err := newAuthenticator()
+		if err != nil {
+			log.Fatalf("Erro ao configurar a autenticação: %v", err)
+		}
+		router.Use(handlers.Authenticate(authenticator))
 
-	// Define as rotas da API
-	router.HandleFunc("/api/clients/personal", handler.CreatePersonalClient).Methods("POST")
-	router.HandleFunc("/api/clients/corporate", handler.CreateCorporateClient).Methods("POST")
+		policyFile := os.Getenv("POLICY_FILE")
+		if policyFile == "" {
+			policyFile = "policy.json"
+		}
+		policy, err := auth.LoadPolicy(policyFile, handlers.Actions)
+		if err != nil {
+			log.Fatalf("Erro ao carregar a política de acesso: %v", err)
+		}
+		opts = append(opts, handlers.WithPolicy(policy))
+	}
+
+	handler := handlers.NewHandler(db, opts...)
+
+	// Define as rotas da API; as operações POST aceitam Idempotency-Key
+	router.HandleFunc("/api/clients/personal", handler.Idempotent(handler.CreatePersonalClient)).Methods("POST")
+	router.HandleFunc("/api/clients/corporate", handler.Idempotent(handler.CreateCorporateClient)).Methods("POST")
 	router.HandleFunc("/api/clients", handler.ListClients).Methods("GET")
 	router.HandleFunc("/api/clients/{id}", handler.GetClient).Methods("GET")
-	router.HandleFunc("/api/clients/{id}/withdraw", handler.Withdraw).Methods("POST")
+	router.HandleFunc("/api/clients/{id}", handler.UpdateClient).Methods("PUT", "PATCH")
+	router.HandleFunc("/api/clients/{id}", handler.DeleteClient).Methods("DELETE")
+	router.HandleFunc("/api/clients/{id}/withdraw", handler.Idempotent(handler.Withdraw)).Methods("POST")
+	router.HandleFunc("/api/clients/{id}/deposit", handler.Idempotent(handler.Deposit)).Methods("POST")
 	router.HandleFunc("/api/clients/{id}/statement", handler.GetStatement).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/transactions/{tx_id}/reverse", handler.Idempotent(handler.ReverseTransaction)).Methods("POST")
+	router.HandleFunc("/api/clients/{id}/limits", handler.GetLimits).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/limits", handler.UpdateLimits).Methods("PUT")
+	router.HandleFunc("/api/clients/{id}/credit-limit", handler.UpdateCreditLimit).Methods("PUT")
+	router.HandleFunc("/api/clients/{id}/schedules", handler.Idempotent(handler.CreateSchedule)).Methods("POST")
+	router.HandleFunc("/api/clients/{id}/schedules", handler.ListSchedules).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/schedules/{schedule_id}", handler.GetSchedule).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/schedules/{schedule_id}", handler.UpdateSchedule).Methods("PUT")
+	router.HandleFunc("/api/clients/{id}/schedules/{schedule_id}", handler.CancelSchedule).Methods("DELETE")
+	router.HandleFunc("/api/clients/{id}/schedules/{schedule_id}/runs", handler.ListScheduleRuns).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/holders", handler.ListHolders).Methods("GET")
+	router.HandleFunc("/api/clients/{id}/holders/{user_id}", handler.GrantAccess).Methods("PUT")
+	router.HandleFunc("/api/clients/{id}/holders/{user_id}", handler.RevokeAccess).Methods("DELETE")
+	router.HandleFunc("/api/transfers", handler.Idempotent(handler.Transfer)).Methods("POST")
+	router.HandleFunc("/api/users", handler.ListUsers).Methods("GET")
+	router.HandleFunc("/api/users", handler.CreateUser).Methods("POST")
+	router.HandleFunc("/api/users/{id}", handler.GetUser).Methods("GET")
+	router.HandleFunc("/api/users/{id}", handler.UpdateUser).Methods("PUT")
+	router.HandleFunc("/api/users/{id}", handler.DeleteUser).Methods("DELETE")
+	router.HandleFunc("/api/users/{id}/accounts", handler.ListUserAccounts).Methods("GET")
+	router.HandleFunc("/api/audit", handler.ListAudit).Methods("GET")
+	router.HandleFunc("/api/audit/verify", handler.VerifyAudit).Methods("GET")
+	router.HandleFunc("/api/admin/accruals", handler.RunAccruals).Methods("POST")
 
 	// Inicia o servidor
 	log.Println("Servidor iniciando na porta 8080...")
 	log.Fatal(http.ListenAndServe(":8080", router))
 }
+
+// newAuthenticator configura as chaves de API e a validação de JWT a partir
+// das variáveis de ambiente
+func newAuthenticator() (*auth.Authenticator, error) {
+	cfg := auth.Config{
+		HMACSecret: []byte(os.Getenv("JWT_HS256_SECRET")),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+	}
+
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		keys, err := auth.LoadAPIKeys(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.APIKeys = keys
+	}
+
+	if path := os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading public key: %w", err)
+		}
+		if cfg.RSAPublicKey, err = auth.ParseRSAPublicKey(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return auth.New(cfg)
+}
+
+// newAccrualConfig lê as taxas e tarifas do motor de juros das variáveis de
+// ambiente; sem elas nada é lançado
+func newAccrualConfig() (accrual.Config, error) {
+	var cfg accrual.Config
+	var err error
+
+	for _, rate := range []struct {
+		env  string
+		dest *int64
+	}{
+		{"ACCRUAL_INTEREST_RATE_BPS", &cfg.InterestRate},
+		{"ACCRUAL_OVERDRAFT_RATE_BPS", &cfg.OverdraftRate},
+	} {
+		if v := os.Getenv(rate.env); v != "" {
+			if *rate.dest, err = strconv.ParseInt(v, 10, 64); err != nil || *rate.dest < 0 {
+				return cfg, fmt.Errorf("%s inválido: %s", rate.env, v)
+			}
+		}
+	}
+
+	for _, fee := range []struct {
+		env  string
+		dest *models.Money
+	}{
+		{"ACCRUAL_PERSONAL_MONTHLY_FEE", &cfg.PersonalMonthlyFee},
+		{"ACCRUAL_CORPORATE_MONTHLY_FEE", &cfg.CorporateMonthlyFee},
+	} {
+		if v := os.Getenv(fee.env); v != "" {
+			if *fee.dest, err = models.ParseMoney(v); err != nil || fee.dest.IsNegative() {
+				return cfg, fmt.Errorf("%s inválido: %s", fee.env, v)
+			}
+		}
+	}
+
+	if v := os.Getenv("ACCRUAL_TIMEZONE"); v != "" {
+		if cfg.Location, err = time.LoadLocation(v); err != nil {
+			return cfg, fmt.Errorf("ACCRUAL_TIMEZONE inválido: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// runAccrue executa o subcomando accrue contra o PostgreSQL: sem argumentos
+// processa o último dia encerrado, com uma data processa aquele dia e com
+// duas processa o período, para recuperar dias não processados
+func runAccrue(host, port, user, password, dbname string, cfg accrual.Config, args []string) {
+	if len(args) > 2 {
+		log.Fatal("Uso: accrue [AAAA-MM-DD [AAAA-MM-DD]]")
+	}
+
+	db, err := database.NewPostgresDB(host, port, user, password, dbname)
+	if err != nil {
+		log.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+	}
+	defer db.Close()
+
+	engine := accrual.New(db, cfg)
+	from, to := engine.Yesterday(), engine.Yesterday()
+	if len(args) > 0 {
+		if from, err = engine.ParseDate(args[0]); err != nil {
+			log.Fatalf("Data inválida: %s", args[0])
+		}
+		to = from
+	}
+	if len(args) > 1 {
+		if to, err = engine.ParseDate(args[1]); err != nil {
+			log.Fatalf("Data inválida: %s", args[1])
+		}
+	}
+
+	results, err := engine.RunRange(from, to, nil)
+	for _, r := range results {
+		fmt.Printf("%s\t%d conta(s)\t%d juros\t%d juros do cheque especial\t%d tarifa(s)\t%d falha(s)\n",
+			r.Date, r.Clients, r.Interest, r.OverdraftInterest, r.Fees, len(r.Failed))
+		for _, f := range r.Failed {
+			fmt.Printf("\tfalha em %s: %s\n", f.ClientID, f.Error)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Erro ao lançar juros e tarifas: %v", err)
+	}
+}
+
+// runMigrate executa o subcomando migrate contra o banco configurado
+func runMigrate(host, port, user, password, dbname string, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Uso: migrate up|down [n]|status")
+	}
+
+	db, err := database.OpenPostgres(host, port, user, password, dbname)
+	if err != nil {
+		log.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(db)
+		if err != nil {
+			log.Fatalf("Erro ao aplicar migrações: %v", err)
+		}
+		for _, m := range applied {
+			log.Printf("Aplicada %04d_%s", m.Version, m.Name)
+		}
+		log.Printf("%d migração(ões) aplicada(s)", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("Número de passos inválido: %s", args[1])
+			}
+		}
+		reverted, err := migrations.Down(db, steps)
+		if err != nil {
+			log.Fatalf("Erro ao reverter migrações: %v", err)
+		}
+		for _, m := range reverted {
+			log.Printf("Revertida %04d_%s", m.Version, m.Name)
+		}
+		log.Printf("%d migração(ões) revertida(s)", len(reverted))
+	case "status":
+		statuses, err := migrations.GetStatus(db)
+		if err != nil {
+			log.Fatalf("Erro ao consultar migrações: %v", err)
+		}
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Printf("%04d_%s\taplicada em %s\n", s.Version, s.Name, s.AppliedAt.Format(time.RFC3339))
+			} else {
+				fmt.Printf("%04d_%s\tpendente\n", s.Version, s.Name)
+			}
+		}
+	default:
+		log.Fatalf("Subcomando desconhecido: migrate %s", args[0])
+	}
+}
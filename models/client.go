@@ -10,38 +10,229 @@ var (
 	ErrInsufficientFunds = errors.New("saldo insuficiente")
 	ErrInvalidAmount     = errors.New("valor inválido")
 	ErrWithdrawLimit     = errors.New("limite de saque excedido")
+	ErrSameClient        = errors.New("conta de origem e destino são iguais")
+	ErrAccountClosed     = errors.New("conta encerrada")
+	ErrNonZeroBalance    = errors.New("a conta só pode ser encerrada com saldo zero")
+	ErrInvalidName       = errors.New("nome inválido")
+
+	ErrTransactionNotFound = errors.New("transação não encontrada")
+	ErrAlreadyReversed     = errors.New("transação já estornada")
+	ErrNotReversible       = errors.New("transação não pode ser estornada")
+)
+
+// MaxNameLength é o tamanho máximo do nome do cliente
+const MaxNameLength = 100
+
+// MaxReversalReasonLength é o tamanho máximo do motivo de um estorno
+const MaxReversalReasonLength = 200
+
+// Tipos de transação
+const (
+	TransactionTypeWithdrawal  = "withdrawal"
+	TransactionTypeDeposit     = "deposit"
+	TransactionTypeTransferOut = "transfer_out"
+	TransactionTypeTransferIn  = "transfer_in"
+	TransactionTypeInterest    = "interest"
+	TransactionTypeFee         = "fee"
+
+	// Estornos: reversal_credit desfaz um débito e reversal_debit um crédito
+	TransactionTypeReversalCredit = "reversal_credit"
+	TransactionTypeReversalDebit  = "reversal_debit"
 )
 
+// Tipos de transação que aumentam e que diminuem o saldo
+var (
+	CreditTransactionTypes = []string{TransactionTypeDeposit, TransactionTypeTransferIn, TransactionTypeInterest, TransactionTypeReversalCredit}
+	DebitTransactionTypes  = []string{TransactionTypeWithdrawal, TransactionTypeTransferOut, TransactionTypeFee, TransactionTypeReversalDebit}
+)
+
+// ReversibleTransactionTypes são os tipos que podem ser estornados. As
+// transferências não estão entre eles porque envolvem duas contas.
+var (
+	ReversibleTransactionTypes = []string{TransactionTypeWithdrawal, TransactionTypeDeposit, TransactionTypeInterest, TransactionTypeFee}
+)
+
+// IsCreditType indica se o tipo de transação aumenta o saldo
+func IsCreditType(txType string) bool {
+	for _, t := range CreditTransactionTypes {
+		if t == txType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidTransactionType indica se o tipo de transação é conhecido
+func IsValidTransactionType(txType string) bool {
+	if IsCreditType(txType) {
+		return true
+	}
+	for _, t := range DebitTransactionTypes {
+		if t == txType {
+			return true
+		}
+	}
+	return false
+}
+
 // Transaction representa uma transação bancária
 type Transaction struct {
 	ID          string    `json:"id"`
-	Amount      float64   `json:"amount"`
+	Amount      Money     `json:"amount"`
 	Type        string    `json:"type"` // "withdrawal" ou "deposit"
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// TransferID e CounterpartyID ligam as duas pontas de uma transferência
+	TransferID     string `json:"transfer_id,omitempty"`
+	CounterpartyID string `json:"counterparty_id,omitempty"`
+
+	// OverdraftAmount é a parte de um débito coberta pelo limite de crédito
+	OverdraftAmount *Money `json:"overdraft_amount,omitempty"`
+
+	// PostingKey identifica lançamentos automáticos, como juros e tarifas,
+	// que só podem ser feitos uma vez por conta
+	PostingKey string `json:"posting_key,omitempty"`
+
+	// ReversedTransactionID liga um estorno à transação estornada
+	ReversedTransactionID string `json:"reversed_transaction_id,omitempty"`
+}
+
+// SignedAmount retorna o valor com sinal: positivo para créditos e negativo para débitos
+func (t Transaction) SignedAmount() Money {
+	if IsCreditType(t.Type) {
+		return t.Amount
+	}
+	return t.Amount.Neg()
 }
 
 // Client é a interface que define os métodos que um cliente deve implementar
 type Client interface {
+	// GetID retorna o identificador do cliente
+	GetID() string
+
+	// GetName retorna o nome do cliente
+	GetName() string
+
 	// Withdraw realiza um saque na conta do cliente
-	Withdraw(amount float64) error
+	Withdraw(amount Money) error
+
+	// WithdrawWithDescription realiza um saque com a descrição informada e
+	// retorna a transação criada
+	WithdrawWithDescription(amount Money, description string) (*Transaction, error)
+
+	// Deposit realiza um depósito na conta do cliente
+	Deposit(amount Money, description string) error
+
+	// TransferOut debita uma transferência enviada para o cliente toID
+	TransferOut(amount Money, toID, transferID, description string) error
+
+	// TransferIn credita uma transferência recebida do cliente fromID
+	TransferIn(amount Money, fromID, transferID, description string) error
 
 	// GetStatement retorna o extrato das transações do cliente
 	GetStatement() []Transaction
 
 	// GetBalance retorna o saldo atual do cliente
-	GetBalance() float64
+	GetBalance() Money
+
+	// GetWithdrawLimit retorna o limite de saque por operação do cliente
+	GetWithdrawLimit() Money
+
+	// GetLimits retorna os limites de saque efetivos, já aplicado o override
+	GetLimits() WithdrawLimits
+
+	// GetLimitOverride retorna os limites específicos deste cliente
+	GetLimitOverride() LimitOverride
+
+	// SetLimitOverride valida e substitui os limites específicos do cliente
+	SetLimitOverride(override LimitOverride) error
+
+	// GetLimitUsage retorna quanto do limite diário e mensal já foi utilizado,
+	// somando ao uso carregado pelo banco o das transações em memória
+	GetLimitUsage() LimitUsage
+
+	// GetCreditLimit retorna até quanto o saldo pode ficar negativo
+	GetCreditLimit() Money
+
+	// SetCreditLimit altera o limite de crédito, que não pode ser negativo
+	SetCreditLimit(limit Money) error
+
+	// GetAvailableBalance retorna o saldo somado ao limite de crédito
+	GetAvailableBalance() Money
+
+	// CreditInterest credita juros na data at, identificados por key
+	CreditInterest(amount Money, key string, at time.Time) error
+
+	// ChargeFee debita uma tarifa na data at, identificada por key, sem
+	// aplicar os limites de saque nem exigir saldo disponível
+	ChargeFee(amount Money, description, key string, at time.Time) error
+
+	// Reverse estorna a transação original com uma transação compensatória
+	// que desfaz o seu efeito no saldo e registra o motivo. Um estorno
+	// repetido só é detectado entre as transações em memória; o banco recusa
+	// os demais.
+	Reverse(original Transaction, reason string) (*Transaction, error)
+
+	// UnsavedTransactions retorna as transações registradas desde a última gravação
+	UnsavedTransactions() []Transaction
+
+	// MarkSaved indica que todas as transações do cliente já foram gravadas
+	MarkSaved()
 
-	// GetWithdrawLimit retorna o limite de saque do cliente
-	GetWithdrawLimit() float64
+	// GetProfile retorna os dados cadastrais do cliente
+	GetProfile() Profile
+
+	// UpdateProfile valida e altera os dados cadastrais informados em update
+	UpdateProfile(update ProfileUpdate) error
+
+	// Close encerra a conta, que precisa estar com saldo zero
+	Close() error
+
+	// GetOpenedAt retorna quando a conta foi aberta
+	GetOpenedAt() time.Time
+
+	// GetClosedAt retorna quando a conta foi encerrada, ou nil se estiver aberta
+	GetClosedAt() *time.Time
+}
+
+// Profile reúne os dados cadastrais alteráveis do cliente
+type Profile struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// ProfileUpdate descreve uma alteração cadastral; campos nil não são
+// alterados e e-mail ou telefone vazios são removidos
+type ProfileUpdate struct {
+	Name  *string
+	Email *string
+	Phone *string
 }
 
 // BaseClient contém os campos comuns entre pessoa física e jurídica
 type BaseClient struct {
 	ID           string        `json:"id"`
 	Name         string        `json:"name"`
-	Balance      float64       `json:"balance"`
-	Transactions []Transaction `json:"transactions"`
+	Balance      Money         `json:"balance"`
+	CreditLimit  Money         `json:"credit_limit"`
+	Email        string        `json:"email,omitempty"`
+	Phone        string        `json:"phone,omitempty"`
+	OpenedAt     time.Time     `json:"opened_at"`
+	ClosedAt     *time.Time    `json:"closed_at,omitempty"`
+	Transactions []Transaction `json:"transactions,omitempty"`
+
+	// LimitOverride guarda os limites de saque específicos do cliente,
+	// expostos em /api/clients/{id}/limits
+	LimitOverride LimitOverride `json:"-"`
+
+	// LimitUsage é o uso dos limites pelas transações já gravadas, calculado
+	// pelo banco ao carregar o cliente, que não traz o histórico
+	LimitUsage LimitUsage `json:"-"`
+
+	// unsaved conta as transações do fim de Transactions ainda não gravadas
+	unsaved int
 }
 
 // PersonalClient representa uma pessoa física
@@ -56,8 +247,17 @@ type CorporateClient struct {
 	CNPJ string `json:"cnpj"`
 }
 
-// Constantes para limites de saque
+// Descrições usadas quando a operação não informa uma
 const (
-	PersonalClientWithdrawLimit  = 1000.0
-	CorporateClientWithdrawLimit = 5000.0
+	DefaultDepositDescription     = "Depósito em dinheiro"
+	DefaultWithdrawalDescription  = "Saque em dinheiro"
+	DefaultTransferOutDescription = "Transferência enviada"
+	DefaultTransferInDescription  = "Transferência recebida"
+)
+
+// Limites padrão de saque por operação; os limites diários e mensais estão
+// em PersonalClientLimits e CorporateClientLimits
+var (
+	PersonalClientWithdrawLimit  = MustParseMoney("1000.00")
+	CorporateClientWithdrawLimit = MustParseMoney("5000.00")
 )
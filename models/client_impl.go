@@ -1,107 +1,503 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Luis-Andrei/api-users/models/validation"
 	"github.com/google/uuid"
 )
 
-// NewPersonalClient cria um novo cliente pessoa física
-func NewPersonalClient(name, cpf string, initialBalance float64) *PersonalClient {
+// NewPersonalClient cria um novo cliente pessoa física, validando o nome e
+// normalizando o CPF
+func NewPersonalClient(name, cpf string, initialBalance Money) (*PersonalClient, error) {
+	cpf, err := validation.NormalizeCPF(cpf)
+	if err != nil {
+		return nil, err
+	}
+	name, err = normalizeName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInitialBalance(initialBalance); err != nil {
+		return nil, err
+	}
+
 	return &PersonalClient{
 		BaseClient: BaseClient{
 			ID:           uuid.New().String(),
 			Name:         name,
 			Balance:      initialBalance,
+			CreditLimit:  NewMoney(0),
+			OpenedAt:     time.Now(),
 			Transactions: make([]Transaction, 0),
 		},
 		CPF: cpf,
-	}
+	}, nil
 }
 
-// NewCorporateClient cria um novo cliente pessoa jurídica
-func NewCorporateClient(name, cnpj string, initialBalance float64) *CorporateClient {
+// NewCorporateClient cria um novo cliente pessoa jurídica, validando o nome e
+// normalizando o CNPJ
+func NewCorporateClient(name, cnpj string, initialBalance Money) (*CorporateClient, error) {
+	cnpj, err := validation.NormalizeCNPJ(cnpj)
+	if err != nil {
+		return nil, err
+	}
+	name, err = normalizeName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInitialBalance(initialBalance); err != nil {
+		return nil, err
+	}
+
 	return &CorporateClient{
 		BaseClient: BaseClient{
 			ID:           uuid.New().String(),
 			Name:         name,
 			Balance:      initialBalance,
+			CreditLimit:  NewMoney(0),
+			OpenedAt:     time.Now(),
 			Transactions: make([]Transaction, 0),
 		},
 		CNPJ: cnpj,
+	}, nil
+}
+
+// Implementação dos métodos comuns a todos os clientes
+
+func (c *BaseClient) GetID() string {
+	return c.ID
+}
+
+func (c *BaseClient) GetName() string {
+	return c.Name
+}
+
+func (c *BaseClient) GetStatement() []Transaction {
+	return c.Transactions
+}
+
+func (c *BaseClient) GetBalance() Money {
+	return c.Balance
+}
+
+func (c *BaseClient) Deposit(amount Money, description string) error {
+	if description == "" {
+		description = DefaultDepositDescription
 	}
+
+	return c.credit(amount, Transaction{
+		Type:        TransactionTypeDeposit,
+		Description: description,
+	})
 }
 
-// Implementação dos métodos para PersonalClient
+func (c *BaseClient) TransferIn(amount Money, fromID, transferID, description string) error {
+	if description == "" {
+		description = DefaultTransferInDescription
+	}
+
+	return c.credit(amount, Transaction{
+		Type:           TransactionTypeTransferIn,
+		Description:    description,
+		TransferID:     transferID,
+		CounterpartyID: fromID,
+	})
+}
 
-func (c *PersonalClient) Withdraw(amount float64) error {
-	if amount <= 0 {
+func (c *BaseClient) CreditInterest(amount Money, key string, at time.Time) error {
+	return c.credit(amount, Transaction{
+		Type:        TransactionTypeInterest,
+		Description: "Rendimento do saldo",
+		PostingKey:  key,
+		CreatedAt:   at,
+	})
+}
+
+func (c *BaseClient) ChargeFee(amount Money, description, key string, at time.Time) error {
+	if c.ClosedAt != nil {
+		return ErrAccountClosed
+	}
+	if !amount.IsPositive() {
 		return ErrInvalidAmount
 	}
-	if amount > c.GetWithdrawLimit() {
-		return ErrWithdrawLimit
+
+	c.withdrawBalance(amount, Transaction{
+		Type:        TransactionTypeFee,
+		Description: description,
+		PostingKey:  key,
+		CreatedAt:   at,
+	})
+	return nil
+}
+
+func (c *BaseClient) Reverse(original Transaction, reason string) (*Transaction, error) {
+	verr := &ValidationError{}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		verr.Add("reason", "é obrigatório")
+	} else if utf8.RuneCountInString(reason) > MaxReversalReasonLength {
+		verr.Add("reason", fmt.Sprintf("deve ter no máximo %d caracteres", MaxReversalReasonLength))
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, err
 	}
-	if amount > c.Balance {
-		return ErrInsufficientFunds
+
+	if !isReversible(original.Type) {
+		return nil, ErrNotReversible
+	}
+	if c.isReversed(original.ID) {
+		return nil, ErrAlreadyReversed
 	}
 
-	c.Balance -= amount
-	c.Transactions = append(c.Transactions, Transaction{
-		ID:          uuid.New().String(),
-		Amount:      amount,
-		Type:        "withdrawal",
-		Description: "Saque em dinheiro",
-		CreatedAt:   time.Now(),
-	})
+	tx := Transaction{
+		Description:           "Estorno: " + reason,
+		ReversedTransactionID: original.ID,
+	}
+	amount := original.Amount
+	if IsCreditType(original.Type) {
+		// Estornar um crédito é um débito comum, mas sem os limites de saque
+		if c.ClosedAt != nil {
+			return nil, ErrAccountClosed
+		}
+		if amount.Cmp(c.GetAvailableBalance()) > 0 {
+			return nil, ErrInsufficientFunds
+		}
+		tx.Type = TransactionTypeReversalDebit
+		c.withdrawBalance(amount, tx)
+	} else {
+		tx.Type = TransactionTypeReversalCredit
+		if err := c.credit(amount, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	reversal := c.Transactions[len(c.Transactions)-1]
+	return &reversal, nil
+}
+
+// isReversed indica se há em Transactions um estorno da transação txID
+func (c *BaseClient) isReversed(txID string) bool {
+	for _, t := range c.Transactions {
+		if t.ReversedTransactionID == txID {
+			return true
+		}
+	}
+	return false
+}
+
+func isReversible(txType string) bool {
+	for _, t := range ReversibleTransactionTypes {
+		if t == txType {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *BaseClient) GetProfile() Profile {
+	return Profile{Name: c.Name, Email: c.Email, Phone: c.Phone}
+}
+
+// UpdateProfile valida todos os campos antes de alterar qualquer um deles
+func (c *BaseClient) UpdateProfile(update ProfileUpdate) error {
+	if c.ClosedAt != nil {
+		return ErrAccountClosed
+	}
 
+	profile := c.GetProfile()
+	var err error
+	if update.Name != nil {
+		if profile.Name, err = normalizeName(*update.Name); err != nil {
+			return err
+		}
+	}
+	if update.Email != nil {
+		profile.Email = ""
+		if *update.Email != "" {
+			if profile.Email, err = validation.NormalizeEmail(*update.Email); err != nil {
+				return err
+			}
+		}
+	}
+	if update.Phone != nil {
+		profile.Phone = ""
+		if *update.Phone != "" {
+			if profile.Phone, err = validation.NormalizePhone(*update.Phone); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.Name, c.Email, c.Phone = profile.Name, profile.Email, profile.Phone
 	return nil
 }
 
-func (c *PersonalClient) GetStatement() []Transaction {
-	return c.Transactions
+func (c *BaseClient) Close() error {
+	if c.ClosedAt != nil {
+		return ErrAccountClosed
+	}
+	if !c.Balance.IsZero() {
+		return ErrNonZeroBalance
+	}
+
+	now := time.Now()
+	c.ClosedAt = &now
+	return nil
 }
 
-func (c *PersonalClient) GetBalance() float64 {
-	return c.Balance
+func (c *BaseClient) GetOpenedAt() time.Time {
+	return c.OpenedAt
 }
 
-func (c *PersonalClient) GetWithdrawLimit() float64 {
-	return PersonalClientWithdrawLimit
+func (c *BaseClient) GetClosedAt() *time.Time {
+	return c.ClosedAt
 }
 
-// Implementação dos métodos para CorporateClient
+func (c *BaseClient) GetLimitOverride() LimitOverride {
+	return c.LimitOverride
+}
+
+// setLimitOverride valida o override sobre os limites padrão do tipo de
+// cliente
+func (c *BaseClient) setLimitOverride(override LimitOverride, defaults WithdrawLimits) error {
+	if c.ClosedAt != nil {
+		return ErrAccountClosed
+	}
+	if err := override.Validate(defaults); err != nil {
+		return err
+	}
 
-func (c *CorporateClient) Withdraw(amount float64) error {
-	if amount <= 0 {
+	c.LimitOverride = override
+	return nil
+}
+
+func (c *BaseClient) GetLimitUsage() LimitUsage {
+	used := ComputeLimitUsage(c.Transactions, time.Now())
+	return LimitUsage{
+		Daily:   c.LimitUsage.Daily.Add(used.Daily),
+		Monthly: c.LimitUsage.Monthly.Add(used.Monthly),
+	}
+}
+
+func (c *BaseClient) GetCreditLimit() Money {
+	return c.CreditLimit
+}
+
+// SetCreditLimit aceita um limite menor que o crédito já utilizado; nesse
+// caso novos débitos são recusados até o saldo voltar ao limite
+func (c *BaseClient) SetCreditLimit(limit Money) error {
+	if c.ClosedAt != nil {
+		return ErrAccountClosed
+	}
+	if limit.IsNegative() {
+		verr := &ValidationError{}
+		verr.Add("credit_limit", "não pode ser negativo")
+		return verr
+	}
+
+	c.CreditLimit = limit
+	return nil
+}
+
+func (c *BaseClient) GetAvailableBalance() Money {
+	return c.Balance.Add(c.CreditLimit)
+}
+
+// normalizeName remove espaços das pontas e exige um nome não vazio de até
+// MaxNameLength caracteres
+func normalizeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+// checkInitialBalance recusa saldos iniciais negativos: uma conta nova não tem
+// limite de crédito que cubra o saldo devedor
+func checkInitialBalance(balance Money) error {
+	if balance.IsNegative() {
+		verr := &ValidationError{}
+		verr.Add("initial_balance", "não pode ser negativo")
+		return verr
+	}
+	return nil
+}
+
+// debit valida o valor contra os limites, considerando o que já foi sacado no
+// dia e no mês, e contra o saldo disponível e registra a transação de saída.
+// A parte do valor que deixa o saldo negativo é registrada em
+// OverdraftAmount.
+func (c *BaseClient) debit(amount Money, limits WithdrawLimits, tx Transaction) error {
+	if c.ClosedAt != nil {
+		return ErrAccountClosed
+	}
+	if !amount.IsPositive() {
 		return ErrInvalidAmount
 	}
-	if amount > c.GetWithdrawLimit() {
-		return ErrWithdrawLimit
+	if err := checkLimits(limits, c.GetLimitUsage(), amount); err != nil {
+		return err
 	}
-	if amount > c.Balance {
+	if amount.Cmp(c.GetAvailableBalance()) > 0 {
 		return ErrInsufficientFunds
 	}
 
-	c.Balance -= amount
-	c.Transactions = append(c.Transactions, Transaction{
-		ID:          uuid.New().String(),
-		Amount:      amount,
-		Type:        "withdrawal",
-		Description: "Saque em dinheiro",
-		CreatedAt:   time.Now(),
+	c.withdrawBalance(amount, tx)
+
+	return nil
+}
+
+// withdraw debita um saque e retorna a transação criada
+func (c *BaseClient) withdraw(amount Money, description string, limits WithdrawLimits) (*Transaction, error) {
+	if description == "" {
+		description = DefaultWithdrawalDescription
+	}
+
+	if err := c.debit(amount, limits, Transaction{
+		Type:        TransactionTypeWithdrawal,
+		Description: description,
+	}); err != nil {
+		return nil, err
+	}
+	withdrawal := c.Transactions[len(c.Transactions)-1]
+	return &withdrawal, nil
+}
+
+// transferOut debita uma transferência enviada
+func (c *BaseClient) transferOut(amount Money, toID, transferID, description string, limits WithdrawLimits) error {
+	if description == "" {
+		description = DefaultTransferOutDescription
+	}
+
+	return c.debit(amount, limits, Transaction{
+		Type:           TransactionTypeTransferOut,
+		Description:    description,
+		TransferID:     transferID,
+		CounterpartyID: toID,
 	})
+}
+
+// withdrawBalance subtrai o valor do saldo e registra a transação, anotando
+// em OverdraftAmount a parte que deixou o saldo negativo
+func (c *BaseClient) withdrawBalance(amount Money, tx Transaction) {
+	c.Balance = c.Balance.Sub(amount)
+	if c.Balance.IsNegative() {
+		overdraft := c.Balance.Neg()
+		if overdraft.Cmp(amount) > 0 {
+			overdraft = amount
+		}
+		tx.OverdraftAmount = &overdraft
+	}
+	c.record(amount, tx)
+}
+
+// credit valida o valor e registra a transação de entrada
+func (c *BaseClient) credit(amount Money, tx Transaction) error {
+	if c.ClosedAt != nil {
+		return ErrAccountClosed
+	}
+	if !amount.IsPositive() {
+		return ErrInvalidAmount
+	}
+
+	c.Balance = c.Balance.Add(amount)
+	c.record(amount, tx)
 
 	return nil
 }
 
-func (c *CorporateClient) GetStatement() []Transaction {
-	return c.Transactions
+// record registra a transação com a data atual, a menos que tx já traga uma
+func (c *BaseClient) record(amount Money, tx Transaction) {
+	tx.ID = uuid.New().String()
+	tx.Amount = amount
+	if tx.CreatedAt.IsZero() {
+		tx.CreatedAt = time.Now()
+	}
+	c.Transactions = append(c.Transactions, tx)
+	c.unsaved++
 }
 
-func (c *CorporateClient) GetBalance() float64 {
-	return c.Balance
+func (c *BaseClient) UnsavedTransactions() []Transaction {
+	return c.Transactions[len(c.Transactions)-c.unsaved:]
+}
+
+func (c *BaseClient) MarkSaved() {
+	c.unsaved = 0
+}
+
+// Implementação dos métodos para PersonalClient
+
+// MarshalJSON acrescenta available_balance aos campos do cliente
+func (c *PersonalClient) MarshalJSON() ([]byte, error) {
+	type fields PersonalClient
+	return json.Marshal(struct {
+		*fields
+		AvailableBalance Money `json:"available_balance"`
+	}{(*fields)(c), c.GetAvailableBalance()})
+}
+
+func (c *PersonalClient) Withdraw(amount Money) error {
+	_, err := c.withdraw(amount, "", c.GetLimits())
+	return err
+}
+
+func (c *PersonalClient) WithdrawWithDescription(amount Money, description string) (*Transaction, error) {
+	return c.withdraw(amount, description, c.GetLimits())
+}
+
+func (c *PersonalClient) TransferOut(amount Money, toID, transferID, description string) error {
+	return c.transferOut(amount, toID, transferID, description, c.GetLimits())
+}
+
+func (c *PersonalClient) GetWithdrawLimit() Money {
+	return c.GetLimits().PerTransaction
+}
+
+func (c *PersonalClient) GetLimits() WithdrawLimits {
+	return c.LimitOverride.Apply(PersonalClientLimits)
+}
+
+func (c *PersonalClient) SetLimitOverride(override LimitOverride) error {
+	return c.setLimitOverride(override, PersonalClientLimits)
+}
+
+// Implementação dos métodos para CorporateClient
+
+// MarshalJSON acrescenta available_balance aos campos do cliente
+func (c *CorporateClient) MarshalJSON() ([]byte, error) {
+	type fields CorporateClient
+	return json.Marshal(struct {
+		*fields
+		AvailableBalance Money `json:"available_balance"`
+	}{(*fields)(c), c.GetAvailableBalance()})
+}
+
+func (c *CorporateClient) Withdraw(amount Money) error {
+	_, err := c.withdraw(amount, "", c.GetLimits())
+	return err
+}
+
+func (c *CorporateClient) WithdrawWithDescription(amount Money, description string) (*Transaction, error) {
+	return c.withdraw(amount, description, c.GetLimits())
+}
+
+func (c *CorporateClient) TransferOut(amount Money, toID, transferID, description string) error {
+	return c.transferOut(amount, toID, transferID, description, c.GetLimits())
+}
+
+func (c *CorporateClient) GetWithdrawLimit() Money {
+	return c.GetLimits().PerTransaction
+}
+
+func (c *CorporateClient) GetLimits() WithdrawLimits {
+	return c.LimitOverride.Apply(CorporateClientLimits)
 }
 
-func (c *CorporateClient) GetWithdrawLimit() float64 {
-	return CorporateClientWithdrawLimit
+func (c *CorporateClient) SetLimitOverride(override LimitOverride) error {
+	return c.setLimitOverride(override, CorporateClientLimits)
 }
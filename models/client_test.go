@@ -1,14 +1,36 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
+
+	"github.com/Luis-Andrei/api-users/models/validation"
 )
 
+func newPersonalClient(t *testing.T, name, cpf string, balance Money) *PersonalClient {
+	t.Helper()
+	client, err := NewPersonalClient(name, cpf, balance)
+	if err != nil {
+		t.Fatalf("Failed to build personal client: %v", err)
+	}
+	return client
+}
+
+func newCorporateClient(t *testing.T, name, cnpj string, balance Money) *CorporateClient {
+	t.Helper()
+	client, err := NewCorporateClient(name, cnpj, balance)
+	if err != nil {
+		t.Fatalf("Failed to build corporate client: %v", err)
+	}
+	return client
+}
+
 func TestPersonalClient(t *testing.T) {
-	client := NewPersonalClient("John Doe", "123.456.789-00", 2000.0)
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("2000.00"))
 
 	// Test initial state
-	if client.GetBalance() != 2000.0 {
+	if client.GetBalance() != MustParseMoney("2000.00") {
 		t.Errorf("Expected initial balance of 2000.0, got %v", client.GetBalance())
 	}
 	if client.GetWithdrawLimit() != PersonalClientWithdrawLimit {
@@ -16,29 +38,29 @@ func TestPersonalClient(t *testing.T) {
 	}
 
 	// Test valid withdrawal
-	err := client.Withdraw(500.0)
+	err := client.Withdraw(MustParseMoney("500.00"))
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	if client.GetBalance() != 1500.0 {
+	if client.GetBalance() != MustParseMoney("1500.00") {
 		t.Errorf("Expected balance of 1500.0, got %v", client.GetBalance())
 	}
 
 	// Test withdrawal above limit
-	err = client.Withdraw(1500.0)
-	if err != ErrWithdrawLimit {
+	err = client.Withdraw(MustParseMoney("1500.00"))
+	if !errors.Is(err, ErrWithdrawLimit) {
 		t.Errorf("Expected ErrWithdrawLimit, got %v", err)
 	}
 
 	// Test invalid amount
-	err = client.Withdraw(-100.0)
+	err = client.Withdraw(MustParseMoney("-100.00"))
 	if err != ErrInvalidAmount {
 		t.Errorf("Expected ErrInvalidAmount, got %v", err)
 	}
 
 	// Test insufficient funds
-	err = client.Withdraw(2000.0)
-	if err != ErrWithdrawLimit {
+	err = client.Withdraw(MustParseMoney("2000.00"))
+	if !errors.Is(err, ErrWithdrawLimit) {
 		t.Errorf("Expected ErrWithdrawLimit, got %v", err)
 	}
 
@@ -47,7 +69,7 @@ func TestPersonalClient(t *testing.T) {
 	if len(transactions) != 1 {
 		t.Errorf("Expected 1 transaction, got %v", len(transactions))
 	}
-	if transactions[0].Amount != 500.0 {
+	if transactions[0].Amount != MustParseMoney("500.00") {
 		t.Errorf("Expected transaction amount of 500.0, got %v", transactions[0].Amount)
 	}
 	if transactions[0].Type != "withdrawal" {
@@ -56,10 +78,10 @@ func TestPersonalClient(t *testing.T) {
 }
 
 func TestCorporateClient(t *testing.T) {
-	client := NewCorporateClient("ACME Corp", "12.345.678/0001-00", 10000.0)
+	client := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", MustParseMoney("10000.00"))
 
 	// Test initial state
-	if client.GetBalance() != 10000.0 {
+	if client.GetBalance() != MustParseMoney("10000.00") {
 		t.Errorf("Expected initial balance of 10000.0, got %v", client.GetBalance())
 	}
 	if client.GetWithdrawLimit() != CorporateClientWithdrawLimit {
@@ -67,29 +89,29 @@ func TestCorporateClient(t *testing.T) {
 	}
 
 	// Test valid withdrawal
-	err := client.Withdraw(3000.0)
+	err := client.Withdraw(MustParseMoney("3000.00"))
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	if client.GetBalance() != 7000.0 {
+	if client.GetBalance() != MustParseMoney("7000.00") {
 		t.Errorf("Expected balance of 7000.0, got %v", client.GetBalance())
 	}
 
 	// Test withdrawal above limit
-	err = client.Withdraw(6000.0)
-	if err != ErrWithdrawLimit {
+	err = client.Withdraw(MustParseMoney("6000.00"))
+	if !errors.Is(err, ErrWithdrawLimit) {
 		t.Errorf("Expected ErrWithdrawLimit, got %v", err)
 	}
 
 	// Test invalid amount
-	err = client.Withdraw(-100.0)
+	err = client.Withdraw(MustParseMoney("-100.00"))
 	if err != ErrInvalidAmount {
 		t.Errorf("Expected ErrInvalidAmount, got %v", err)
 	}
 
 	// Test insufficient funds
-	err = client.Withdraw(8000.0)
-	if err != ErrWithdrawLimit {
+	err = client.Withdraw(MustParseMoney("8000.00"))
+	if !errors.Is(err, ErrWithdrawLimit) {
 		t.Errorf("Expected ErrWithdrawLimit, got %v", err)
 	}
 
@@ -98,10 +120,382 @@ func TestCorporateClient(t *testing.T) {
 	if len(transactions) != 1 {
 		t.Errorf("Expected 1 transaction, got %v", len(transactions))
 	}
-	if transactions[0].Amount != 3000.0 {
+	if transactions[0].Amount != MustParseMoney("3000.00") {
 		t.Errorf("Expected transaction amount of 3000.0, got %v", transactions[0].Amount)
 	}
 	if transactions[0].Type != "withdrawal" {
 		t.Errorf("Expected transaction type 'withdrawal', got %v", transactions[0].Type)
 	}
+	if transactions[0].Description != DefaultWithdrawalDescription {
+		t.Errorf("Expected default description, got %v", transactions[0].Description)
+	}
+
+	// Test withdrawal with a description
+	withdrawal, err := client.WithdrawWithDescription(MustParseMoney("100.00"), "Retirada do caixa")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if last := client.GetStatement()[1]; withdrawal.ID != last.ID || last.Description != "Retirada do caixa" {
+		t.Errorf("Expected the described withdrawal to be returned, got %+v", withdrawal)
+	}
+}
+
+func TestDeposit(t *testing.T) {
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("100.00"))
+
+	// Test valid deposit
+	err := client.Deposit(MustParseMoney("250.00"), "Depósito no caixa")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if client.GetBalance() != MustParseMoney("350.00") {
+		t.Errorf("Expected balance of 350.0, got %v", client.GetBalance())
+	}
+
+	// Test invalid amount
+	err = client.Deposit(Money{}, "")
+	if err != ErrInvalidAmount {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+
+	// Test default description
+	err = client.Deposit(MustParseMoney("50.00"), "")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	transactions := client.GetStatement()
+	if len(transactions) != 2 {
+		t.Fatalf("Expected 2 transactions, got %v", len(transactions))
+	}
+	if transactions[0].Type != TransactionTypeDeposit {
+		t.Errorf("Expected transaction type 'deposit', got %v", transactions[0].Type)
+	}
+	if transactions[0].Description != "Depósito no caixa" {
+		t.Errorf("Expected description 'Depósito no caixa', got %v", transactions[0].Description)
+	}
+	if transactions[1].Description != DefaultDepositDescription {
+		t.Errorf("Expected default description, got %v", transactions[1].Description)
+	}
+}
+
+func TestExecuteTransfer(t *testing.T) {
+	from := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", MustParseMoney("10000.00"))
+	to := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("100.00"))
+
+	// Test valid transfer
+	transfer, err := ExecuteTransfer(from, to, MustParseMoney("2000.00"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if from.GetBalance() != MustParseMoney("8000.00") {
+		t.Errorf("Expected balance of 8000.0, got %v", from.GetBalance())
+	}
+	if to.GetBalance() != MustParseMoney("2100.00") {
+		t.Errorf("Expected balance of 2100.0, got %v", to.GetBalance())
+	}
+
+	out := from.GetStatement()[0]
+	in := to.GetStatement()[0]
+	if out.Type != TransactionTypeTransferOut || in.Type != TransactionTypeTransferIn {
+		t.Errorf("Expected transfer_out/transfer_in, got %v/%v", out.Type, in.Type)
+	}
+	if out.TransferID != transfer.ID || in.TransferID != transfer.ID {
+		t.Errorf("Expected both transactions linked to transfer %v", transfer.ID)
+	}
+	if out.CounterpartyID != to.ID || in.CounterpartyID != from.ID {
+		t.Errorf("Expected counterparties to reference each other")
+	}
+	if out.Description != DefaultTransferOutDescription || in.Description != DefaultTransferInDescription {
+		t.Errorf("Expected default descriptions, got %q/%q", out.Description, in.Description)
+	}
+
+	// Test transfer with a description
+	if _, err := ExecuteTransferWithDescription(from, to, MustParseMoney("100.00"), "Aluguel"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if out, in := from.GetStatement()[1], to.GetStatement()[1]; out.Description != "Aluguel" || in.Description != "Aluguel" {
+		t.Errorf("Expected both transactions described as 'Aluguel', got %q/%q", out.Description, in.Description)
+	}
+
+	// Test transfer above limit
+	_, err = ExecuteTransfer(from, to, MustParseMoney("6000.00"))
+	if !errors.Is(err, ErrWithdrawLimit) {
+		t.Errorf("Expected ErrWithdrawLimit, got %v", err)
+	}
+
+	// Test transfer to the same client
+	_, err = ExecuteTransfer(from, from, MustParseMoney("100.00"))
+	if err != ErrSameClient {
+		t.Errorf("Expected ErrSameClient, got %v", err)
+	}
+
+	if len(from.GetStatement()) != 2 || len(to.GetStatement()) != 2 {
+		t.Errorf("Expected failed transfers to leave statements untouched")
+	}
+}
+
+func TestMoney(t *testing.T) {
+	// Test parsing and formatting
+	cases := map[string]string{
+		"1500":    "1500.00",
+		"1500.5":  "1500.50",
+		"0.10":    "0.10",
+		"-3.1":    "-3.10",
+		" 42.00 ": "42.00",
+	}
+	for input, want := range cases {
+		m, err := ParseMoney(input)
+		if err != nil {
+			t.Errorf("ParseMoney(%q) returned error %v", input, err)
+			continue
+		}
+		if m.String() != want {
+			t.Errorf("ParseMoney(%q) = %v, want %v", input, m, want)
+		}
+	}
+
+	// Test invalid amounts
+	for _, input := range []string{"", "1.234", "1e3", "abc", "1.", ".5", "--1"} {
+		if _, err := ParseMoney(input); err != ErrInvalidMoney {
+			t.Errorf("ParseMoney(%q) expected ErrInvalidMoney, got %v", input, err)
+		}
+	}
+
+	// Test exact arithmetic
+	balance := MustParseMoney("1500.00")
+	for i := 0; i < 10; i++ {
+		balance = balance.Sub(MustParseMoney("0.10"))
+	}
+	if balance != MustParseMoney("1499.00") {
+		t.Errorf("Expected balance of 1499.00, got %v", balance)
+	}
+
+	// Test JSON round trip
+	var decoded struct {
+		Amount Money `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 10.25}`), &decoded); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded.Amount.Cents != 1025 {
+		t.Errorf("Expected 1025 cents, got %+v", decoded.Amount)
+	}
+	encoded, _ := json.Marshal(decoded)
+	if string(encoded) != `{"amount":10.25}` {
+		t.Errorf("Expected {\"amount\":10.25}, got %s", encoded)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 10.255}`), &decoded); !errors.Is(err, ErrInvalidMoney) {
+		t.Errorf("Expected ErrInvalidMoney, got %v", err)
+	}
+}
+
+func TestUnsavedTransactions(t *testing.T) {
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("2000.00"))
+
+	client.Withdraw(MustParseMoney("100.00"))
+	client.Deposit(MustParseMoney("50.00"), "")
+	if len(client.UnsavedTransactions()) != 2 {
+		t.Fatalf("Expected 2 unsaved transactions, got %v", len(client.UnsavedTransactions()))
+	}
+
+	client.MarkSaved()
+	if len(client.UnsavedTransactions()) != 0 {
+		t.Errorf("Expected no unsaved transactions, got %v", len(client.UnsavedTransactions()))
+	}
+
+	client.Withdraw(MustParseMoney("10.00"))
+	unsaved := client.UnsavedTransactions()
+	if len(unsaved) != 1 || unsaved[0].Amount != MustParseMoney("10.00") {
+		t.Errorf("Expected only the last withdrawal to be unsaved, got %+v", unsaved)
+	}
+}
+
+func TestNewClientValidatesDocuments(t *testing.T) {
+	client, err := NewPersonalClient("John Doe", "52998224725", MustParseMoney("0.00"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if client.CPF != "529.982.247-25" {
+		t.Errorf("Expected normalized CPF, got %v", client.CPF)
+	}
+
+	if _, err := NewPersonalClient("John Doe", "123.456.789-00", MustParseMoney("0.00")); err != validation.ErrInvalidCPF {
+		t.Errorf("Expected ErrInvalidCPF, got %v", err)
+	}
+	if _, err := NewCorporateClient("ACME Corp", "", MustParseMoney("0.00")); err != validation.ErrInvalidCNPJ {
+		t.Errorf("Expected ErrInvalidCNPJ, got %v", err)
+	}
+}
+
+func TestNewClientRejectsNegativeBalance(t *testing.T) {
+	var verr *ValidationError
+	if _, err := NewPersonalClient("John Doe", "529.982.247-25", MustParseMoney("-500.00")); !errors.As(err, &verr) ||
+		verr.Fields[0].Field != "initial_balance" {
+		t.Errorf("Expected a validation error on initial_balance, got %v", err)
+	}
+	if _, err := NewCorporateClient("ACME Corp", "11.222.333/0001-81", MustParseMoney("-0.01")); !errors.As(err, &verr) {
+		t.Errorf("Expected a validation error on initial_balance, got %v", err)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("0.00"))
+
+	name, email, phone := "  John Smith ", "john@Example.com", "(11) 98765-4321"
+	if err := client.UpdateProfile(ProfileUpdate{Name: &name, Email: &email, Phone: &phone}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := Profile{Name: "John Smith", Email: "john@example.com", Phone: "+5511987654321"}
+	if client.GetProfile() != want {
+		t.Errorf("Expected %+v, got %+v", want, client.GetProfile())
+	}
+
+	// Um campo inválido não pode deixar a alteração pela metade
+	newName, badEmail := "Johnny", "johnny"
+	if err := client.UpdateProfile(ProfileUpdate{Name: &newName, Email: &badEmail}); err != validation.ErrInvalidEmail {
+		t.Errorf("Expected ErrInvalidEmail, got %v", err)
+	}
+	if client.GetProfile() != want {
+		t.Errorf("Expected profile to be unchanged, got %+v", client.GetProfile())
+	}
+
+	empty := ""
+	if err := client.UpdateProfile(ProfileUpdate{Phone: &empty}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if client.Phone != "" || client.Email != want.Email {
+		t.Errorf("Expected only the phone to be removed, got %+v", client.GetProfile())
+	}
+
+	blank := "   "
+	if err := client.UpdateProfile(ProfileUpdate{Name: &blank}); err != ErrInvalidName {
+		t.Errorf("Expected ErrInvalidName, got %v", err)
+	}
+	if _, err := NewCorporateClient("", "11.222.333/0001-81", MustParseMoney("0.00")); err != ErrInvalidName {
+		t.Errorf("Expected ErrInvalidName, got %v", err)
+	}
+}
+
+func TestCloseAccount(t *testing.T) {
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("10.00"))
+
+	if err := client.Close(); err != ErrNonZeroBalance {
+		t.Errorf("Expected ErrNonZeroBalance, got %v", err)
+	}
+
+	if err := client.Withdraw(MustParseMoney("10.00")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if client.GetClosedAt() == nil {
+		t.Error("Expected closed_at to be set")
+	}
+
+	// Conta encerrada não aceita novas operações
+	if err := client.Deposit(MustParseMoney("1.00"), ""); err != ErrAccountClosed {
+		t.Errorf("Expected ErrAccountClosed on deposit, got %v", err)
+	}
+	if err := client.Withdraw(MustParseMoney("1.00")); err != ErrAccountClosed {
+		t.Errorf("Expected ErrAccountClosed on withdraw, got %v", err)
+	}
+	name := "Jane Doe"
+	if err := client.UpdateProfile(ProfileUpdate{Name: &name}); err != ErrAccountClosed {
+		t.Errorf("Expected ErrAccountClosed on profile update, got %v", err)
+	}
+	if err := client.Close(); err != ErrAccountClosed {
+		t.Errorf("Expected ErrAccountClosed when closing twice, got %v", err)
+	}
+}
+
+func TestOverdraft(t *testing.T) {
+	client := newCorporateClient(t, "ACME Corp", "11.222.333/0001-81", MustParseMoney("100.00"))
+
+	if err := client.SetCreditLimit(MustParseMoney("-1.00")); err == nil {
+		t.Error("Expected a negative credit limit to be rejected")
+	}
+	if err := client.SetCreditLimit(MustParseMoney("1000.00")); err != nil {
+		t.Fatalf("Failed to set credit limit: %v", err)
+	}
+
+	if err := client.Withdraw(MustParseMoney("60.00")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := client.Withdraw(MustParseMoney("90.00")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := client.Withdraw(MustParseMoney("951.00")); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds, got %v", err)
+	}
+
+	if client.GetBalance() != MustParseMoney("-50.00") || client.GetAvailableBalance() != MustParseMoney("950.00") {
+		t.Errorf("Expected balance -50.00 and 950.00 available, got %v and %v", client.GetBalance(), client.GetAvailableBalance())
+	}
+
+	// Só a parte do saque que deixou o saldo negativo usa o limite
+	statement := client.GetStatement()
+	if statement[0].OverdraftAmount != nil {
+		t.Errorf("Expected no overdraft on the first withdrawal, got %v", statement[0].OverdraftAmount)
+	}
+	if statement[1].OverdraftAmount == nil || *statement[1].OverdraftAmount != MustParseMoney("50.00") {
+		t.Errorf("Expected 50.00 of overdraft, got %v", statement[1].OverdraftAmount)
+	}
+
+	data, err := json.Marshal(client)
+	if err != nil {
+		t.Fatalf("Failed to marshal client: %v", err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	if fields["available_balance"] != 950.0 || fields["balance"] != -50.0 || fields["credit_limit"] != 1000.0 || fields["cnpj"] == nil {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("1000.00"))
+
+	if err := client.Withdraw(MustParseMoney("800.00")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := client.Deposit(MustParseMoney("300.00"), ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	withdrawal, deposit := client.GetStatement()[0], client.GetStatement()[1]
+
+	var verr *ValidationError
+	if _, err := client.Reverse(withdrawal, "  "); !errors.As(err, &verr) || verr.Fields[0].Field != "reason" {
+		t.Errorf("Expected a validation error on reason, got %v", err)
+	}
+
+	reversal, err := client.Reverse(withdrawal, "Saque lançado em duplicidade")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if reversal.Type != TransactionTypeReversalCredit || reversal.ReversedTransactionID != withdrawal.ID ||
+		reversal.Description != "Estorno: Saque lançado em duplicidade" {
+		t.Errorf("Unexpected reversal %+v", reversal)
+	}
+	if client.GetBalance() != MustParseMoney("1300.00") {
+		t.Errorf("Expected balance 1300.00, got %v", client.GetBalance())
+	}
+	if used := client.GetLimitUsage(); !used.Daily.IsZero() {
+		t.Errorf("Expected a reversed withdrawal not to use the daily limit, got %v", used.Daily)
+	}
+
+	if _, err := client.Reverse(withdrawal, "De novo"); !errors.Is(err, ErrAlreadyReversed) {
+		t.Errorf("Expected ErrAlreadyReversed, got %v", err)
+	}
+	if _, err := client.Reverse(*reversal, "Estorno do estorno"); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("Expected ErrNotReversible, got %v", err)
+	}
+
+	if _, err := client.Reverse(deposit, "Depósito sem lastro"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if client.GetBalance() != MustParseMoney("1000.00") {
+		t.Errorf("Expected balance 1000.00, got %v", client.GetBalance())
+	}
 }
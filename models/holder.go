@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Papéis de um usuário em uma conta
+const (
+	RoleOwner    = "owner"    // titular: movimenta a conta e gerencia os acessos
+	RoleOperator = "operator" // operador: movimenta a conta
+	RoleViewer   = "viewer"   // consulta saldo e extrato
+)
+
+// ErrInvalidRole indica um papel desconhecido
+var ErrInvalidRole = errors.New("papel inválido: use owner, operator ou viewer")
+
+// AccountHolder liga um usuário a uma conta de cliente com um papel
+type AccountHolder struct {
+	UserID    string    `json:"user_id"`
+	ClientID  string    `json:"client_id"`
+	Role      string    `json:"role"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// IsValidRole indica se o papel é conhecido
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleOperator, RoleViewer:
+		return true
+	default:
+		return false
+	}
+}
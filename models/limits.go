@@ -0,0 +1,184 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limites de saque avaliados por Withdraw e TransferOut
+const (
+	LimitPerTransaction = "per_transaction"
+	LimitDaily          = "daily"
+	LimitMonthly        = "monthly"
+)
+
+// WithdrawLimits são os limites de saque efetivos de um cliente
+type WithdrawLimits struct {
+	PerTransaction Money `json:"per_transaction"`
+	Daily          Money `json:"daily"`
+	Monthly        Money `json:"monthly"`
+}
+
+// LimitOverride altera os limites padrão de um cliente específico; campos
+// nil usam o limite padrão do tipo de cliente
+type LimitOverride struct {
+	PerTransaction *Money `json:"per_transaction,omitempty"`
+	Daily          *Money `json:"daily,omitempty"`
+	Monthly        *Money `json:"monthly,omitempty"`
+}
+
+// Limites padrão por tipo de cliente
+var (
+	PersonalClientLimits = WithdrawLimits{
+		PerTransaction: PersonalClientWithdrawLimit,
+		Daily:          MustParseMoney("5000.00"),
+		Monthly:        MustParseMoney("20000.00"),
+	}
+	CorporateClientLimits = WithdrawLimits{
+		PerTransaction: CorporateClientWithdrawLimit,
+		Daily:          MustParseMoney("50000.00"),
+		Monthly:        MustParseMoney("500000.00"),
+	}
+)
+
+// LimitedTransactionTypes são os tipos de transação que consomem os limites
+// diários e mensais
+var LimitedTransactionTypes = []string{TransactionTypeWithdrawal, TransactionTypeTransferOut}
+
+// LimitExceededError indica qual limite de saque foi atingido. Satisfaz
+// errors.Is(err, ErrWithdrawLimit).
+type LimitExceededError struct {
+	Limit     string `json:"limit"`
+	Max       Money  `json:"max"`
+	Used      Money  `json:"used"`
+	Requested Money  `json:"requested"`
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s: limite %s de %s, já utilizado %s", ErrWithdrawLimit, e.Limit, e.Max, e.Used)
+}
+
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrWithdrawLimit
+}
+
+// Apply retorna os limites com as alterações do override
+func (o LimitOverride) Apply(defaults WithdrawLimits) WithdrawLimits {
+	limits := defaults
+	if o.PerTransaction != nil {
+		limits.PerTransaction = *o.PerTransaction
+	}
+	if o.Daily != nil {
+		limits.Daily = *o.Daily
+	}
+	if o.Monthly != nil {
+		limits.Monthly = *o.Monthly
+	}
+	return limits
+}
+
+// Validate exige limites positivos e confere os limites efetivos, somados
+// aos padrão em defaults: o por operação até o diário e o diário até o
+// mensal. Um limite fora de ordem é apontado no campo alterado pelo override.
+func (o LimitOverride) Validate(defaults WithdrawLimits) error {
+	verr := &ValidationError{}
+	for _, f := range []struct {
+		name  string
+		value *Money
+	}{
+		{LimitPerTransaction, o.PerTransaction},
+		{LimitDaily, o.Daily},
+		{LimitMonthly, o.Monthly},
+	} {
+		if f.value != nil && !f.value.IsPositive() {
+			verr.Add(f.name, "deve ser maior que zero")
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+
+	limits := o.Apply(defaults)
+	if limits.PerTransaction.Cmp(limits.Daily) > 0 {
+		if o.PerTransaction != nil {
+			verr.Add(LimitPerTransaction, fmt.Sprintf("não pode ser maior que o limite diário (%s)", limits.Daily))
+		} else {
+			verr.Add(LimitDaily, fmt.Sprintf("não pode ser menor que o limite por operação (%s)", limits.PerTransaction))
+		}
+	}
+	if limits.Daily.Cmp(limits.Monthly) > 0 {
+		if o.Daily != nil {
+			verr.Add(LimitDaily, fmt.Sprintf("não pode ser maior que o limite mensal (%s)", limits.Monthly))
+		} else {
+			verr.Add(LimitMonthly, fmt.Sprintf("não pode ser menor que o limite diário (%s)", limits.Daily))
+		}
+	}
+	return verr.OrNil()
+}
+
+// IsZero indica que o override não altera nenhum limite
+func (o LimitOverride) IsZero() bool {
+	return o.PerTransaction == nil && o.Daily == nil && o.Monthly == nil
+}
+
+// LimitUsage é o total já sacado no dia e no mês corrente
+type LimitUsage struct {
+	Daily   Money `json:"daily"`
+	Monthly Money `json:"monthly"`
+}
+
+// UsageWindow retorna o início do dia e do mês de now, no fuso horário de
+// now, a partir dos quais os débitos contam para os limites
+func UsageWindow(now time.Time) (startOfDay, startOfMonth time.Time) {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+}
+
+// ComputeLimitUsage soma os débitos de LimitedTransactionTypes feitos desde
+// o início do dia e do mês de now. Saques estornados não contam.
+func ComputeLimitUsage(transactions []Transaction, now time.Time) LimitUsage {
+	startOfDay, startOfMonth := UsageWindow(now)
+
+	reversed := make(map[string]bool)
+	for _, t := range transactions {
+		if t.ReversedTransactionID != "" {
+			reversed[t.ReversedTransactionID] = true
+		}
+	}
+
+	used := LimitUsage{Daily: NewMoney(0), Monthly: NewMoney(0)}
+	for _, t := range transactions {
+		if !isLimited(t.Type) || reversed[t.ID] || t.CreatedAt.Before(startOfMonth) {
+			continue
+		}
+		used.Monthly = used.Monthly.Add(t.Amount)
+		if !t.CreatedAt.Before(startOfDay) {
+			used.Daily = used.Daily.Add(t.Amount)
+		}
+	}
+	return used
+}
+
+func isLimited(txType string) bool {
+	for _, t := range LimitedTransactionTypes {
+		if t == txType {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLimits avalia amount contra os limites por operação, diário e mensal,
+// nessa ordem, retornando o primeiro limite atingido
+func checkLimits(limits WithdrawLimits, used LimitUsage, amount Money) error {
+	if amount.Cmp(limits.PerTransaction) > 0 {
+		return &LimitExceededError{Limit: LimitPerTransaction, Max: limits.PerTransaction, Used: NewMoney(0), Requested: amount}
+	}
+	if used.Daily.Add(amount).Cmp(limits.Daily) > 0 {
+		return &LimitExceededError{Limit: LimitDaily, Max: limits.Daily, Used: used.Daily, Requested: amount}
+	}
+	if used.Monthly.Add(amount).Cmp(limits.Monthly) > 0 {
+		return &LimitExceededError{Limit: LimitMonthly, Max: limits.Monthly, Used: used.Monthly, Requested: amount}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithdrawLimits(t *testing.T) {
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("30000.00"))
+
+	now := time.Now()
+	y, m, _ := now.Date()
+	startOfMonth := time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	lastMonth := startOfMonth.Add(-time.Hour)
+
+	// Saques antigos: o do mês passado não conta para nenhum limite
+	client.Transactions = append(client.Transactions,
+		Transaction{Type: TransactionTypeWithdrawal, Amount: MustParseMoney("19000.00"), CreatedAt: lastMonth},
+		Transaction{Type: TransactionTypeDeposit, Amount: MustParseMoney("9000.00"), CreatedAt: now},
+		Transaction{Type: TransactionTypeTransferOut, Amount: MustParseMoney("900.00"), CreatedAt: now},
+	)
+
+	used := client.GetLimitUsage()
+	if used.Daily != MustParseMoney("900.00") || used.Monthly != MustParseMoney("900.00") {
+		t.Errorf("Expected 900.00 used today and this month, got %+v", used)
+	}
+
+	var limitErr *LimitExceededError
+	err := client.Withdraw(MustParseMoney("1000.01"))
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitPerTransaction || !errors.Is(err, ErrWithdrawLimit) {
+		t.Errorf("Expected per-transaction limit error, got %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := client.Withdraw(MustParseMoney("1000.00")); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	err = client.Withdraw(MustParseMoney("100.01"))
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitDaily {
+		t.Fatalf("Expected daily limit error, got %v", err)
+	}
+	if limitErr.Used != MustParseMoney("4900.00") || limitErr.Max != PersonalClientLimits.Daily {
+		t.Errorf("Expected 4900.00 of %v used, got %+v", PersonalClientLimits.Daily, limitErr)
+	}
+
+	// Um override diário igual ao mensal expõe o limite mensal
+	daily := MustParseMoney("20000.00")
+	if err := client.SetLimitOverride(LimitOverride{Daily: &daily}); err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+	client.Transactions = append(client.Transactions,
+		Transaction{Type: TransactionTypeWithdrawal, Amount: MustParseMoney("15000.00"), CreatedAt: startOfMonth})
+	err = client.Withdraw(MustParseMoney("200.00"))
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitMonthly {
+		t.Errorf("Expected monthly limit error, got %v", err)
+	}
+}
+
+func TestLoadedLimitUsage(t *testing.T) {
+	client := newPersonalClient(t, "John Doe", "529.982.247-25", MustParseMoney("30000.00"))
+
+	// Um cliente carregado do banco traz o uso já gravado em LimitUsage, sem
+	// o histórico
+	client.LimitUsage = LimitUsage{Daily: MustParseMoney("4500.00"), Monthly: MustParseMoney("4500.00")}
+	if err := client.Withdraw(MustParseMoney("500.00")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	used := client.GetLimitUsage()
+	if used.Daily != MustParseMoney("5000.00") || used.Monthly != MustParseMoney("5000.00") {
+		t.Errorf("Expected 5000.00 used, got %+v", used)
+	}
+
+	var limitErr *LimitExceededError
+	if err := client.Withdraw(MustParseMoney("0.01")); !errors.As(err, &limitErr) || limitErr.Limit != LimitDaily {
+		t.Errorf("Expected daily limit error, got %v", err)
+	}
+}
+
+func TestLimitOverrideValidate(t *testing.T) {
+	zero, small, large, huge := NewMoney(0), MustParseMoney("100.00"), MustParseMoney("1000.00"), MustParseMoney("30000.00")
+
+	tests := []struct {
+		name     string
+		override LimitOverride
+		fields   []string
+	}{
+		{"empty", LimitOverride{}, nil},
+		{"valid", LimitOverride{PerTransaction: &small, Daily: &large, Monthly: &large}, nil},
+		{"zero", LimitOverride{Monthly: &zero}, []string{LimitMonthly}},
+		{"per transaction above daily", LimitOverride{PerTransaction: &large, Daily: &small}, []string{LimitPerTransaction}},
+		{"daily above monthly", LimitOverride{Daily: &large, Monthly: &small}, []string{LimitDaily}},
+		{"daily above default monthly", LimitOverride{Daily: &huge}, []string{LimitDaily}},
+		{"monthly below default daily", LimitOverride{Monthly: &large}, []string{LimitMonthly}},
+		{"daily below default per transaction", LimitOverride{Daily: &small}, []string{LimitDaily}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.override.Validate(PersonalClientLimits)
+			if tt.fields == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) || len(verr.Fields) != len(tt.fields) || verr.Fields[0].Field != tt.fields[0] {
+				t.Errorf("Expected errors on %v, got %v", tt.fields, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidMoney é retornado para valores com mais de duas casas decimais
+var ErrInvalidMoney = errors.New("valor monetário inválido: use no máximo duas casas decimais")
+
+// Money representa um valor monetário exato em centavos de real. Todas as
+// contas operam em reais.
+// Em JSON é serializado como número decimal com duas casas (ex.: 1500.50).
+type Money struct {
+	Cents int64
+}
+
+// NewMoney cria um valor em centavos
+func NewMoney(cents int64) Money {
+	return Money{Cents: cents}
+}
+
+// ParseMoney converte um decimal como "1500", "1500.5" ou "-3.10" em Money,
+// rejeitando valores com mais de duas casas decimais
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+
+	units, fraction, hasPoint := strings.Cut(s, ".")
+	if units == "" || (hasPoint && fraction == "") || len(fraction) > 2 {
+		return Money{}, ErrInvalidMoney
+	}
+	for _, r := range units + fraction {
+		if r < '0' || r > '9' {
+			return Money{}, ErrInvalidMoney
+		}
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	cents, err := strconv.ParseInt(units+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidMoney
+	}
+	if negative {
+		cents = -cents
+	}
+
+	return NewMoney(cents), nil
+}
+
+// MustParseMoney é como ParseMoney mas entra em pânico se o valor for inválido.
+// Deve ser usada apenas com constantes conhecidas.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(fmt.Sprintf("models: invalid money %q", s))
+	}
+	return m
+}
+
+// String formata o valor com duas casas decimais (ex.: "-3.10")
+func (m Money) String() string {
+	cents := m.Cents
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Add soma dois valores
+func (m Money) Add(other Money) Money {
+	return Money{Cents: m.Cents + other.Cents}
+}
+
+// Sub subtrai dois valores
+func (m Money) Sub(other Money) Money {
+	return Money{Cents: m.Cents - other.Cents}
+}
+
+// Neg retorna o valor com o sinal invertido
+func (m Money) Neg() Money {
+	return Money{Cents: -m.Cents}
+}
+
+// Cmp retorna -1, 0 ou 1 se m for menor, igual ou maior que other
+func (m Money) Cmp(other Money) int {
+	switch {
+	case m.Cents < other.Cents:
+		return -1
+	case m.Cents > other.Cents:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) IsZero() bool     { return m.Cents == 0 }
+func (m Money) IsPositive() bool { return m.Cents > 0 }
+func (m Money) IsNegative() bool { return m.Cents < 0 }
+
+// MarshalJSON serializa o valor como número decimal exato
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON aceita números ou strings decimais com até duas casas
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implementa sql.Scanner para colunas DECIMAL
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = NewMoney(v * 100)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+}
+
+func (m *Money) scanString(s string) error {
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value implementa driver.Valuer, gravando o valor como decimal exato
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecurrence indica uma regra de recorrência malformada
+var ErrInvalidRecurrence = errors.New("regra de recorrência inválida")
+
+// recurrenceHorizon limita a busca pela próxima ocorrência, para que regras
+// que nunca ocorrem, como "0 0 31 2 *", não travem Next
+const recurrenceHorizon = 5
+
+// Atalhos aceitos no lugar das cinco colunas
+var recurrenceMacros = map[string]string{
+	"@daily":    "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// Recurrence é uma regra no formato do cron: "minuto hora dia-do-mês mês
+// dia-da-semana", com *, listas (1,15), intervalos (1-5) e passos (*/15).
+// O dia da semana vai de 0 (domingo) a 6; 7 também é domingo. Como no cron,
+// quando o dia do mês e o da semana são restritos basta um deles coincidir.
+// Ex.: "0 9 5 * *" ocorre às 9h do dia 5 de cada mês.
+type Recurrence struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// ParseRecurrence lê uma regra de recorrência
+func ParseRecurrence(expr string) (*Recurrence, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := recurrenceMacros[expr]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: são esperados 5 campos, recebidos %d", ErrInvalidRecurrence, len(fields))
+	}
+
+	r := &Recurrence{}
+	for i, f := range []struct {
+		name     string
+		min, max int
+		bits     *uint64
+	}{
+		{"minuto", 0, 59, &r.minute},
+		{"hora", 0, 23, &r.hour},
+		{"dia do mês", 1, 31, &r.dom},
+		{"mês", 1, 12, &r.month},
+		{"dia da semana", 0, 7, &r.dow},
+	} {
+		bits, err := parseCronField(fields[i], f.min, f.max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRecurrence, f.name, err)
+		}
+		*f.bits = bits
+	}
+
+	// 7 é outro nome para domingo
+	if r.dow&(1<<7) != 0 {
+		r.dow = r.dow&^(1<<7) | 1
+	}
+	r.domStar = strings.HasPrefix(fields[2], "*")
+	r.dowStar = strings.HasPrefix(fields[4], "*")
+	return r, nil
+}
+
+// parseCronField converte um campo em um conjunto de bits com os valores
+// permitidos entre min e max
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("passo inválido em %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("intervalo inválido em %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("valor inválido em %q", part)
+			}
+			// "5/10" vai de 5 até o máximo
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q fora do intervalo %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next retorna a primeira ocorrência estritamente posterior a after, no fuso
+// horário de after, ou o instante zero se a regra não ocorre nos próximos
+// anos
+func (r *Recurrence) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(recurrenceHorizon, 0, 0)
+
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case !hasBit(r.month, int(t.Month())):
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !r.matchesDay(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !hasBit(r.hour, t.Hour()):
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !hasBit(r.minute, t.Minute()):
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+
+		// Nas mudanças de horário de verão time.Date pode voltar no tempo
+		if !next.After(t) {
+			next = t.Add(time.Hour)
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+func (r *Recurrence) matchesDay(t time.Time) bool {
+	dom, dow := hasBit(r.dom, t.Day()), hasBit(r.dow, int(t.Weekday()))
+	if r.domStar || r.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func hasBit(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t.Helper()
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		if err != nil {
+			t.Fatalf("Invalid time %q: %v", s, err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		expr  string
+		after string
+		want  string
+	}{
+		{"0 9 5 * *", "2026-10-17 12:00", "2026-11-05 09:00"},
+		{"0 9 5 * *", "2026-11-05 08:59", "2026-11-05 09:00"},
+		{"0 9 5 * *", "2026-11-05 09:00", "2026-12-05 09:00"},
+		{"*/15 * * * *", "2026-10-17 12:07", "2026-10-17 12:15"},
+		{"30 8 * * 1-5", "2026-10-17 12:00", "2026-10-19 08:30"}, // sábado -> segunda
+		{"0 0 31 * *", "2026-11-01 00:00", "2026-12-31 00:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		{"0 12 1 * 0", "2026-10-17 12:00", "2026-10-18 12:00"}, // dia 1 ou domingo
+		{"0 0 * * 7", "2026-10-17 12:00", "2026-10-18 00:00"},
+		{"@monthly", "2026-10-17 12:00", "2026-11-01 00:00"},
+		{"0 0 10,20 * *", "2026-10-17 12:00", "2026-10-20 00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr+" after "+tt.after, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.expr)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			if got := r.Next(at(tt.after)); !got.Equal(at(tt.want)) {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	r, _ := ParseRecurrence("0 0 31 2 *")
+	if got := r.Next(at("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("Expected no occurrence on February 31st, got %s", got)
+	}
+}
+
+func TestParseRecurrenceErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@hourly"} {
+		if _, err := ParseRecurrence(expr); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("Expected ErrInvalidRecurrence for %q, got %v", expr, err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de operação agendada
+const (
+	ScheduleTypeWithdrawal = "withdrawal"
+	ScheduleTypeTransfer   = "transfer"
+)
+
+// Situações de um agendamento. Um agendamento ativo é executado em
+// NextRunAt; running indica uma execução em andamento; os demais são finais.
+const (
+	ScheduleStatusActive    = "active"
+	ScheduleStatusRunning   = "running"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusFailed    = "failed"
+	ScheduleStatusCanceled  = "canceled"
+)
+
+// Resultados de uma execução
+const (
+	ScheduleRunSucceeded = "succeeded"
+	ScheduleRunFailed    = "failed"
+)
+
+// MaxScheduleDescriptionLength é o tamanho máximo da descrição de um agendamento
+const MaxScheduleDescriptionLength = 255
+
+var (
+	ErrScheduleRunning  = errors.New("o agendamento está em execução")
+	ErrScheduleFinished = errors.New("o agendamento já foi encerrado")
+)
+
+// ScheduleSpec descreve um saque ou transferência agendado. Sem Recurrence a
+// operação é executada uma vez, em RunAt; com Recurrence ela se repete a
+// partir de RunAt (ou de agora, se RunAt não for informado).
+type ScheduleSpec struct {
+	Type        string     `json:"type"`
+	Amount      Money      `json:"amount"`
+	ToClientID  string     `json:"to_client_id,omitempty"`
+	Description string     `json:"description,omitempty"`
+	RunAt       *time.Time `json:"run_at,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+}
+
+// ScheduledOperation é uma operação agendada de um cliente
+type ScheduledOperation struct {
+	ID       string `json:"id"`
+	ClientID string `json:"client_id"`
+	ScheduleSpec
+
+	// NextRunAt é a próxima ocorrência; nil quando o agendamento foi encerrado
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	Status    string     `json:"status"`
+
+	// Attempts conta as tentativas que falharam na ocorrência atual, que é
+	// tentada de novo em RetryAt
+	Attempts  int        `json:"attempts"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// ClaimedAt marca o início da execução em andamento
+	ClaimedAt *time.Time `json:"-"`
+
+	// Version é incrementada a cada gravação e impede que duas gravações
+	// concorrentes se sobrescrevam
+	Version int `json:"-"`
+}
+
+// ScheduleRun registra uma tentativa de execução de um agendamento
+type ScheduleRun struct {
+	ID            string    `json:"id"`
+	ScheduleID    string    `json:"schedule_id"`
+	ScheduledFor  time.Time `json:"scheduled_for"`
+	ExecutedAt    time.Time `json:"executed_at"`
+	Attempt       int       `json:"attempt"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	TransactionID string    `json:"transaction_id,omitempty"`
+	TransferID    string    `json:"transfer_id,omitempty"`
+}
+
+// NewScheduledOperation valida spec e cria um agendamento ativo do cliente.
+// As regras de recorrência são avaliadas no fuso horário de now.
+func NewScheduledOperation(clientID string, spec ScheduleSpec, now time.Time) (*ScheduledOperation, error) {
+	op := &ScheduledOperation{
+		ID:        uuid.New().String(),
+		ClientID:  clientID,
+		CreatedAt: now,
+	}
+	if err := op.Update(spec, now); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// Update substitui a operação agendada e recalcula a próxima ocorrência.
+// Apenas agendamentos ativos podem ser alterados.
+func (op *ScheduledOperation) Update(spec ScheduleSpec, now time.Time) error {
+	if err := op.checkActive(); err != nil {
+		return err
+	}
+
+	spec.Description = strings.TrimSpace(spec.Description)
+	spec.Recurrence = strings.TrimSpace(spec.Recurrence)
+	next, err := spec.validate(op.ClientID, now)
+	if err != nil {
+		return err
+	}
+
+	op.ScheduleSpec = spec
+	op.NextRunAt = &next
+	op.Status = ScheduleStatusActive
+	op.Attempts, op.RetryAt = 0, nil
+	op.UpdatedAt = now
+	return nil
+}
+
+// Cancel encerra um agendamento ativo
+func (op *ScheduledOperation) Cancel(now time.Time) error {
+	if err := op.checkActive(); err != nil {
+		return err
+	}
+	op.Status = ScheduleStatusCanceled
+	op.NextRunAt, op.RetryAt = nil, nil
+	op.UpdatedAt = now
+	return nil
+}
+
+// NextOccurrence retorna a ocorrência seguinte a after, ou false se a
+// operação não é recorrente ou a regra não ocorre mais
+func (op *ScheduledOperation) NextOccurrence(after time.Time) (time.Time, bool) {
+	if op.Recurrence == "" {
+		return time.Time{}, false
+	}
+	recurrence, err := ParseRecurrence(op.Recurrence)
+	if err != nil {
+		return time.Time{}, false
+	}
+	next := recurrence.Next(after)
+	return next, !next.IsZero()
+}
+
+// DueAt é quando o agendamento deve ser executado: a próxima tentativa, se a
+// ocorrência atual falhou, ou a própria ocorrência
+func (op *ScheduledOperation) DueAt() time.Time {
+	if op.RetryAt != nil {
+		return *op.RetryAt
+	}
+	if op.NextRunAt != nil {
+		return *op.NextRunAt
+	}
+	return time.Time{}
+}
+
+func (op *ScheduledOperation) checkActive() error {
+	switch op.Status {
+	case "", ScheduleStatusActive:
+		return nil
+	case ScheduleStatusRunning:
+		return ErrScheduleRunning
+	default:
+		return ErrScheduleFinished
+	}
+}
+
+// validate confere os campos e retorna a primeira ocorrência
+func (spec ScheduleSpec) validate(clientID string, now time.Time) (time.Time, error) {
+	verr := &ValidationError{}
+
+	switch spec.Type {
+	case ScheduleTypeWithdrawal:
+		if spec.ToClientID != "" {
+			verr.Add("to_client_id", "não se aplica a saques")
+		}
+	case ScheduleTypeTransfer:
+		if spec.ToClientID == "" {
+			verr.Add("to_client_id", "é obrigatório em transferências")
+		} else if spec.ToClientID == clientID {
+			verr.Add("to_client_id", "deve ser outra conta")
+		}
+	default:
+		verr.Add("type", fmt.Sprintf("deve ser %s ou %s", ScheduleTypeWithdrawal, ScheduleTypeTransfer))
+	}
+	if !spec.Amount.IsPositive() {
+		verr.Add("amount", "deve ser maior que zero")
+	}
+	if utf8.RuneCountInString(spec.Description) > MaxScheduleDescriptionLength {
+		verr.Add("description", fmt.Sprintf("deve ter no máximo %d caracteres", MaxScheduleDescriptionLength))
+	}
+
+	var next time.Time
+	if spec.Recurrence == "" {
+		switch {
+		case spec.RunAt == nil:
+			verr.Add("run_at", "é obrigatório em agendamentos sem recorrência")
+		case !spec.RunAt.After(now):
+			verr.Add("run_at", "deve estar no futuro")
+		default:
+			next = *spec.RunAt
+		}
+	} else if recurrence, err := ParseRecurrence(spec.Recurrence); err != nil {
+		verr.Add("recurrence", err.Error())
+	} else {
+		// A primeira ocorrência pode ser o próprio run_at
+		start := now
+		if spec.RunAt != nil && spec.RunAt.After(now) {
+			start = spec.RunAt.In(now.Location()).Add(-time.Nanosecond)
+		}
+		if next = recurrence.Next(start); next.IsZero() {
+			verr.Add("recurrence", "a regra não ocorre nos próximos anos")
+		}
+	}
+
+	return next, verr.OrNil()
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduledOperation(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(48*time.Hour)
+
+	var verr *ValidationError
+	_, err := NewScheduledOperation("client", ScheduleSpec{Type: ScheduleTypeTransfer, Amount: MustParseMoney("10.00"), RunAt: &past}, now)
+	if !errors.As(err, &verr) || len(verr.Fields) != 2 {
+		t.Errorf("Expected errors on to_client_id and run_at, got %v", err)
+	}
+	_, err = NewScheduledOperation("client", ScheduleSpec{Type: ScheduleTypeWithdrawal, Amount: MustParseMoney("10.00"), Recurrence: "0 0 31 2 *"}, now)
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "recurrence" {
+		t.Errorf("Expected an error on recurrence, got %v", err)
+	}
+
+	// O run_at de uma recorrência pode ser a primeira ocorrência
+	rent := time.Date(2026, 11, 5, 9, 0, 0, 0, time.UTC)
+	op, err := NewScheduledOperation("client", ScheduleSpec{
+		Type:       ScheduleTypeTransfer,
+		Amount:     MustParseMoney("2500.00"),
+		ToClientID: "landlord",
+		RunAt:      &rent,
+		Recurrence: "0 9 5 * *",
+	}, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if op.Status != ScheduleStatusActive || !op.NextRunAt.Equal(rent) || !op.DueAt().Equal(rent) {
+		t.Errorf("Expected an active schedule due at %s, got %+v", rent, op)
+	}
+	if next, ok := op.NextOccurrence(rent); !ok || !next.Equal(rent.AddDate(0, 1, 0)) {
+		t.Errorf("Expected the next occurrence a month later, got %s", next)
+	}
+
+	if err := op.Update(ScheduleSpec{Type: ScheduleTypeWithdrawal, Amount: MustParseMoney("5.00"), RunAt: &future}, now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := op.NextOccurrence(future); ok || !op.NextRunAt.Equal(future) {
+		t.Errorf("Expected a single occurrence at %s, got %+v", future, op)
+	}
+
+	op.Status = ScheduleStatusRunning
+	if err := op.Cancel(now); !errors.Is(err, ErrScheduleRunning) {
+		t.Errorf("Expected ErrScheduleRunning, got %v", err)
+	}
+	op.Status = ScheduleStatusActive
+	if err := op.Cancel(now); err != nil || op.Status != ScheduleStatusCanceled || op.NextRunAt != nil {
+		t.Errorf("Expected a canceled schedule, got %v and %+v", err, op)
+	}
+	if err := op.Update(ScheduleSpec{Type: ScheduleTypeWithdrawal, Amount: MustParseMoney("5.00"), RunAt: &future}, now); !errors.Is(err, ErrScheduleFinished) {
+		t.Errorf("Expected ErrScheduleFinished, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Transfer representa uma transferência entre dois clientes
+type Transfer struct {
+	ID           string    `json:"id"`
+	FromClientID string    `json:"from_client_id"`
+	ToClientID   string    `json:"to_client_id"`
+	Amount       Money     `json:"amount"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Saldos das duas contas antes e depois da transferência, usados na
+	// auditoria; não são expostos na API, que responde a quem envia
+	FromBalanceBefore Money `json:"-"`
+	FromBalanceAfter  Money `json:"-"`
+	ToBalanceBefore   Money `json:"-"`
+	ToBalanceAfter    Money `json:"-"`
+}
+
+// ExecuteTransfer debita o cliente de origem e credita o de destino,
+// registrando transações "transfer_out" e "transfer_in" ligadas pelo mesmo ID.
+// A persistência das duas contas deve ser feita de forma atômica pelo chamador.
+func ExecuteTransfer(from, to Client, amount Money) (*Transfer, error) {
+	return ExecuteTransferWithDescription(from, to, amount, "")
+}
+
+// ExecuteTransferWithDescription é ExecuteTransfer com a descrição das duas
+// transações; vazia, cada lado recebe a descrição padrão
+func ExecuteTransferWithDescription(from, to Client, amount Money, description string) (*Transfer, error) {
+	if from.GetID() == to.GetID() {
+		return nil, ErrSameClient
+	}
+
+	transfer := &Transfer{
+		ID:           uuid.New().String(),
+		FromClientID: from.GetID(),
+		ToClientID:   to.GetID(),
+		Amount:       amount,
+		CreatedAt:    time.Now(),
+
+		FromBalanceBefore: from.GetBalance(),
+		ToBalanceBefore:   to.GetBalance(),
+	}
+
+	if err := from.TransferOut(amount, to.GetID(), transfer.ID, description); err != nil {
+		return nil, err
+	}
+	if err := to.TransferIn(amount, from.GetID(), transfer.ID, description); err != nil {
+		return nil, err
+	}
+
+	transfer.FromBalanceAfter = from.GetBalance()
+	transfer.ToBalanceAfter = to.GetBalance()
+	return transfer, nil
+}
@@ -1,7 +1,6 @@
 package models
 
 import (
-	"errors"
 	"strings"
 )
 
@@ -13,18 +12,21 @@ type User struct {
 	Biography string `json:"biography"`
 }
 
-// Validate verifica se os campos do usuário são válidos
+// Validate verifica se os campos do usuário são válidos. Todos os campos são
+// conferidos e os problemas encontrados são retornados juntos em um
+// *ValidationError.
 func (u *User) Validate() error {
+	var verr ValidationError
 	if len(u.FirstName) < 2 || len(u.FirstName) > 20 {
-		return errors.New("first_name deve ter entre 2 e 20 caracteres")
+		verr.Add("first_name", "first_name deve ter entre 2 e 20 caracteres")
 	}
 	if len(u.LastName) < 2 || len(u.LastName) > 20 {
-		return errors.New("last_name deve ter entre 2 e 20 caracteres")
+		verr.Add("last_name", "last_name deve ter entre 2 e 20 caracteres")
 	}
 	if len(u.Biography) < 20 || len(u.Biography) > 450 {
-		return errors.New("biography deve ter entre 20 e 450 caracteres")
+		verr.Add("biography", "biography deve ter entre 20 e 450 caracteres")
 	}
-	return nil
+	return verr.OrNil()
 }
 
 // NewUser cria um novo usuário com os dados fornecidos
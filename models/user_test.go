@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewUser(t *testing.T) {
+	user, err := NewUser("  John ", "Doe", "A biography with more than twenty characters")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if user.FirstName != "John" {
+		t.Errorf("Expected trimmed first name, got %q", user.FirstName)
+	}
+
+	_, err = NewUser("J", "Doe", "short")
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *ValidationError, got %v", err)
+	}
+	if len(verr.Fields) != 2 || verr.Fields[0].Field != "first_name" || verr.Fields[1].Field != "biography" {
+		t.Errorf("Expected first_name and biography errors, got %+v", verr.Fields)
+	}
+	if !strings.Contains(err.Error(), "first_name") || !strings.Contains(err.Error(), "biography") {
+		t.Errorf("Expected message to mention every field, got %q", err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// Erros de validação de contato
+var (
+	ErrInvalidEmail = errors.New("e-mail inválido")
+	ErrInvalidPhone = errors.New("telefone inválido")
+)
+
+// NormalizeEmail valida um endereço de e-mail simples (sem nome de exibição)
+// e o retorna com o domínio em minúsculas
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if len(email) > 254 {
+		return "", ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	domain := strings.ToLower(email[at+1:])
+	if !strings.Contains(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+
+	return email[:at+1] + domain, nil
+}
+
+// NormalizePhone valida um telefone e o retorna no formato E.164. Números
+// sem código do país são tratados como brasileiros (DDD + número).
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	international := strings.HasPrefix(phone, "+")
+
+	digits, ok := onlyDigits(strings.TrimPrefix(phone, "+"), "()-. ")
+	if !ok {
+		return "", ErrInvalidPhone
+	}
+
+	switch {
+	case international && len(digits) >= 8 && len(digits) <= 15:
+		return "+" + digits, nil
+	case !international && (len(digits) == 10 || len(digits) == 11):
+		return "+55" + digits, nil
+	default:
+		return "", ErrInvalidPhone
+	}
+}
@@ -0,0 +1,51 @@
+package validation
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	valid := map[string]string{
+		"john@example.com":      "john@example.com",
+		" John.Doe@Example.COM": "John.Doe@example.com",
+	}
+	for input, want := range valid {
+		got, err := NormalizeEmail(input)
+		if err != nil {
+			t.Errorf("NormalizeEmail(%q) returned error %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "john", "john@", "john@localhost", "John <john@example.com>", "john@example."} {
+		if _, err := NormalizeEmail(input); err != ErrInvalidEmail {
+			t.Errorf("NormalizeEmail(%q) expected ErrInvalidEmail, got %v", input, err)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	valid := map[string]string{
+		"(11) 98765-4321":   "+5511987654321",
+		"11 3456-7890":      "+551134567890",
+		"+55 11 98765-4321": "+5511987654321",
+		"+1 202 555 0100":   "+12025550100",
+	}
+	for input, want := range valid {
+		got, err := NormalizePhone(input)
+		if err != nil {
+			t.Errorf("NormalizePhone(%q) returned error %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "98765-4321", "+123", "11 9876a-4321", "+1234567890123456"} {
+		if _, err := NormalizePhone(input); err != ErrInvalidPhone {
+			t.Errorf("NormalizePhone(%q) expected ErrInvalidPhone, got %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package validation normaliza e valida documentos brasileiros (CPF e CNPJ),
+// conferindo os dígitos verificadores, e os dados de contato dos clientes.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Erros de validação de documentos
+var (
+	ErrInvalidCPF  = errors.New("CPF inválido")
+	ErrInvalidCNPJ = errors.New("CNPJ inválido")
+)
+
+// NormalizeCPF valida um CPF, com ou sem pontuação, e o retorna no formato
+// 000.000.000-00
+func NormalizeCPF(cpf string) (string, error) {
+	digits, ok := onlyDigits(cpf, "./- ")
+	if !ok || len(digits) != 11 || repeated(digits) {
+		return "", ErrInvalidCPF
+	}
+
+	if checkDigit(digits[:9], weights(10, 9)) != digits[9] ||
+		checkDigit(digits[:10], weights(11, 10)) != digits[10] {
+		return "", ErrInvalidCPF
+	}
+
+	return fmt.Sprintf("%s.%s.%s-%s", digits[:3], digits[3:6], digits[6:9], digits[9:]), nil
+}
+
+// NormalizeCNPJ valida um CNPJ, com ou sem pontuação, e o retorna no formato
+// 00.000.000/0000-00
+func NormalizeCNPJ(cnpj string) (string, error) {
+	digits, ok := onlyDigits(cnpj, "./- ")
+	if !ok || len(digits) != 14 || repeated(digits) {
+		return "", ErrInvalidCNPJ
+	}
+
+	first := []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	second := append([]int{6}, first...)
+	if checkDigit(digits[:12], first) != digits[12] ||
+		checkDigit(digits[:13], second) != digits[13] {
+		return "", ErrInvalidCNPJ
+	}
+
+	return fmt.Sprintf("%s.%s.%s/%s-%s", digits[:2], digits[2:5], digits[5:8], digits[8:12], digits[12:]), nil
+}
+
+// onlyDigits remove os separadores permitidos e indica se sobraram apenas dígitos
+func onlyDigits(s, separators string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case strings.ContainsRune(separators, r):
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// repeated indica sequências como 111.111.111-11, que passam no cálculo dos
+// dígitos verificadores mas não são documentos válidos
+func repeated(digits string) bool {
+	return strings.Count(digits, digits[:1]) == len(digits)
+}
+
+// weights retorna os pesos decrescentes a partir de start usados no CPF
+func weights(start, n int) []int {
+	w := make([]int, n)
+	for i := range w {
+		w[i] = start - i
+	}
+	return w
+}
+
+// checkDigit calcula o dígito verificador módulo 11
+func checkDigit(digits string, weights []int) byte {
+	sum := 0
+	for i, w := range weights {
+		sum += int(digits[i]-'0') * w
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
@@ -0,0 +1,50 @@
+package validation
+
+import "testing"
+
+func TestNormalizeCPF(t *testing.T) {
+	valid := map[string]string{
+		"529.982.247-25":  "529.982.247-25",
+		"52998224725":     "529.982.247-25",
+		" 111.444.777-35": "111.444.777-35",
+	}
+	for input, want := range valid {
+		got, err := NormalizeCPF(input)
+		if err != nil {
+			t.Errorf("NormalizeCPF(%q) returned error %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("NormalizeCPF(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "123.456.789-00", "111.111.111-11", "5299822472", "529.982.247-2a"} {
+		if _, err := NormalizeCPF(input); err != ErrInvalidCPF {
+			t.Errorf("NormalizeCPF(%q) expected ErrInvalidCPF, got %v", input, err)
+		}
+	}
+}
+
+func TestNormalizeCNPJ(t *testing.T) {
+	valid := map[string]string{
+		"11.222.333/0001-81": "11.222.333/0001-81",
+		"12345678000195":     "12.345.678/0001-95",
+	}
+	for input, want := range valid {
+		got, err := NormalizeCNPJ(input)
+		if err != nil {
+			t.Errorf("NormalizeCNPJ(%q) returned error %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("NormalizeCNPJ(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "12.345.678/0001-00", "00.000.000/0000-00", "1234567800019", "11.222.333/0001-8x"} {
+		if _, err := NormalizeCNPJ(input); err != ErrInvalidCNPJ {
+			t.Errorf("NormalizeCNPJ(%q) expected ErrInvalidCNPJ, got %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "strings"
+
+// FieldError descreve um problema de validação em um campo
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError reúne os problemas de validação de uma entidade
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add registra um problema no campo field
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// OrNil retorna e se algum problema foi registrado e nil caso contrário
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Message
+	}
+	return strings.Join(messages, "; ")
+}
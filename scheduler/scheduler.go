@@ -0,0 +1,287 @@
+// Package scheduler executa os saques e transferências agendados. A cada
+// intervalo o worker procura os agendamentos vencidos, executa cada um pela
+// mesma lógica das rotas de saque e transferência e registra o resultado em
+// uma execução (models.ScheduleRun). Falhas são tentadas de novo algumas
+// vezes antes de a ocorrência ser abandonada.
+//
+// Um agendamento fica em execução (running) enquanto a operação é feita. Se o
+// processo termina nesse intervalo não se sabe se o dinheiro saiu da conta;
+// a ocorrência é registrada como interrompida e não é repetida, para que o
+// pagamento nunca seja feito em dobro.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+	"github.com/google/uuid"
+)
+
+// batchSize limita os agendamentos processados a cada ciclo
+const batchSize = 100
+
+// Ações registradas no log de auditoria pelas execuções, as mesmas das rotas
+// de saque e transferência; o chamador é AuditActor
+const (
+	AuditActor          = "scheduler"
+	auditActionWithdraw = "accounts:withdraw"
+	auditActionTransfer = "accounts:transfer"
+)
+
+// ErrInterrupted é registrado nas execuções que não terminaram
+var ErrInterrupted = errors.New("execução interrompida sem resultado registrado; confira o extrato antes de repetir a operação")
+
+// Config define o ritmo do worker. Campos zerados usam os valores padrão.
+type Config struct {
+	// Interval é o intervalo entre as verificações (padrão 1 minuto)
+	Interval time.Duration
+	// MaxAttempts é o número de tentativas por ocorrência (padrão 3)
+	MaxAttempts int
+	// RetryDelay é a espera antes da segunda tentativa, multiplicada pelo
+	// número de tentativas já feitas (padrão 15 minutos)
+	RetryDelay time.Duration
+	// Lease é quanto tempo uma execução pode durar antes de ser considerada
+	// interrompida (padrão 10 minutos)
+	Lease time.Duration
+	// Location é o fuso horário das regras de recorrência; o padrão é time.Local
+	Location *time.Location
+}
+
+// Result resume um ciclo do worker
+type Result struct {
+	Succeeded   int
+	Failed      int
+	Interrupted int
+}
+
+// Scheduler executa os agendamentos guardados em store sobre db
+type Scheduler struct {
+	db    database.Database
+	store database.ScheduleStore
+	cfg   Config
+	now   func() time.Time
+}
+
+// New cria um worker com a configuração informada
+func New(db database.Database, store database.ScheduleStore, cfg Config) *Scheduler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = 15 * time.Minute
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 10 * time.Minute
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	return &Scheduler{db: db, store: store, cfg: cfg, now: time.Now}
+}
+
+// Store retorna o repositório de agendamentos do worker
+func (s *Scheduler) Store() database.ScheduleStore {
+	return s.store
+}
+
+// Now retorna o instante atual no fuso horário das regras de recorrência
+func (s *Scheduler) Now() time.Time {
+	return s.now().In(s.cfg.Location)
+}
+
+// Run executa RunDue a cada intervalo até ctx ser cancelado
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.RunDue()
+		if err != nil {
+			log.Printf("scheduler: %v", err)
+		} else if result.Succeeded+result.Failed+result.Interrupted > 0 {
+			log.Printf("scheduler: %d executado(s), %d falha(s), %d interrompido(s)",
+				result.Succeeded, result.Failed, result.Interrupted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue executa os agendamentos vencidos e encerra as execuções
+// interrompidas. Apenas a indisponibilidade do banco interrompe o ciclo.
+func (s *Scheduler) RunDue() (*Result, error) {
+	schedules, err := s.store.ListDueSchedules(s.Now(), batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for i := range schedules {
+		op, now := &schedules[i], s.Now()
+
+		if op.Status == models.ScheduleStatusRunning {
+			if op.ClaimedAt != nil && now.Sub(*op.ClaimedAt) < s.cfg.Lease {
+				continue
+			}
+			err = s.interrupt(op, now)
+			switch {
+			case err == nil:
+				result.Interrupted++
+			case errors.Is(err, database.ErrConflict):
+				// Outro worker já encerrou a execução
+				err = nil
+			}
+		} else {
+			var run *models.ScheduleRun
+			run, err = s.execute(op, now)
+			switch {
+			case run == nil:
+			case run.Status == models.ScheduleRunSucceeded:
+				result.Succeeded++
+			default:
+				result.Failed++
+			}
+		}
+
+		if errors.Is(err, database.ErrUnavailable) {
+			return result, err
+		}
+		if err != nil {
+			log.Printf("scheduler: agendamento %s: %v", op.ID, err)
+		}
+	}
+	return result, nil
+}
+
+// execute marca o agendamento como em execução, faz a operação e grava o
+// resultado. Retorna nil se outro worker já assumiu o agendamento.
+func (s *Scheduler) execute(op *models.ScheduledOperation, now time.Time) (*models.ScheduleRun, error) {
+	op.Status, op.ClaimedAt, op.UpdatedAt = models.ScheduleStatusRunning, &now, now
+	if err := s.store.UpdateSchedule(op); err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	run := &models.ScheduleRun{
+		ID:           uuid.New().String(),
+		ScheduleID:   op.ID,
+		ScheduledFor: *op.NextRunAt,
+		ExecutedAt:   now,
+		Attempt:      op.Attempts + 1,
+	}
+	err := s.perform(op, run)
+
+	op.LastRunAt, op.ClaimedAt, op.UpdatedAt = &now, nil, now
+	if err == nil {
+		run.Status, op.LastError = models.ScheduleRunSucceeded, ""
+		s.advance(op, now, models.ScheduleStatusCompleted)
+		return run, s.store.RecordScheduleRun(op, *run)
+	}
+
+	run.Status, run.Error, op.LastError = models.ScheduleRunFailed, err.Error(), err.Error()
+	switch {
+	case permanent(err):
+		// A conta foi encerrada ou não existe: o agendamento é encerrado
+		op.Status, op.NextRunAt, op.Attempts, op.RetryAt = models.ScheduleStatusFailed, nil, 0, nil
+	case op.Attempts+1 < s.cfg.MaxAttempts:
+		op.Attempts++
+		retryAt := now.Add(time.Duration(op.Attempts) * s.cfg.RetryDelay)
+		op.Status, op.RetryAt = models.ScheduleStatusActive, &retryAt
+	default:
+		s.advance(op, now, models.ScheduleStatusFailed)
+	}
+	return run, s.store.RecordScheduleRun(op, *run)
+}
+
+// perform faz o saque ou a transferência, com a descrição do agendamento, e
+// anota na execução o que foi criado. A operação é registrada no log de
+// auditoria na mesma transação, com o ID do agendamento.
+func (s *Scheduler) perform(op *models.ScheduledOperation, run *models.ScheduleRun) error {
+	audit := &database.AuditEntry{Actor: AuditActor, ActorType: AuditActor, ScheduleID: op.ID}
+	switch op.Type {
+	case models.ScheduleTypeWithdrawal:
+		audit.Action = auditActionWithdraw
+		var withdrawal *models.Transaction
+		_, err := s.db.UpdateClientTx(op.ClientID, audit, func(client models.Client) error {
+			var err error
+			withdrawal, err = client.WithdrawWithDescription(op.Amount, op.Description)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		run.TransactionID = withdrawal.ID
+	case models.ScheduleTypeTransfer:
+		audit.Action = auditActionTransfer
+		transfer, err := s.db.TransferWithDescription(op.ClientID, op.ToClientID, op.Amount, op.Description, audit)
+		if err != nil {
+			return err
+		}
+		run.TransferID = transfer.ID
+	default:
+		return errors.New("tipo de agendamento desconhecido: " + op.Type)
+	}
+	return nil
+}
+
+// interrupt encerra uma execução que passou do prazo sem registrar o
+// resultado. A ocorrência não é repetida.
+func (s *Scheduler) interrupt(op *models.ScheduledOperation, now time.Time) error {
+	claimedAt := now
+	if op.ClaimedAt != nil {
+		claimedAt = *op.ClaimedAt
+	}
+	run := models.ScheduleRun{
+		ID:           uuid.New().String(),
+		ScheduleID:   op.ID,
+		ScheduledFor: *op.NextRunAt,
+		ExecutedAt:   claimedAt,
+		Attempt:      op.Attempts + 1,
+		Status:       models.ScheduleRunFailed,
+		Error:        ErrInterrupted.Error(),
+	}
+	op.LastRunAt, op.ClaimedAt, op.UpdatedAt = &claimedAt, nil, now
+	op.LastError = ErrInterrupted.Error()
+	s.advance(op, now, models.ScheduleStatusFailed)
+	return s.store.RecordScheduleRun(op, run)
+}
+
+// advance passa para a próxima ocorrência depois de now, pulando as que
+// venceram enquanto o worker estava parado, ou encerra o agendamento com
+// finalStatus se ele não se repete
+func (s *Scheduler) advance(op *models.ScheduledOperation, now time.Time, finalStatus string) {
+	op.Attempts, op.RetryAt = 0, nil
+	if next, ok := op.NextOccurrence(now); ok {
+		op.Status, op.NextRunAt = models.ScheduleStatusActive, &next
+		return
+	}
+	op.Status, op.NextRunAt = finalStatus, nil
+}
+
+// permanent indica erros que não mudam com novas tentativas
+func permanent(err error) bool {
+	for _, target := range []error{
+		database.ErrClientNotFound,
+		models.ErrAccountClosed,
+		models.ErrSameClient,
+		models.ErrInvalidAmount,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Luis-Andrei/api-users/database"
+	"github.com/Luis-Andrei/api-users/models"
+)
+
+func setup(t *testing.T) (*Scheduler, *time.Time, database.Database, *models.CorporateClient, *models.PersonalClient) {
+	db := database.NewMemoryDB()
+
+	payer, err := models.NewCorporateClient("ACME Corp", "11.222.333/0001-81", models.MustParseMoney("3000.00"))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	landlord, err := models.NewPersonalClient("John Doe", "529.982.247-25", models.NewMoney(0))
+	if err != nil {
+		t.Fatalf("Failed to build client: %v", err)
+	}
+	if err := db.CreateCorporateClient(payer, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := db.CreatePersonalClient(landlord, nil); err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	clock := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	s := New(db, database.NewMemoryScheduleStore(), Config{RetryDelay: time.Hour, Location: time.UTC})
+	s.now = func() time.Time { return clock }
+	return s, &clock, db, payer, landlord
+}
+
+func create(t *testing.T, s *Scheduler, clientID string, spec models.ScheduleSpec) *models.ScheduledOperation {
+	t.Helper()
+	op, err := models.NewScheduledOperation(clientID, spec, s.Now())
+	if err != nil {
+		t.Fatalf("Failed to build scheduled operation: %v", err)
+	}
+	if err := s.Store().CreateSchedule(op); err != nil {
+		t.Fatalf("Failed to create scheduled operation: %v", err)
+	}
+	return op
+}
+
+func TestRecurringTransfer(t *testing.T) {
+	s, clock, db, payer, landlord := setup(t)
+
+	op := create(t, s, payer.ID, models.ScheduleSpec{
+		Type:        models.ScheduleTypeTransfer,
+		Amount:      models.MustParseMoney("2000.00"),
+		ToClientID:  landlord.ID,
+		Recurrence:  "0 9 5 * *",
+		Description: "Aluguel",
+	})
+	if want := time.Date(2026, 11, 5, 9, 0, 0, 0, time.UTC); !op.NextRunAt.Equal(want) {
+		t.Fatalf("Expected the first occurrence at %s, got %s", want, op.NextRunAt)
+	}
+
+	// Nada vence antes do dia 5
+	if result, err := s.RunDue(); err != nil || result.Succeeded+result.Failed != 0 {
+		t.Fatalf("Expected nothing to run, got %+v (%v)", result, err)
+	}
+
+	*clock = time.Date(2026, 11, 5, 9, 0, 30, 0, time.UTC)
+	if result, err := s.RunDue(); err != nil || result.Succeeded != 1 {
+		t.Fatalf("Expected one successful run, got %+v (%v)", result, err)
+	}
+
+	got, _ := db.GetClient(landlord.ID)
+	if got.GetBalance() != models.MustParseMoney("2000.00") {
+		t.Errorf("Expected the landlord to receive 2000.00, got %v", got.GetBalance())
+	}
+	statement, _ := db.GetStatement(landlord.ID, database.StatementFilter{})
+	if statement.Transactions[0].Description != "Aluguel" {
+		t.Errorf("Expected the transfer to be described as the schedule, got %v", statement.Transactions)
+	}
+	stored, _ := s.Store().GetSchedule(op.ID)
+	if want := time.Date(2026, 12, 5, 9, 0, 0, 0, time.UTC); stored.Status != models.ScheduleStatusActive || !stored.NextRunAt.Equal(want) {
+		t.Errorf("Expected the next occurrence at %s, got %+v", want, stored)
+	}
+
+	// Em dezembro não há saldo: três tentativas e a ocorrência é abandonada
+	*clock = time.Date(2026, 12, 5, 9, 0, 0, 0, time.UTC)
+	for attempt := 1; attempt <= 3; attempt++ {
+		if result, err := s.RunDue(); err != nil || result.Failed != 1 {
+			t.Fatalf("Expected attempt %d to fail, got %+v (%v)", attempt, result, err)
+		}
+		stored, _ = s.Store().GetSchedule(op.ID)
+		if attempt < 3 && (stored.Attempts != attempt || stored.RetryAt == nil) {
+			t.Fatalf("Expected a retry after attempt %d, got %+v", attempt, stored)
+		}
+		*clock = clock.Add(time.Duration(attempt) * time.Hour)
+	}
+	if want := time.Date(2027, 1, 5, 9, 0, 0, 0, time.UTC); stored.Status != models.ScheduleStatusActive || stored.Attempts != 0 ||
+		!stored.NextRunAt.Equal(want) || stored.LastError == "" {
+		t.Errorf("Expected to move on to %s after the failures, got %+v", want, stored)
+	}
+
+	runs, _ := s.Store().ListScheduleRuns(op.ID, 10)
+	if len(runs) != 4 || runs[0].Status != models.ScheduleRunFailed || runs[0].Attempt != 3 ||
+		runs[3].Status != models.ScheduleRunSucceeded || runs[3].TransferID == "" {
+		t.Errorf("Expected one success and three failures, got %+v", runs)
+	}
+
+	// Só a execução bem-sucedida é auditada, uma vez para cada conta
+	page, _ := db.Audit().List(database.AuditFilter{Actor: AuditActor})
+	if len(page.Entries) != 2 || page.Entries[0].ClientID != payer.ID || page.Entries[1].ClientID != landlord.ID {
+		t.Fatalf("Expected the transfer to be audited on both accounts, got %+v", page.Entries)
+	}
+	for _, entry := range page.Entries {
+		if entry.Action != auditActionTransfer || entry.ActorType != AuditActor || entry.ScheduleID != op.ID {
+			t.Errorf("Unexpected audit entry %+v", entry)
+		}
+	}
+}
+
+func TestOneOffWithdrawal(t *testing.T) {
+	s, clock, db, payer, _ := setup(t)
+
+	runAt := clock.Add(time.Hour)
+	op := create(t, s, payer.ID, models.ScheduleSpec{
+		Type:        models.ScheduleTypeWithdrawal,
+		Amount:      models.MustParseMoney("500.00"),
+		RunAt:       &runAt,
+		Description: "Retirada do caixa",
+	})
+
+	*clock = runAt
+	if result, err := s.RunDue(); err != nil || result.Succeeded != 1 {
+		t.Fatalf("Expected one successful run, got %+v (%v)", result, err)
+	}
+
+	got, _ := db.GetClient(payer.ID)
+	statement, _ := db.GetStatement(payer.ID, database.StatementFilter{})
+	if got.GetBalance() != models.MustParseMoney("2500.00") || statement.Transactions[0].Type != models.TransactionTypeWithdrawal ||
+		statement.Transactions[0].Description != op.Description {
+		t.Errorf("Expected a 500.00 withdrawal described as the schedule, got %v", statement.Transactions)
+	}
+	stored, _ := s.Store().GetSchedule(op.ID)
+	if stored.Status != models.ScheduleStatusCompleted || stored.NextRunAt != nil {
+		t.Errorf("Expected a completed schedule, got %+v", stored)
+	}
+	runs, _ := s.Store().ListScheduleRuns(op.ID, 10)
+	if len(runs) != 1 || runs[0].TransactionID != statement.Transactions[0].ID {
+		t.Errorf("Expected the run to point to the withdrawal, got %+v", runs)
+	}
+
+	page, _ := db.Audit().List(database.AuditFilter{Actor: AuditActor})
+	if len(page.Entries) != 1 {
+		t.Fatalf("Expected the run to be audited, got %+v", page.Entries)
+	}
+	entry := page.Entries[0]
+	if entry.Action != auditActionWithdraw || entry.ScheduleID != op.ID || entry.ClientID != payer.ID ||
+		*entry.BalanceBefore != models.MustParseMoney("3000.00") || *entry.BalanceAfter != models.MustParseMoney("2500.00") {
+		t.Errorf("Unexpected audit entry %+v", entry)
+	}
+}
+
+func TestClosedAccountFails(t *testing.T) {
+	s, clock, db, payer, _ := setup(t)
+
+	op := create(t, s, payer.ID, models.ScheduleSpec{
+		Type:       models.ScheduleTypeWithdrawal,
+		Amount:     models.MustParseMoney("100.00"),
+		Recurrence: "@daily",
+	})
+	if _, err := db.UpdateClientTx(payer.ID, nil, func(c models.Client) error {
+		if err := c.Withdraw(models.MustParseMoney("3000.00")); err != nil {
+			return err
+		}
+		return c.Close()
+	}); err != nil {
+		t.Fatalf("Failed to close account: %v", err)
+	}
+
+	*clock = clock.AddDate(0, 0, 1)
+	if result, err := s.RunDue(); err != nil || result.Failed != 1 {
+		t.Fatalf("Expected one failed run, got %+v (%v)", result, err)
+	}
+	stored, _ := s.Store().GetSchedule(op.ID)
+	if stored.Status != models.ScheduleStatusFailed || stored.NextRunAt != nil {
+		t.Errorf("Expected the schedule to fail without retries, got %+v", stored)
+	}
+}
+
+func TestInterruptedRun(t *testing.T) {
+	s, clock, db, payer, _ := setup(t)
+
+	op := create(t, s, payer.ID, models.ScheduleSpec{
+		Type:       models.ScheduleTypeWithdrawal,
+		Amount:     models.MustParseMoney("100.00"),
+		Recurrence: "0 * * * *",
+	})
+
+	// Simula um processo que assumiu a execução e terminou antes de gravá-la
+	*clock = *op.NextRunAt
+	claimedAt := *clock
+	op.Status, op.ClaimedAt = models.ScheduleStatusRunning, &claimedAt
+	if err := s.Store().UpdateSchedule(op); err != nil {
+		t.Fatalf("Failed to update scheduled operation: %v", err)
+	}
+
+	*clock = clock.Add(time.Minute)
+	if result, err := s.RunDue(); err != nil || result.Interrupted != 0 {
+		t.Fatalf("Expected the running schedule to be left alone, got %+v (%v)", result, err)
+	}
+
+	*clock = clock.Add(s.cfg.Lease)
+	if result, err := s.RunDue(); err != nil || result.Interrupted != 1 || result.Succeeded != 0 {
+		t.Fatalf("Expected one interrupted run, got %+v (%v)", result, err)
+	}
+
+	stored, _ := s.Store().GetSchedule(op.ID)
+	if stored.Status != models.ScheduleStatusActive || !stored.NextRunAt.After(claimedAt) || stored.LastError != ErrInterrupted.Error() {
+		t.Errorf("Expected to skip to the next occurrence, got %+v", stored)
+	}
+	got, _ := db.GetClient(payer.ID)
+	if got.GetBalance() != models.MustParseMoney("3000.00") {
+		t.Errorf("Expected the interrupted occurrence not to be repeated, got %v", got.GetBalance())
+	}
+}
+
+func TestConcurrentInterrupt(t *testing.T) {
+	s, clock, _, payer, _ := setup(t)
+
+	op := create(t, s, payer.ID, models.ScheduleSpec{
+		Type:       models.ScheduleTypeWithdrawal,
+		Amount:     models.MustParseMoney("100.00"),
+		Recurrence: "0 * * * *",
+	})
+	*clock = *op.NextRunAt
+	claimedAt := *clock
+	op.Status, op.ClaimedAt = models.ScheduleStatusRunning, &claimedAt
+	if err := s.Store().UpdateSchedule(op); err != nil {
+		t.Fatalf("Failed to update scheduled operation: %v", err)
+	}
+
+	// Dois workers leem a mesma execução vencida; só o primeiro a encerra
+	*clock = clock.Add(s.cfg.Lease)
+	first, second := *op, *op
+	if err := s.interrupt(&first, s.Now()); err != nil {
+		t.Fatalf("Failed to interrupt: %v", err)
+	}
+	if err := s.interrupt(&second, s.Now()); !errors.Is(err, database.ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+
+	runs, _ := s.Store().ListScheduleRuns(op.ID, 10)
+	if len(runs) != 1 {
+		t.Errorf("Expected a single interrupted run, got %+v", runs)
+	}
+}